@@ -0,0 +1,135 @@
+package bgroutine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/techrail/ground/distlock"
+	"github.com/techrail/ground/logger"
+	"github.com/techrail/ground/typs/appError"
+)
+
+// Option customises a routine added using AddRoutine
+type Option func(r *Typ)
+
+// WithMonitorFunc sets the function called with the events of the routine (see AddMonitorFunc)
+func WithMonitorFunc(f func(typ appError.Typ)) Option {
+	return func(r *Typ) {
+		r.AddMonitorFunc(f)
+	}
+}
+
+// WithLeaderElection makes the routine a cluster singleton: it runs only on the replica elected as its leader. The
+// election starts with the routine and ends when it stops; when the leader dies, another replica takes over once
+// the ttl of the leadership ends. The monitor function gets an event when the leadership is acquired or lost.
+func WithLeaderElection(p distlock.Provider, ttl time.Duration) Option {
+	return func(r *Typ) {
+		cfg := distlock.DefaultElectionConfig("bgroutine:leader:" + r.Name)
+		cfg.TTL = ttl
+		cfg.OnElected = func(lock distlock.Lock) {
+			r.monitor(appError.NewError(appError.Info, "3AJ9TV",
+				fmt.Sprintf("Routine %v acquired the leadership (token %v)", r.Name, lock.Token())))
+		}
+		cfg.OnDemoted = func(reason string) {
+			r.monitor(appError.NewError(appError.Warning, "3EJ9I0",
+				fmt.Sprintf("Routine %v lost the leadership: %v", r.Name, reason)))
+		}
+		r.elector = distlock.NewElector(p, cfg)
+	}
+}
+
+// WithRunLock makes every run of the routine take a lock, so that each run happens on one replica only (the others
+// skip it). The lock is not released after the run but left to expire, so that the replicas whose clocks are a bit
+// behind do not run the same tick again: the ttl must be longer than the clock skew and shorter than the interval
+// between the runs. It is extended while a run lasts longer. A ttl which is not positive is replaced with
+// defaultRunLockTTL. The locks of the providers which do not expire them (see distlock.Expiring) are released once
+// the run is over instead.
+func WithRunLock(p distlock.Provider, ttl time.Duration) Option {
+	return func(r *Typ) {
+		if ttl <= 0 {
+			logger.Warn(fmt.Sprintf("W#3EN6VV - Invalid run lock ttl %v for routine %v. Using %v.",
+				ttl, r.Name, defaultRunLockTTL))
+			ttl = defaultRunLockTTL
+		}
+		r.runLocks = p
+		r.runLockTTL = ttl
+	}
+}
+
+// defaultRunLockTTL is the ttl of the run locks when WithRunLock is given an invalid one
+const defaultRunLockTTL = 15 * time.Second
+
+// startElection runs the election of the routine (if any) until the routine stops
+func (r *Typ) startElection() {
+	if r.elector == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.stopElection = cancel
+	go r.elector.Run(ctx)
+}
+
+func (r *Typ) endElection() {
+	if r.stopElection != nil {
+		r.stopElection()
+	}
+}
+
+// acquireRun tells if this replica should do the run, returning the function to call once the run is over
+func (r *Typ) acquireRun() (bool, func()) {
+	if r.elector != nil && !r.elector.IsLeader() {
+		r.monitor(appError.NewError(appError.Notice, "3EGHD6",
+			fmt.Sprintf("Routine %v skipped the run: another replica is the leader", r.Name)))
+		return false, nil
+	}
+	if r.runLocks == nil {
+		return true, func() {}
+	}
+
+	name := "bgroutine:run:" + r.Name
+	lock, acquired, errTy := r.runLocks.TryAcquire(context.Background(), name, r.runLockTTL)
+	if errTy.IsNotBlank() {
+		r.monitor(appError.NewError(appError.Error, "3EKQIU",
+			fmt.Sprintf("Routine %v skipped the run: could not take its lock", r.Name), errTy))
+		return false, nil
+	}
+	if !acquired {
+		r.monitor(appError.NewError(appError.Notice, "3BA1PT",
+			fmt.Sprintf("Routine %v skipped the run: another replica holds its lock", r.Name)))
+		return false, nil
+	}
+	r.monitor(appError.NewError(appError.Info, "3D01ZD",
+		fmt.Sprintf("Routine %v acquired the run lock (token %v)", r.Name, lock.Token())))
+
+	// Keep the lock while the run lasts
+	ctx, cancel := context.WithCancel(context.Background())
+	runOver := cancel
+	if !distlock.LocksExpire(r.runLocks) {
+		runOver = func() {
+			cancel()
+			if errTy := lock.Release(context.Background()); errTy.IsNotBlank() {
+				r.monitor(appError.NewError(appError.Error, "3HITB3",
+					fmt.Sprintf("Routine %v could not release its run lock", r.Name), errTy))
+			}
+		}
+	}
+	go func() {
+		// The lock is extended at a third of its ttl (at most every millisecond, which is the precision of the locks)
+		ticker := time.NewTicker(max(r.runLockTTL/3, time.Millisecond))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if held, errTy := lock.Refresh(ctx, r.runLockTTL); !held && ctx.Err() == nil {
+				r.monitor(appError.NewError(appError.Warning, "3DXS6K",
+					fmt.Sprintf("Routine %v lost its run lock while running", r.Name), errTy))
+				return
+			}
+		}
+	}()
+	return true, runOver
+}
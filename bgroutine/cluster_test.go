@@ -0,0 +1,99 @@
+package bgroutine
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/techrail/ground/distlock"
+	"github.com/techrail/ground/typs/appError"
+)
+
+func TestLeaderElectionHandoff(t *testing.T) {
+	locks := distlock.NewMemoryProvider()
+	var runs [2]atomic.Int32
+	var routines [2]*Typ
+	for i := range routines {
+		m := NewManager()
+		errTy := m.AddRoutine("report", "10", func() appError.Typ {
+			runs[i].Add(1)
+			return appError.BlankError
+		}, WithLeaderElection(locks, 60*time.Millisecond))
+		if errTy.IsNotBlank() {
+			t.Fatalf("E#3GN5WK - Could not add the routine: %v", errTy)
+		}
+		routines[i] = m.routineMap["report"]
+		_ = routines[i].Start(false)
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if runs[0].Load() == 0 || runs[1].Load() != 0 {
+		t.Fatalf("E#3H7VYC - Expected only the leader to run (%v, %v runs)", runs[0].Load(), runs[1].Load())
+	}
+	routines[0].Stop()
+	time.Sleep(150 * time.Millisecond)
+	if runs[1].Load() == 0 {
+		t.Errorf("E#3A7FSW - The leadership was not handed off")
+	}
+	routines[1].Stop()
+}
+
+func TestRunLockWithInvalidTTL(t *testing.T) {
+	locks := distlock.NewMemoryProvider()
+	m := NewManager()
+	var runs atomic.Int32
+	_ = m.AddRoutine("zero", "@hourly", func() appError.Typ {
+		runs.Add(1)
+		return appError.BlankError
+	}, WithRunLock(locks, 0))
+	_ = m.AddRoutine("tiny", "@hourly", func() appError.Typ {
+		runs.Add(1)
+		return appError.BlankError
+	}, WithRunLock(locks, time.Nanosecond))
+
+	zero, _ := m.Get("zero")
+	if zero.runLockTTL != defaultRunLockTTL {
+		t.Errorf("E#3EO8P6 - Expected the default ttl, got %v", zero.runLockTTL)
+	}
+	markRunning(zero).runOnce()
+	tiny, _ := m.Get("tiny")
+	markRunning(tiny).runOnce()
+	if runs.Load() != 2 {
+		t.Errorf("E#3GJD5B - Expected both routines to run, got %v runs", runs.Load())
+	}
+}
+
+// heldUntilReleased gives out locks which do not expire, like the advisory locks of PostgreSQL
+type heldUntilReleased struct {
+	*distlock.MemoryProvider
+}
+
+func (p heldUntilReleased) Expiring() bool {
+	return false
+}
+
+func (p heldUntilReleased) TryAcquire(ctx context.Context, name string, ttl time.Duration) (distlock.Lock, bool, appError.Typ) {
+	return p.MemoryProvider.TryAcquire(ctx, name, 24*time.Hour)
+}
+
+func TestRunLockWhichDoesNotExpire(t *testing.T) {
+	locks := heldUntilReleased{distlock.NewMemoryProvider()}
+	m := NewManager()
+	var runs atomic.Int32
+	_ = m.AddRoutine("report", "@hourly", func() appError.Typ {
+		runs.Add(1)
+		return appError.BlankError
+	}, WithRunLock(locks, time.Minute))
+	r, _ := m.Get("report")
+
+	markRunning(r).runOnce()
+	r.runOnce()
+	if runs.Load() != 2 {
+		t.Errorf("E#3GJ39R - Expected the lock to be released after each run, got %v runs", runs.Load())
+	}
+	if _, acquired, _ := locks.TryAcquire(context.Background(), "bgroutine:run:report", time.Minute); !acquired {
+		t.Errorf("E#3B55FV - The run lock is still held after the runs")
+	}
+}
@@ -0,0 +1,196 @@
+package bgroutine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/techrail/ground/config"
+	"github.com/techrail/ground/logger"
+	"github.com/techrail/ground/typs/appError"
+)
+
+// Misfire policies: what a cron routine does with the runs it missed while it was paused, while its previous run
+// was not over, or because the clock jumped forward
+const (
+	MisfireSkip    = "skip"    // Drop the missed runs, wait for the next scheduled time
+	MisfireRunOnce = "runOnce" // Run once for all the missed runs (the default)
+	MisfireCatchUp = "catchUp" // Run once for each missed run (up to maxCatchUpRuns)
+)
+
+const (
+	// misfireTolerance is how late a scheduled run can start before it counts as missed
+	misfireTolerance = time.Second
+	// maxCatchUpRuns caps the runs done to catch up (so that a long pause of a frequent routine does not flood)
+	maxCatchUpRuns = 100
+	// shutdownCheckInterval is how often the loop checks if the shutdown of the application was requested (and if
+	// the clock jumped beyond the next scheduled time)
+	shutdownCheckInterval = time.Second
+)
+
+// cronParser parses the standard expressions, the ones with the seconds as the first field and the descriptors
+// (@hourly, @every 90s etc.)
+var cronParser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// WithTimezone sets the timezone in which the cron expression of the routine is evaluated. By default, it is the
+// one of the configuration (config.Time.Timezone). An expression starting with CRON_TZ= uses its own.
+func WithTimezone(loc *time.Location) Option {
+	return func(r *Typ) {
+		if loc != nil {
+			r.location = loc
+		}
+	}
+}
+
+// WithMisfirePolicy sets what a cron routine does with the runs it missed (see MisfireSkip, MisfireRunOnce and
+// MisfireCatchUp). It has no effect in the ticker mode, where the missed ticks are dropped.
+func WithMisfirePolicy(policy string) Option {
+	return func(r *Typ) {
+		switch policy {
+		case MisfireSkip, MisfireRunOnce, MisfireCatchUp:
+			r.misfirePolicy = policy
+		default:
+			logger.Warn(fmt.Sprintf("W#3BKN15 - Unknown misfire policy %v for routine %v. Using %v.",
+				policy, r.Name, r.misfirePolicy))
+		}
+	}
+}
+
+// configuredLocation returns the timezone of the configuration (UTC if it is invalid)
+func configuredLocation() *time.Location {
+	tz := config.Store().Time.Timezone
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("W#3BGXCB - Could not load timezone %v for the routines. Using UTC. Error: %v", tz, err))
+		return time.UTC
+	}
+	return loc
+}
+
+// cronLoop runs the routine at the times of its schedule until the routine is stopped (or the shutdown of the
+// application is requested). It sleeps until the next scheduled time; the runs missed while the routine was
+// paused, busy or because the clock jumped are handled as per the misfire policy.
+func (r *Typ) cronLoop() {
+	defer r.loopExited()
+	next := r.schedule.Next(time.Now().In(r.location))
+	r.setNextRun(next)
+	timer := time.NewTimer(time.Until(next))
+	defer timer.Stop()
+	shutdownCheck := time.NewTicker(shutdownCheckInterval)
+	defer shutdownCheck.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			e := appError.NewError(appError.Info, "1NCFGS", fmt.Sprintf("Routine %v shutting down.", r.Name))
+			r.monitor(e)
+			return
+		case t := <-shutdownCheck.C:
+			if r.shutdownRequested(t) {
+				return
+			}
+			if time.Now().Before(next) {
+				continue
+			}
+			// The clock jumped forward: the timer (which uses the monotonic clock) would fire too late
+		case <-r.resumed:
+			r.runMissed()
+			continue
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		if now.Before(next) {
+			// The clock jumped backward: sleep again until the scheduled time
+			timer.Reset(time.Until(next))
+			continue
+		}
+		var due []time.Time
+		for t := next; !t.After(now) && len(due) < maxCatchUpRuns; t = r.schedule.Next(t) {
+			due = append(due, t)
+		}
+		next = r.schedule.Next(now.In(r.location))
+		r.setNextRun(next)
+		timer.Reset(time.Until(next))
+
+		r.runDue(due, now)
+	}
+}
+
+// runDue runs the routine for the scheduled times which are due, as per the misfire policy
+func (r *Typ) runDue(due []time.Time, now time.Time) {
+	if !r.IsRunning() {
+		r.mu.Lock()
+		r.stats.missed += len(due)
+		r.mu.Unlock()
+		e := appError.NewError(appError.Info, "3FX6RC", fmt.Sprintf("Routine %v missed %v run(s) at %v as it is %v", r.Name, len(due), now, r.GetCurrentState()))
+		r.monitor(e)
+		return
+	}
+
+	onTime := now.Sub(due[len(due)-1]) <= misfireTolerance
+	missed := len(due)
+	if onTime {
+		missed--
+	}
+	if missed > 0 {
+		e := appError.NewError(appError.Warning, "3BYJ9B", fmt.Sprintf("Routine %v missed %v run(s) before %v. Misfire policy: %v", r.Name, missed, now, r.misfirePolicy))
+		r.monitor(e)
+	}
+
+	runs := 1
+	switch r.misfirePolicy {
+	case MisfireSkip:
+		if !onTime {
+			runs = 0
+		}
+	case MisfireCatchUp:
+		runs = len(due)
+	}
+	r.runTimes(runs)
+}
+
+// runMissed handles the runs missed while the routine was paused, once it is resumed
+func (r *Typ) runMissed() {
+	r.mu.Lock()
+	missed := r.stats.missed
+	r.stats.missed = 0
+	r.mu.Unlock()
+	if missed == 0 || !r.IsRunning() {
+		return
+	}
+
+	runs := 0
+	switch r.misfirePolicy {
+	case MisfireRunOnce:
+		runs = 1
+	case MisfireCatchUp:
+		runs = min(missed, maxCatchUpRuns)
+	}
+	e := appError.NewError(appError.Notice, "3G1WE2", fmt.Sprintf("Routine %v was resumed after missing %v run(s). Misfire policy: %v. Running it %v time(s).", r.Name, missed, r.misfirePolicy, runs))
+	r.monitor(e)
+	r.runTimes(runs)
+}
+
+// runTimes runs the routine the given number of times, one after the other, unless it is stopped or paused
+func (r *Typ) runTimes(n int) {
+	for i := 0; i < n; i++ {
+		select {
+		case <-r.stop:
+			return
+		default:
+		}
+		if !r.IsRunning() {
+			return
+		}
+		r.runOnce()
+	}
+}
+
+func (r *Typ) setNextRun(next time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.nextTickAt = next
+}
@@ -0,0 +1,94 @@
+package bgroutine
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/techrail/ground/typs/appError"
+)
+
+func TestCronWithSeconds(t *testing.T) {
+	m := NewManager()
+	var runs atomic.Int32
+	if errTy := m.AddRoutine("everySecond", "* * * * * *", func() appError.Typ {
+		runs.Add(1)
+		return appError.BlankError
+	}); errTy.IsNotBlank() {
+		t.Fatalf("E#3GPY0Y - Could not add the routine: %v", errTy)
+	}
+	r, _ := m.Get("everySecond")
+	_ = r.Start(false)
+	defer r.Stop()
+
+	time.Sleep(2100 * time.Millisecond)
+	if n := runs.Load(); n < 1 || n > 3 {
+		t.Errorf("E#3HY4ZB - Expected the routine to run once per second, it ran %v times", n)
+	}
+	next := r.Status().NextRunAt
+	if next == nil || !next.After(time.Now()) || time.Until(*next) > time.Second {
+		t.Errorf("E#3HRLBL - Unexpected next run: %v", next)
+	}
+}
+
+func TestCronTimezoneAndDescriptors(t *testing.T) {
+	m := NewManager()
+	loc := time.FixedZone("UTC+5:30", 5*3600+1800)
+	_ = m.AddRoutine("nineAm", "0 9 * * *", func() appError.Typ { return appError.BlankError }, WithTimezone(loc))
+	r, _ := m.Get("nineAm")
+	_ = r.Start(false)
+	defer r.Stop()
+	time.Sleep(10 * time.Millisecond)
+	if next := r.Status().NextRunAt; next == nil || next.In(loc).Hour() != 9 || next.In(loc).Minute() != 0 {
+		t.Errorf("E#3BOZ8Q - Expected the next run at 09:00 in the timezone of the routine, got %v", next)
+	}
+
+	for _, expr := range []string{"@every 90s", "@hourly", "*/10 * * * * *"} {
+		if errTy := m.AddRoutine(expr, expr, func() appError.Typ { return appError.BlankError }); errTy.IsNotBlank() {
+			t.Errorf("E#3DBEI3 - Could not add a routine with the expression %v: %v", expr, errTy)
+		}
+	}
+}
+
+func TestMisfirePolicies(t *testing.T) {
+	now := time.Now()
+	late := []time.Time{now.Add(-3 * time.Minute), now.Add(-2 * time.Minute), now.Add(-time.Minute)}
+	onTime := []time.Time{now.Add(-2 * time.Minute), now}
+	tests := []struct {
+		policy                      string
+		late, onTime, pausedMissing int32
+	}{
+		{MisfireSkip, 0, 1, 0},
+		{MisfireRunOnce, 1, 1, 1},
+		{MisfireCatchUp, 3, 2, 4},
+	}
+	for _, tt := range tests {
+		m := NewManager()
+		var runs atomic.Int32
+		_ = m.AddRoutine(tt.policy, "@hourly", func() appError.Typ {
+			runs.Add(1)
+			return appError.BlankError
+		}, WithMisfirePolicy(tt.policy))
+		r, _ := m.Get(tt.policy)
+		r.state = StateRunning
+		r.started = true // Without the loop, to call runDue and runMissed by hand
+
+		r.runDue(late, now)
+		if n := runs.Swap(0); n != tt.late {
+			t.Errorf("E#3FAPAI - %v: expected %v runs after a late wake up, got %v", tt.policy, tt.late, n)
+		}
+		r.runDue(onTime, now)
+		if n := runs.Swap(0); n != tt.onTime {
+			t.Errorf("E#3AKFHF - %v: expected %v runs after an on time wake up, got %v", tt.policy, tt.onTime, n)
+		}
+
+		r.Pause()
+		r.runDue(late, now)
+		r.runDue(late[:1], now)
+		r.Resume()
+		r.runMissed()
+		if n := runs.Swap(0); n != tt.pausedMissing {
+			t.Errorf("E#3C8E4M - %v: expected %v runs on resume, got %v", tt.policy, tt.pausedMissing, n)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package bgroutine
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+
+	"github.com/techrail/ground/problem"
+	"github.com/techrail/ground/typs/appError"
+)
+
+// Handler returns the HTTP API controlling the routines, under the given base path (e.g. "/ops/routines"):
+//
+//	GET  {base}                   the status of all the routines
+//	GET  {base}/{name}            the status of a routine
+//	POST {base}/{name}/{action}   start (?now=true to run it right away), pause, resume, stop or remove a routine
+//	POST {base}/{action}          start-all, pause-all or resume-all
+//
+// The errors are sent as problem details. The API lets its callers stop the routines, so it must be served behind
+// the authentication of the operators only.
+func (m *Manager) Handler(basePath string) http.Handler {
+	basePath = strings.TrimSuffix(basePath, "/")
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+basePath, func(w http.ResponseWriter, r *http.Request) {
+		writeJson(w, m.Snapshot())
+	})
+	mux.HandleFunc("GET "+basePath+"/{name}", func(w http.ResponseWriter, r *http.Request) {
+		routine, found := m.Get(r.PathValue("name"))
+		if !found {
+			writeProblem(w, r, notFound(r.PathValue("name")))
+			return
+		}
+		writeJson(w, routine.Status())
+	})
+	mux.HandleFunc("POST "+basePath+"/{name}/{action}", func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		routine, found := m.Get(name)
+		if !found {
+			writeProblem(w, r, notFound(name))
+			return
+		}
+		switch r.PathValue("action") {
+		case "start":
+			if errTy := routine.Start(r.URL.Query().Get("now") == "true"); errTy.IsNotBlank() {
+				writeProblem(w, r, appError.NewNetworkError(http.StatusConflict, appError.Error, "3A9FWI",
+					fmt.Sprintf("Could not start routine %v", name), "", errTy))
+				return
+			}
+		case "pause":
+			routine.Pause()
+		case "resume":
+			routine.Resume()
+		case "stop":
+			routine.Stop()
+		case "remove":
+			_ = m.Remove(name)
+		default:
+			writeProblem(w, r, unknownAction(r.PathValue("action")))
+			return
+		}
+		writeJson(w, routine.Status())
+	})
+	mux.HandleFunc("POST "+basePath+"/{action}", func(w http.ResponseWriter, r *http.Request) {
+		switch r.PathValue("action") {
+		case "start-all":
+			if errTy := m.StartAll(r.URL.Query().Get("now") == "true"); errTy.IsNotBlank() {
+				writeProblem(w, r, appError.NewNetworkError(http.StatusConflict, appError.Error, "3E4W59",
+					"Could not start all the routines", "", errTy))
+				return
+			}
+		case "pause-all":
+			m.PauseAll()
+		case "resume-all":
+			m.ResumeAll()
+		default:
+			writeProblem(w, r, unknownAction(r.PathValue("action")))
+			return
+		}
+		writeJson(w, m.Snapshot())
+	})
+	return mux
+}
+
+// FastHttpHandler returns the same API as Handler, for the fasthttp server
+func (m *Manager) FastHttpHandler(basePath string) fasthttp.RequestHandler {
+	return fasthttpadaptor.NewFastHTTPHandler(m.Handler(basePath))
+}
+
+func notFound(name string) appError.Typ {
+	return appError.NewNetworkError(http.StatusNotFound, appError.Error, "3HA2HG", fmt.Sprintf("No routine named %v", name), "")
+}
+
+func unknownAction(action string) appError.Typ {
+	return appError.NewNetworkError(http.StatusBadRequest, appError.Error, "3FK6A3", fmt.Sprintf("Unknown action %v", action), "")
+}
+
+func writeJson(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, errTy appError.Typ) {
+	d := problem.FromAppError(errTy, problem.Options{Instance: r.URL.Path})
+	w.Header().Set("Content-Type", problem.ContentType)
+	w.WriteHeader(d.Status)
+	_, _ = w.Write(d.Bytes())
+}
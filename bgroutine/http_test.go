@@ -0,0 +1,50 @@
+package bgroutine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/techrail/ground/problem"
+	"github.com/techrail/ground/typs/appError"
+)
+
+func TestHandler(t *testing.T) {
+	m := NewManager()
+	_ = m.AddRoutine("cleanup", "1000", func() appError.Typ {
+		return appError.NewError(appError.Error, "X", "disk full")
+	})
+	srv := httptest.NewServer(m.Handler("/ops/routines"))
+	defer srv.Close()
+	defer m.ShutdownAllRoutines()
+
+	resp, err := http.Post(srv.URL+"/ops/routines/cleanup/start?now=true", "", nil)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("E#3AGJBH - Could not start the routine: %v, %v", resp, err)
+	}
+	_ = resp.Body.Close()
+	time.Sleep(20 * time.Millisecond)
+
+	resp, _ = http.Get(srv.URL + "/ops/routines")
+	var statuses []Status
+	_ = json.NewDecoder(resp.Body).Decode(&statuses)
+	_ = resp.Body.Close()
+	if len(statuses) != 1 || statuses[0].State != StateRunning || statuses[0].Runs != 1 || statuses[0].Failures != 1 ||
+		statuses[0].LastResult == "" || statuses[0].NextRunAt == nil {
+		t.Errorf("E#3DOWLD - Unexpected snapshot %+v", statuses)
+	}
+
+	resp, _ = http.Post(srv.URL+"/ops/routines/missing/pause", "", nil)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound || resp.Header.Get("Content-Type") != problem.ContentType {
+		t.Errorf("E#3GO22T - Unexpected response %v (%v) for a missing routine", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	resp, _ = http.Post(srv.URL+"/ops/routines/pause-all", "", nil)
+	_ = resp.Body.Close()
+	if r, _ := m.Get("cleanup"); !r.IsPaused() {
+		t.Errorf("E#3ESS1D - The routine was not paused")
+	}
+}
@@ -1,17 +1,28 @@
 package bgroutine
 
+import "sync"
+
+// Manager keeps the routines of the application. It is safe for concurrent use; create it using NewManager.
 type Manager struct {
+	mu         sync.RWMutex
 	routineMap map[string]*Typ
 }
 
-func NewManager() Manager {
-	return Manager{
+func NewManager() *Manager {
+	return &Manager{
 		routineMap: make(map[string]*Typ),
 	}
 }
 
+// ShutdownAllRoutines stops all the routines and waits until their loops exit
 func (m *Manager) ShutdownAllRoutines() {
-	for _, v := range m.routineMap {
-		v.Stop()
+	var wg sync.WaitGroup
+	for _, v := range m.routines() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v.Stop()
+		}()
 	}
+	wg.Wait()
 }
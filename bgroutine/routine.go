@@ -2,14 +2,18 @@
 package bgroutine
 
 import (
+	"context"
 	"fmt"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/robfig/cron/v3"
 	"github.com/techrail/bark/appRuntime"
 
 	"github.com/techrail/ground/constants"
+	"github.com/techrail/ground/distlock"
 	"github.com/techrail/ground/typs/appError"
 )
 
@@ -26,66 +30,136 @@ const (
 	TickerMode = "tickerMode" // When we are working in the ticker mode
 )
 
+// Typ is a background routine. All its methods are safe for concurrent use.
 type Typ struct {
-	Name            string                 // Name of the routine
-	done            chan bool              // Send to this channel to stop the routine
-	operationMode   string                 // Which more are we working in?
-	cronExpr        string                 // The Cron expression to run the function repeatedly
-	ticker          *time.Ticker           // Time ticker to call the function in case we are in ticker mode
-	schedule        cron.Schedule          // Schedule using which we call the function when we are in cron mode
-	function        func() appError.Typ    // The function to run on each tick
-	state           string                 // What is the state of this routine
-	instanceRunning bool                   // Is the function already running (used to prevent parallel runs only)
-	shouldMonitor   bool                   // Monitor this routine? If yes, monitorHook will be called on each run
-	monitorHook     func(typ appError.Typ) // The function which is called for each run if monitoring enabled
-}
-
-func (m *Manager) AddRoutine(name string, cronExpression string, runnerFunc func() appError.Typ) appError.Typ {
+	Name          string         // Name of the routine
+	operationMode string         // Which more are we working in?
+	cronExpr      string         // The Cron expression to run the function repeatedly
+	ticker        *time.Ticker   // Time ticker to call the function in case we are in ticker mode
+	interval      time.Duration  // Interval of the ticker in ticker mode
+	schedule      cron.Schedule  // Schedule using which we call the function when we are in cron mode
+	location      *time.Location // Timezone in which the schedule is evaluated in cron mode
+	misfirePolicy string         // What to do with the missed runs in cron mode
+	function      RunnerFunc     // The function to run on each tick
+
+	timeout                time.Duration // How long a run can last (no limit if zero)
+	maxRetries             int           // How many times a failed run is retried
+	backoff                time.Duration // How long to wait before the first retry
+	maxBackoff             time.Duration // How long to wait before a retry at most
+	maxConsecutiveFailures int           // Pause the routine after these many failed runs in a row (never if zero)
+
+	mu            sync.Mutex             // Guards the fields below it
+	state         string                 // What is the state of this routine
+	started       bool                   // Was the loop of the routine launched
+	shouldMonitor bool                   // Monitor this routine? If yes, monitorHook will be called on each run
+	monitorHook   func(typ appError.Typ) // The function which is called for each run if monitoring enabled
+	stats         runStats               // What happened to the runs so far
+
+	stop            chan struct{}      // Closed to stop the routine
+	runsCtx         context.Context    // Parent context of the runs, cancelled when the routine stops
+	cancelRuns      context.CancelFunc // Cancels runsCtx
+	resumed         chan struct{}      // Signalled when the routine is resumed (to handle the missed runs)
+	stopOnce        sync.Once
+	loopDone        chan struct{}      // Closed when the loop of the routine exits
+	instanceRunning atomic.Bool        // Is the function already running (used to prevent parallel runs only)
+	elector         *distlock.Elector  // Elects the replica running the routine (cluster singleton mode)
+	stopElection    context.CancelFunc // Ends the election when the routine stops
+	runLocks        distlock.Provider  // Provides the lock taken by each run (per run lock mode)
+	runLockTTL      time.Duration      // How long the lock of a run is held
+	gracePeriod     time.Duration      // How long a run can take to return once its context is done
+}
+
+// RunnerFunc is the function of a routine. Its context is cancelled when the run times out (see WithTimeout) or when
+// the routine is stopped.
+type RunnerFunc func(ctx context.Context) appError.Typ
+
+// AddRoutine adds a routine running the function as per the cron expression (or every given number of
+// milliseconds). The cron expression can have the seconds as its first field, or be a descriptor such as @hourly or
+// @every 90s; see WithTimezone and WithMisfirePolicy. By default, the routine runs on every replica; see
+// WithLeaderElection and WithRunLock.
+func (m *Manager) AddRoutine(name string, cronExpression string, runnerFunc func() appError.Typ, opts ...Option) appError.Typ {
+	return m.AddContextRoutine(name, cronExpression, func(context.Context) appError.Typ {
+		return runnerFunc()
+	}, opts...)
+}
+
+// AddContextRoutine is AddRoutine for a function which takes the context of the run. Prefer it for the functions
+// which can hang (e.g. doing network calls), so that they can be interrupted; see WithTimeout.
+func (m *Manager) AddContextRoutine(name string, cronExpression string, runnerFunc RunnerFunc, opts ...Option) appError.Typ {
 	mode := CronMode
-	tickr := time.NewTicker(87600 * time.Hour) // 10 years default duration for the routine
+	interval := time.Duration(0)
+	var tickr *time.Ticker // Only used in the ticker mode
 	// check if we have a valid cron expression or not
-	s, err := cron.ParseStandard(cronExpression)
+	s, err := cronParser.Parse(cronExpression)
 	if err != nil {
-		// The expression is not in the standard format. Let's check if we can convert this into an integer
+		// The expression is not a cron expression. Let's check if we can convert this into an integer
 		tickerMills, convErr := strconv.Atoi(cronExpression)
 		if convErr != nil {
 			// Can't convert it to integer either. It's definitely an error
 			return appError.NewError(appError.Error, "1NIV49", fmt.Sprintf("Could not parse cron expression %v for routine %v. Parser error: %v and Atoi error: %v", cronExpression, name, err, convErr))
 		}
+		if tickerMills <= 0 {
+			return appError.NewError(appError.Error, "3GGT37", fmt.Sprintf("The interval of routine %v must be positive", name))
+		}
 		// Looks like the expression is that of milliseconds
 		mode = TickerMode
-		tickr = time.NewTicker(time.Duration(tickerMills) * time.Millisecond)
+		interval = time.Duration(tickerMills) * time.Millisecond
+		tickr = time.NewTicker(interval)
+		tickr.Stop() // Started along with the routine
 	}
 
-	r := Typ{
-		Name:            name,
-		done:            make(chan bool),
-		operationMode:   mode,
-		cronExpr:        cronExpression,
-		ticker:          tickr,
-		schedule:        s,
-		function:        runnerFunc,
-		state:           StateInitialized,
-		instanceRunning: false,
-		monitorHook:     nil,
+	runsCtx, cancelRuns := context.WithCancel(context.Background())
+	r := &Typ{
+		Name:          name,
+		operationMode: mode,
+		cronExpr:      cronExpression,
+		ticker:        tickr,
+		interval:      interval,
+		schedule:      s,
+		location:      configuredLocation(),
+		misfirePolicy: MisfireRunOnce,
+		function:      runnerFunc,
+		state:         StateInitialized,
+		stop:          make(chan struct{}),
+		resumed:       make(chan struct{}, 1),
+		runsCtx:       runsCtx,
+		cancelRuns:    cancelRuns,
+		loopDone:      make(chan struct{}),
+		gracePeriod:   defaultGracePeriod,
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
 
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if _, ok := m.routineMap[name]; ok {
 		// already exists
+		cancelRuns()
 		return appError.NewError(appError.Error, "1NCFF9", "Another routine by that name already exists")
 	}
-	m.routineMap[name] = &r
+	m.routineMap[name] = r
 
 	return appError.BlankError
 }
 
 func (r *Typ) AddMonitorFunc(f func(typ appError.Typ)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.monitorHook = f
+	r.shouldMonitor = f != nil
 }
 
 func (r *Typ) monitor(e appError.Typ) {
-	if r.shouldMonitor && r.monitorHook != nil {
-		r.monitorHook(e)
+	r.mu.Lock()
+	hook := r.monitorHook
+	if !r.shouldMonitor {
+		hook = nil
+	}
+	r.mu.Unlock()
+	// Called without the lock, so that the hook can use the routine
+	if hook != nil {
+		hook(e)
 	}
 }
 
@@ -93,139 +167,174 @@ func (r *Typ) Start(launchRightNow bool) appError.Typ {
 	if r.Name == constants.EmptyString {
 		return appError.NewError(appError.Error, "1NCFFT", "Cannot launch nameless routine")
 	}
-	if r.state == StatePaused {
+
+	r.mu.Lock()
+	switch r.state {
+	case StatePaused:
+		r.mu.Unlock()
 		return appError.NewError(
 			appError.Error, "1NCFFY", fmt.Sprintf("Routine %v is paused. You can resume it, but not start it.", r.Name))
-	}
-
-	if r.state == StateRunning {
+	case StateRunning:
+		r.mu.Unlock()
 		return appError.NewError(
 			appError.Error, "1NCFG1", fmt.Sprintf("Routine %v is already running.", r.Name))
-	}
-
-	if r.state == StateTerminated {
+	case StateTerminated:
+		r.mu.Unlock()
 		return appError.NewError(
 			appError.Error, "1NCFG4", fmt.Sprintf("Routine %v has been terminated. It cannot be started again.", r.Name))
-	}
-
-	if r.state == StateUninitialized {
+	case StateUninitialized:
+		r.mu.Unlock()
 		return appError.NewError(
 			appError.Error, "1NCFG7", fmt.Sprintf("Routine %v has not been initialized yet.", r.Name))
 	}
+	// We are supposed to start the routine
+	r.state = StateRunning
+	r.started = true
+	r.mu.Unlock()
 
-	if r.state == StateInitialized {
-		// We are supposed to start the routine
-		r.state = StateRunning
+	r.startElection()
+	if r.operationMode == CronMode {
+		go r.cronLoop()
+	} else {
+		r.mu.Lock()
+		r.stats.nextTickAt = time.Now().Add(r.interval)
+		r.mu.Unlock()
+		r.ticker.Reset(r.interval)
+		go r.loop()
+	}
+	if launchRightNow {
+		go r.runOnce()
 	}
 
-	// The actual function that launches the routine function (once)
-	runRoutineOnce := func() {
-		if !r.instanceRunning {
-			r.instanceRunning = true
-			err := r.function()
-			if err.IsNotBlank() {
-				e := appError.NewError(appError.Error, "1NCHIL", fmt.Sprintf("function for routine %v could not run: %v", r.Name, err))
+	return appError.BlankError
+}
+
+// loop keeps running the routine function on each tick (ticker mode) until the routine is stopped (or the shutdown
+// of the application is requested)
+func (r *Typ) loop() {
+	defer r.loopExited()
+	defer r.ticker.Stop()
+	shutdownCheck := time.NewTicker(shutdownCheckInterval)
+	defer shutdownCheck.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			e := appError.NewError(appError.Info, "1NCFGS", fmt.Sprintf("Routine %v shutting down.", r.Name))
+			r.monitor(e)
+			return
+		case t := <-r.ticker.C:
+			r.mu.Lock()
+			r.stats.nextTickAt = t.Add(r.interval)
+			r.mu.Unlock()
+			if state := r.GetCurrentState(); state != StateRunning {
+				e := appError.NewError(appError.Info, "1NI933", fmt.Sprintf("Tick for Routine %s was received at %v but the routine is %v.", r.Name, t, state))
 				r.monitor(e)
-				r.instanceRunning = false
 			} else {
-				e := appError.NewError(appError.Info, "1NCHKC", fmt.Sprintf("function for routine %v finished running", r.Name))
+				e := appError.NewError(appError.Info, "1NI9P9", fmt.Sprintf("Tick for routine %v at %v", r.Name, t))
 				r.monitor(e)
+				r.runOnce()
 			}
-			r.instanceRunning = false
-		} else {
-			e := appError.NewError(appError.Notice, "1NCHML", fmt.Sprintf("function for routine %v seems to be running already", r.Name))
-			r.monitor(e)
-		}
-	}
-
-	// goroutine to keep running the routine function periodically and to stop doing that when done channel gets data
-	// 	indicating that the routine is no longer needed
-	go func() {
-		for {
-			select {
-			case <-r.done:
-				e := appError.NewError(appError.Info, "1NCFGS", fmt.Sprintf("Routine %v shutting down.", r.Name))
-				r.monitor(e)
-				r.ticker.Stop()
+		case t := <-shutdownCheck.C:
+			if r.shutdownRequested(t) {
 				return
-			case t := <-r.ticker.C:
-				if r.operationMode == TickerMode {
-					if r.state != StateRunning {
-						e := appError.NewError(appError.Info, "1NI933", fmt.Sprintf("Tick for Routine %s was received at %v but the routine is %v.", r.Name, t, r.state))
-						r.monitor(e)
-					} else {
-						e := appError.NewError(appError.Info, "1NI9P9", fmt.Sprintf("Tick for routine %v at %v", r.Name, t))
-						r.monitor(e)
-						runRoutineOnce()
-					}
-				} else {
-					e := appError.NewError(appError.Info, "1NPBCQ", fmt.Sprintf("Tick for routine %v recieved when it should not have happened at %v", r.Name, t))
-					r.monitor(e)
-				}
-			case t := <-time.After(time.Second):
-				if appRuntime.ShutdownRequested.Load() {
-					r.done <- true
-					e := appError.NewError(appError.Info, "1NPB3F", fmt.Sprintf("Shutdown was requested. Stopping routine %v at %v", r.Name, t))
-					r.monitor(e)
-					return
-				}
-
-				if r.operationMode == CronMode && time.Now().UTC().After(r.schedule.Next(time.Now().UTC())) {
-					// Time to execute
-					runRoutineOnce()
-				}
 			}
 		}
-	}()
+	}
+}
 
-	if launchRightNow {
-		go runRoutineOnce()
+// shutdownRequested tells if the shutdown of the application was requested
+func (r *Typ) shutdownRequested(t time.Time) bool {
+	if !appRuntime.ShutdownRequested.Load() {
+		return false
 	}
+	e := appError.NewError(appError.Info, "1NPB3F", fmt.Sprintf("Shutdown was requested. Stopping routine %v at %v", r.Name, t))
+	r.monitor(e)
+	return true
+}
 
-	return appError.BlankError
+// loopExited marks the routine as terminated once its loop exits
+func (r *Typ) loopExited() {
+	r.cancelRuns()
+	r.endElection()
+	r.mu.Lock()
+	r.state = StateTerminated
+	r.mu.Unlock()
+	close(r.loopDone)
 }
 
 func (r *Typ) CurrentMode() string {
 	return r.operationMode
 }
 
+// Pause stops the runs of the routine (a run in progress finishes) until it is resumed
 func (r *Typ) Pause() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	if r.state == StateRunning || r.state == StateInitialized {
 		r.state = StatePaused
 	}
 }
 
+// Resume lets a paused routine run again. A routine paused before it was started goes back to the initialized state.
 func (r *Typ) Resume() {
-	if r.state == StatePaused {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state != StatePaused {
+		return
+	}
+	if r.started {
 		r.state = StateRunning
+		select {
+		case r.resumed <- struct{}{}:
+		default:
+		}
+	} else {
+		r.state = StateInitialized
 	}
 }
 
+// Stop terminates the routine and waits until its loop exits. The context of a run in progress is cancelled, and Stop
+// waits for its function to return up to the grace period of the routine (see WithGracePeriod); a function which
+// does not return by then keeps running in the background. It can be called any number of times, whether the
+// routine was started or not; it must not be called from the monitor function. A stopped routine can not be started
+// again.
 func (r *Typ) Stop() {
-	r.done <- true
+	r.mu.Lock()
+	started := r.started
+	if !started {
+		r.state = StateTerminated
+	}
+	r.mu.Unlock()
+
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+	r.cancelRuns()
+	deadline := time.Now().Add(r.gracePeriod)
+	if started {
+		<-r.loopDone
+	}
+	if !r.waitForRun(deadline) {
+		e := appError.NewError(appError.Warning, "3HRC25", fmt.Sprintf("Routine %v stopped while its function is still running", r.Name))
+		r.monitor(e)
+	}
 }
 
 func (r *Typ) IsTerminated() bool {
-	if r.state == StateTerminated {
-		return true
-	}
-	return false
+	return r.GetCurrentState() == StateTerminated
 }
 
 func (r *Typ) IsPaused() bool {
-	if r.state == StatePaused {
-		return true
-	}
-	return false
+	return r.GetCurrentState() == StatePaused
 }
 
 func (r *Typ) IsRunning() bool {
-	if r.state == StateRunning {
-		return true
-	}
-	return false
+	return r.GetCurrentState() == StateRunning
 }
 
 func (r *Typ) GetCurrentState() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	return r.state
 }
@@ -0,0 +1,110 @@
+package bgroutine
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/techrail/ground/typs/appError"
+)
+
+func TestStateMachineUnderLoad(t *testing.T) {
+	m := NewManager()
+	var runs atomic.Int32
+	_ = m.AddRoutine("busy", "1", func() appError.Typ {
+		runs.Add(1)
+		time.Sleep(time.Millisecond)
+		return appError.BlankError
+	})
+	r := m.routineMap["busy"]
+	r.AddMonitorFunc(func(appError.Typ) { _ = r.GetCurrentState() })
+	if errTy := r.Start(true); errTy.IsNotBlank() {
+		t.Fatalf("E#3BI99L - Could not start the routine: %v", errTy)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 200 {
+				if i%2 == 0 {
+					r.Pause()
+				} else {
+					r.Resume()
+				}
+				_ = r.IsRunning()
+			}
+		}()
+	}
+	wg.Wait()
+	r.Resume()
+	time.Sleep(20 * time.Millisecond)
+	if runs.Load() == 0 {
+		t.Errorf("E#3G7JRY - The routine never ran")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		r.Stop()
+		r.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("E#3EEHE7 - Stop blocked")
+	}
+	if !r.IsTerminated() {
+		t.Errorf("E#3DL9Y7 - The stopped routine is %v", r.GetCurrentState())
+	}
+	if errTy := r.Start(false); errTy.IsBlank() {
+		t.Errorf("E#3EGPMW - The terminated routine was started again")
+	}
+}
+
+func TestStopBeforeStart(t *testing.T) {
+	m := NewManager()
+	_ = m.AddRoutine("idle", "1000", func() appError.Typ { return appError.BlankError })
+	r := m.routineMap["idle"]
+	r.Pause()
+	r.Resume()
+	if r.GetCurrentState() != StateInitialized {
+		t.Errorf("E#3B7QU8 - A routine resumed before its start is %v", r.GetCurrentState())
+	}
+	r.Stop()
+	r.Stop()
+	if !r.IsTerminated() {
+		t.Errorf("E#3D6C87 - The routine stopped before its start is %v", r.GetCurrentState())
+	}
+}
+
+func TestConcurrentManager(t *testing.T) {
+	m := NewManager()
+	var wg sync.WaitGroup
+	var added atomic.Int32
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if m.AddRoutine(fmt.Sprintf("r%v", i%25), "1000", func() appError.Typ { return appError.BlankError }).IsBlank() {
+				added.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if added.Load() != 25 {
+		t.Errorf("E#3GP46Y - %v routines were added instead of 25", added.Load())
+	}
+	for _, r := range m.routineMap {
+		_ = r.Start(false)
+	}
+	m.ShutdownAllRoutines()
+	for name, r := range m.routineMap {
+		if !r.IsTerminated() {
+			t.Errorf("E#3B21LS - Routine %v is %v after the shutdown", name, r.GetCurrentState())
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package bgroutine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/techrail/bark/appRuntime"
+
+	"github.com/techrail/ground/typs/appError"
+)
+
+// WithTimeout limits how long each run (each attempt, when retrying) of the routine can last. Once the timeout is
+// over, the context of the run is cancelled and the run is recorded as failed. A function which does not return
+// when its context is done is abandoned: it keeps running in the background and the next runs are skipped until it
+// returns.
+func WithTimeout(d time.Duration) Option {
+	return func(r *Typ) {
+		r.timeout = d
+	}
+}
+
+// WithGracePeriod sets how long a run can take to return once its context is done (on timeout or when the routine
+// stops) before it is abandoned; 100ms by default. Stop waits up to as long for the function to return.
+func WithGracePeriod(d time.Duration) Option {
+	return func(r *Typ) {
+		r.gracePeriod = max(d, 0)
+	}
+}
+
+// WithRetry makes a failed run retry up to maxRetries times. The first retry waits for backoff, and each next one
+// waits twice as long as the previous one, up to maxBackoff. An abandoned run (see WithTimeout) is not retried, nor
+// is a run of a routine which is stopped or paused meanwhile.
+func WithRetry(maxRetries int, backoff, maxBackoff time.Duration) Option {
+	return func(r *Typ) {
+		r.maxRetries = max(maxRetries, 0)
+		r.backoff = backoff
+		r.maxBackoff = max(maxBackoff, backoff)
+	}
+}
+
+// WithMaxConsecutiveFailures pauses the routine once the given number of runs failed in a row (a run fails when its
+// last retry fails). The routine must then be resumed to run again.
+func WithMaxConsecutiveFailures(n int) Option {
+	return func(r *Typ) {
+		r.maxConsecutiveFailures = n
+	}
+}
+
+// runOnce runs the routine function (retrying as per its retry policy), unless it is running already
+func (r *Typ) runOnce() {
+	if !r.instanceRunning.CompareAndSwap(false, true) {
+		r.recordSkip()
+		e := appError.NewError(appError.Notice, "1NCHML", fmt.Sprintf("function for routine %v seems to be running already", r.Name))
+		r.monitor(e)
+		return
+	}
+
+	shouldRun, releaseRun := r.acquireRun()
+	if !shouldRun {
+		r.instanceRunning.Store(false)
+		r.recordSkip()
+		return
+	}
+	// The run is over once the function returns, which can be after runOnce when the run is abandoned
+	runOver := func() {
+		releaseRun()
+		r.instanceRunning.Store(false)
+	}
+
+	startedAt := time.Now()
+	backoff := r.backoff
+	var err appError.Typ
+	for attempt := 0; ; attempt++ {
+		var abandoned bool
+		err, abandoned = r.attempt(runOver)
+		if abandoned {
+			// runOver is called once the function returns
+			break
+		}
+		if err.IsBlank() || attempt >= r.maxRetries || !r.waitBeforeRetry(backoff) {
+			runOver()
+			break
+		}
+		r.recordRetry()
+		e := appError.NewError(appError.Warning, "3BGL4N", fmt.Sprintf("Retrying routine %v (retry %v of %v) after %v. Error: %v", r.Name, attempt+1, r.maxRetries, backoff, err))
+		r.monitor(e)
+		backoff = min(2*backoff, r.maxBackoff)
+	}
+
+	if r.recordRun(startedAt, err) {
+		e := appError.NewError(appError.Alert, "3F6BC7", fmt.Sprintf("Routine %v failed %v times in a row. Pausing it.", r.Name, r.maxConsecutiveFailures), err)
+		r.monitor(e)
+		r.Pause()
+	}
+	if err.IsNotBlank() {
+		e := appError.NewError(appError.Error, "1NCHIL", fmt.Sprintf("function for routine %v could not run: %v", r.Name, err))
+		r.monitor(e)
+	} else {
+		e := appError.NewError(appError.Info, "1NCHKC", fmt.Sprintf("function for routine %v finished running", r.Name))
+		r.monitor(e)
+	}
+}
+
+// attempt calls the function once, with a context which is cancelled when the timeout of the run is over, when the
+// routine is stopped or when the shutdown of the application is requested. It tells if the function was abandoned
+// because it did not return once its context was done, in which case runOver is called once it returns.
+func (r *Typ) attempt(runOver func()) (appError.Typ, bool) {
+	ctx, cancel := r.runContext()
+	defer cancel()
+
+	result := make(chan appError.Typ, 1)
+	go func() {
+		result <- r.function(ctx)
+	}()
+
+	shutdownCheck := time.NewTicker(shutdownCheckInterval)
+	defer shutdownCheck.Stop()
+	for {
+		select {
+		case err := <-result:
+			return err, false
+		case <-shutdownCheck.C:
+			if appRuntime.ShutdownRequested.Load() {
+				r.cancelRuns()
+			}
+			continue
+		case <-ctx.Done():
+		}
+
+		// Give the function a moment to return on its own after the cancellation
+		select {
+		case err := <-result:
+			return r.cancelled(ctx, err), false
+		case <-time.After(r.gracePeriod):
+		}
+		go func() {
+			<-result
+			runOver()
+			e := appError.NewError(appError.Notice, "3CCPA6", fmt.Sprintf("Abandoned run of routine %v finally returned", r.Name))
+			r.monitor(e)
+		}()
+		e := appError.NewError(appError.Error, "3FL8YR", fmt.Sprintf("Routine %v did not return after its context was done. Abandoning the run; the next runs are skipped until it returns.", r.Name))
+		r.monitor(e)
+		return r.cancelled(ctx, appError.BlankError), true
+	}
+}
+
+// defaultGracePeriod is how long a run can take to return once its context is done, before it is abandoned (unless
+// set with WithGracePeriod)
+const defaultGracePeriod = 100 * time.Millisecond
+
+// waitForRun waits until the deadline for the run in progress (if any) to be over, telling if it is
+func (r *Typ) waitForRun(deadline time.Time) bool {
+	for r.instanceRunning.Load() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(runPollInterval)
+	}
+	return true
+}
+
+// runPollInterval is how often waitForRun checks if the run is over
+const runPollInterval = 5 * time.Millisecond
+
+// runContext returns the context of a run
+func (r *Typ) runContext() (context.Context, context.CancelFunc) {
+	if r.timeout > 0 {
+		return context.WithTimeout(r.runsCtx, r.timeout)
+	}
+	return context.WithCancel(r.runsCtx)
+}
+
+// cancelled returns the error of a run whose context is done, and notifies the monitor function about it
+func (r *Typ) cancelled(ctx context.Context, err appError.Typ) appError.Typ {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		r.recordTimeout()
+		e := appError.NewError(appError.Error, "3H8BC1", fmt.Sprintf("Run of routine %v timed out after %v", r.Name, r.timeout), err)
+		r.monitor(e)
+		return e
+	}
+	e := appError.NewError(appError.Warning, "3AV7AE", fmt.Sprintf("Run of routine %v was cancelled as the routine is stopping", r.Name), err)
+	r.monitor(e)
+	return e
+}
+
+// waitBeforeRetry waits for the backoff, telling if the run should be retried (it is not once the routine is
+// stopped or paused)
+func (r *Typ) waitBeforeRetry(backoff time.Duration) bool {
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-r.stop:
+		return false
+	case <-r.runsCtx.Done():
+		return false
+	case <-timer.C:
+	}
+	return r.IsRunning()
+}
@@ -0,0 +1,180 @@
+package bgroutine
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/techrail/ground/distlock"
+	"github.com/techrail/ground/typs/appError"
+)
+
+// markRunning marks the routine as running without launching its loop, to call runOnce by hand
+func markRunning(r *Typ) *Typ {
+	r.state = StateRunning
+	r.started = true
+	return r
+}
+
+func TestTimeoutAndRetries(t *testing.T) {
+	m := NewManager()
+	var mu sync.Mutex
+	var codes []string
+	_ = m.AddContextRoutine("hangs", "@hourly", func(ctx context.Context) appError.Typ {
+		<-ctx.Done()
+		return appError.BlankError
+	}, WithTimeout(20*time.Millisecond), WithRetry(1, time.Millisecond, time.Millisecond), WithMonitorFunc(func(e appError.Typ) {
+		mu.Lock()
+		codes = append(codes, e.Code)
+		mu.Unlock()
+	}))
+	r, _ := m.Get("hangs")
+	markRunning(r).runOnce()
+
+	s := r.Status()
+	if s.Timeouts != 2 || s.Retries != 1 || s.Failures != 1 || s.LastResult == "" {
+		t.Errorf("E#3H07CM - Unexpected status after the timeouts: %+v", s)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	seen := map[string]bool{}
+	for _, c := range codes {
+		seen[c] = true
+	}
+	if !seen["3H8BC1"] || !seen["3BGL4N"] {
+		t.Errorf("E#3BSB6X - Expected the timeout and retry events, got %v", codes)
+	}
+}
+
+func TestRetryThenAutoPause(t *testing.T) {
+	m := NewManager()
+	var calls atomic.Int32
+	_ = m.AddRoutine("flaky", "@hourly", func() appError.Typ {
+		if calls.Add(1)%3 != 0 {
+			return appError.NewError(appError.Error, "3ETCVH", "flaky")
+		}
+		return appError.BlankError
+	}, WithRetry(2, time.Millisecond, 2*time.Millisecond), WithMaxConsecutiveFailures(2))
+	r, _ := m.Get("flaky")
+	markRunning(r).runOnce()
+	if s := r.Status(); s.Runs != 1 || s.Retries != 2 || s.Failures != 0 {
+		t.Errorf("E#3GGEYR - Expected a run succeeding on its last retry, got %+v", s)
+	}
+
+	r.maxRetries = 0
+	r.runOnce()
+	r.runOnce()
+	if !r.IsPaused() || r.Status().FailedInARow != 2 {
+		t.Errorf("E#3AECJX - Expected the routine to be paused after 2 failures in a row, got %+v", r.Status())
+	}
+}
+
+func TestStopCancelsTheRun(t *testing.T) {
+	m := NewManager()
+	cancelled := make(chan struct{})
+	_ = m.AddContextRoutine("long", "@hourly", func(ctx context.Context) appError.Typ {
+		<-ctx.Done()
+		close(cancelled)
+		return appError.BlankError
+	})
+	r, _ := m.Get("long")
+	_ = r.Start(true)
+	time.Sleep(10 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		r.Stop()
+		close(done)
+	}()
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatalf("E#3HN69X - The context of the run was not cancelled")
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("E#3F085C - Stop blocked")
+	}
+}
+
+func TestAbandonedRun(t *testing.T) {
+	m := NewManager()
+	release := make(chan struct{})
+	_ = m.AddRoutine("stuck", "@hourly", func() appError.Typ {
+		<-release
+		return appError.BlankError
+	}, WithTimeout(10*time.Millisecond))
+	r, _ := m.Get("stuck")
+
+	returned := make(chan struct{})
+	go func() {
+		markRunning(r).runOnce()
+		close(returned)
+	}()
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatalf("E#3B5YLG - The stuck run was not abandoned")
+	}
+	r.runOnce()
+	if s := r.Status(); s.Skips != 1 || !s.InstanceRunning {
+		t.Errorf("E#3AT8TN - Expected the next run to be skipped while the abandoned one lasts, got %+v", s)
+	}
+
+	close(release)
+	time.Sleep(20 * time.Millisecond)
+	if r.Status().InstanceRunning {
+		t.Errorf("E#3D1SZ2 - The abandoned run returned but the routine still looks busy")
+	}
+}
+
+func TestAbandonedRunKeepsItsLock(t *testing.T) {
+	locks := distlock.NewMemoryProvider()
+	m := NewManager()
+	release := make(chan struct{})
+	_ = m.AddRoutine("stuck", "@hourly", func() appError.Typ {
+		<-release
+		return appError.BlankError
+	}, WithTimeout(10*time.Millisecond), WithGracePeriod(10*time.Millisecond), WithRunLock(locks, 30*time.Millisecond))
+	r, _ := m.Get("stuck")
+
+	markRunning(r).runOnce()
+	time.Sleep(100 * time.Millisecond)
+	if _, acquired, _ := locks.TryAcquire(context.Background(), "bgroutine:run:stuck", time.Minute); acquired {
+		t.Errorf("E#3AE5OT - The run lock expired while the abandoned run was still going")
+	}
+	close(release)
+}
+
+func TestStopWaitsForTheGracePeriod(t *testing.T) {
+	m := NewManager()
+	var returned atomic.Bool
+	_ = m.AddContextRoutine("slow", "@hourly", func(ctx context.Context) appError.Typ {
+		<-ctx.Done()
+		time.Sleep(150 * time.Millisecond)
+		returned.Store(true)
+		return appError.BlankError
+	}, WithGracePeriod(time.Second))
+	_ = m.AddContextRoutine("stuck", "@hourly", func(ctx context.Context) appError.Typ {
+		select {}
+	}, WithGracePeriod(20*time.Millisecond))
+
+	slow, _ := m.Get("slow")
+	_ = slow.Start(true)
+	stuck, _ := m.Get("stuck")
+	_ = stuck.Start(true)
+	time.Sleep(20 * time.Millisecond)
+
+	slow.Stop()
+	if !returned.Load() {
+		t.Errorf("E#3CIDCF - Stop returned before the function did")
+	}
+	startedAt := time.Now()
+	stuck.Stop()
+	if elapsed := time.Since(startedAt); elapsed > 500*time.Millisecond {
+		t.Errorf("E#3GV0YO - Stop waited %v for a function which does not return", elapsed)
+	}
+}
@@ -0,0 +1,189 @@
+package bgroutine
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/techrail/ground/typs/appError"
+)
+
+// runStats keeps what happened to the runs of a routine. It is guarded by the lock of the routine.
+type runStats struct {
+	runs         int64
+	failures     int64
+	skips        int64
+	retries      int64
+	timeouts     int64
+	failedInARow int // Consecutive failed runs
+	lastRunAt    time.Time
+	lastDuration time.Duration
+	lastResult   appError.Typ
+	nextTickAt   time.Time // Next tick in ticker mode, next scheduled time in cron mode
+	missed       int       // Runs missed while paused in cron mode (handled as per the misfire policy on resume)
+}
+
+// Status is a snapshot of a routine
+type Status struct {
+	Name              string     `json:"name"`
+	State             string     `json:"state"`
+	Mode              string     `json:"mode"`
+	Schedule          string     `json:"schedule"` // Cron expression, or interval in milliseconds
+	InstanceRunning   bool       `json:"instanceRunning"`
+	LastRunAt         *time.Time `json:"lastRunAt,omitempty"`
+	LastRunDurationMs int64      `json:"lastRunDurationMs"`
+	LastResult        string     `json:"lastResult,omitempty"` // The error of the last run (blank if it succeeded)
+	NextRunAt         *time.Time `json:"nextRunAt,omitempty"`  // Only known while the routine is running
+	Runs              int64      `json:"runs"`
+	Failures          int64      `json:"failures"`
+	Skips             int64      `json:"skips"` // Runs skipped as the previous one was not over (or another replica ran it)
+	Retries           int64      `json:"retries"`
+	Timeouts          int64      `json:"timeouts"`
+	FailedInARow      int        `json:"failedInARow"` // Consecutive failed runs
+}
+
+// recordRun records the result of a run, telling if the routine failed too many times in a row and must be paused
+func (r *Typ) recordRun(startedAt time.Time, result appError.Typ) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.runs++
+	if result.IsNotBlank() {
+		r.stats.failures++
+		r.stats.failedInARow++
+	} else {
+		r.stats.failedInARow = 0
+	}
+	r.stats.lastRunAt = startedAt
+	r.stats.lastDuration = time.Since(startedAt)
+	r.stats.lastResult = result
+	return r.maxConsecutiveFailures > 0 && r.stats.failedInARow >= r.maxConsecutiveFailures
+}
+
+func (r *Typ) recordRetry() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.retries++
+}
+
+func (r *Typ) recordTimeout() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.timeouts++
+}
+
+func (r *Typ) recordSkip() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.skips++
+}
+
+// Status returns the snapshot of the routine
+func (r *Typ) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := Status{
+		Name:              r.Name,
+		State:             r.state,
+		Mode:              r.operationMode,
+		Schedule:          r.cronExpr,
+		InstanceRunning:   r.instanceRunning.Load(),
+		LastRunDurationMs: r.stats.lastDuration.Milliseconds(),
+		Runs:              r.stats.runs,
+		Failures:          r.stats.failures,
+		Skips:             r.stats.skips,
+		Retries:           r.stats.retries,
+		Timeouts:          r.stats.timeouts,
+		FailedInARow:      r.stats.failedInARow,
+	}
+	if !r.stats.lastRunAt.IsZero() {
+		lastRunAt := r.stats.lastRunAt
+		s.LastRunAt = &lastRunAt
+	}
+	if r.stats.lastResult.IsNotBlank() {
+		s.LastResult = r.stats.lastResult.Error()
+	}
+	if r.state == StateRunning {
+		next := r.stats.nextTickAt
+		s.NextRunAt = &next
+	}
+	return s
+}
+
+// Get returns the routine with the given name
+func (m *Manager) Get(name string) (*Typ, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	r, found := m.routineMap[name]
+	return r, found
+}
+
+// Remove stops the routine with the given name (see Typ.Stop) and removes it from the manager
+func (m *Manager) Remove(name string) appError.Typ {
+	m.mu.Lock()
+	r, found := m.routineMap[name]
+	delete(m.routineMap, name)
+	m.mu.Unlock()
+	if !found {
+		return appError.NewNetworkError(http.StatusNotFound, appError.Error, "3HFAQI", fmt.Sprintf("No routine named %v", name), "")
+	}
+	r.Stop()
+	return appError.BlankError
+}
+
+// StartAll starts the routines which were not started yet (the paused, running and terminated ones are left alone)
+func (m *Manager) StartAll(launchRightNow bool) appError.Typ {
+	var failed []string
+	for _, r := range m.routines() {
+		if r.GetCurrentState() != StateInitialized {
+			continue
+		}
+		if errTy := r.Start(launchRightNow); errTy.IsNotBlank() {
+			failed = append(failed, fmt.Sprintf("%v (%v)", r.Name, errTy.Message))
+		}
+	}
+	if len(failed) > 0 {
+		return appError.NewError(appError.Error, "3G093J",
+			fmt.Sprintf("Could not start the routines: %v", strings.Join(failed, ", ")))
+	}
+	return appError.BlankError
+}
+
+// PauseAll pauses all the routines
+func (m *Manager) PauseAll() {
+	for _, r := range m.routines() {
+		r.Pause()
+	}
+}
+
+// ResumeAll resumes all the paused routines
+func (m *Manager) ResumeAll() {
+	for _, r := range m.routines() {
+		r.Resume()
+	}
+}
+
+// Snapshot returns the status of all the routines, sorted by name
+func (m *Manager) Snapshot() []Status {
+	routines := m.routines()
+	statuses := make([]Status, len(routines))
+	for i, r := range routines {
+		statuses[i] = r.Status()
+	}
+	return statuses
+}
+
+// routines returns the routines sorted by name
+func (m *Manager) routines() []*Typ {
+	m.mu.RLock()
+	routines := make([]*Typ, 0, len(m.routineMap))
+	for _, r := range m.routineMap {
+		routines = append(routines, r)
+	}
+	m.mu.RUnlock()
+	sort.Slice(routines, func(i, j int) bool {
+		return routines[i].Name < routines[j].Name
+	})
+	return routines
+}
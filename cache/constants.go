@@ -3,5 +3,13 @@ package cache
 const (
 	ModeStandalone = "standalone"
 	ModeCluster    = "cluster"
+	ModeSentinel   = "sentinel"
 	ModeAuto       = "auto"
 )
+
+// How badly the application needs the cache (see RedisConfig.Requirement)
+const (
+	RequirementHardcore = "hardcore" // The application exits if it can not connect to the cache
+	RequirementSoftcore = "softcore" // The application starts anyway and keeps trying to connect in the background
+	RequirementNone     = "none"     // The cache is not used at all
+)
@@ -0,0 +1,736 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/techrail/ground/typs/appError"
+)
+
+// The special values returned by TTL
+const (
+	TTLNoExpiry   = time.Duration(-1) // The key exists but has no expiry
+	TTLKeyMissing = time.Duration(-2) // The key does not exist
+)
+
+// ZMember is a member of a sorted set along with its score
+type ZMember struct {
+	Member string
+	Score  float64
+}
+
+// Key returns the key as stored in redis, i.e. with the namespace of the application (if any) prepended. All the
+// methods of the client apply it, so it is only needed when the Connection is used directly.
+func (c *Client) Key(key string) string {
+	if c.namespace == "" {
+		return key
+	}
+	return c.namespace + ":" + key
+}
+
+func (c *Client) keys(keys []string) []string {
+	nsKeys := make([]string, len(keys))
+	for i, key := range keys {
+		nsKeys[i] = c.Key(key)
+	}
+	return nsKeys
+}
+
+// perKey tells if the multi-key commands must be sent one key at a time: a cluster refuses the keys of different
+// slots in one command (CROSSSLOT), and the cluster client of go-redis does not split them
+func (c *Client) perKey() bool {
+	_, ok := c.Connection.(*goredis.ClusterClient)
+	return ok
+}
+
+// sumPerKey pipelines the command built for each of the (namespaced) keys and sums their replies
+func (c *Client) sumPerKey(ctx context.Context, nsKeys []string, cmd func(p goredis.Pipeliner, nsKey string) *goredis.IntCmd) (int64, error) {
+	cmds := make([]*goredis.IntCmd, len(nsKeys))
+	_, err := c.Connection.Pipelined(ctx, func(p goredis.Pipeliner) error {
+		for i, nsKey := range nsKeys {
+			cmds[i] = cmd(p, nsKey)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	for _, cmd := range cmds {
+		n += cmd.Val()
+	}
+	return n, nil
+}
+
+// stripNamespace is the reverse of Key
+func (c *Client) stripNamespace(key string) string {
+	if c.namespace == "" {
+		return key
+	}
+	return strings.TrimPrefix(key, c.namespace+":")
+}
+
+// expiry returns the TTL to use for a write: the given one if set, AutoExpireTopLevelKeysAfterSeconds otherwise
+func (c *Client) expiry(ttl time.Duration) time.Duration {
+	if ttl > 0 {
+		return ttl
+	}
+	return c.autoExpire
+}
+
+// autoExpireScript sets the expiry (ARGV[1], in milliseconds) of a key which has none, keeping the one the caller set
+var autoExpireScript = goredis.NewScript(`
+if redis.call('PTTL', KEYS[1]) == -1 then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return 0`)
+
+// withAutoExpire runs a write done by a command which can not set the expiry by itself. When the automatic expiry is
+// configured, the write is run in a transaction along with autoExpireScript, so that a key left without an expiry
+// gets the automatic one while an expiry set earlier (e.g. with Expire) is kept.
+func withAutoExpire[T goredis.Cmder](ctx context.Context, c *Client, nsKey string, write func(r goredis.Cmdable) T) (T, error) {
+	if c.autoExpire <= 0 {
+		cmd := write(c.Connection)
+		return cmd, cmd.Err()
+	}
+	var cmd T
+	_, err := c.Connection.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		cmd = write(pipe)
+		autoExpireScript.Eval(ctx, pipe, []string{nsKey}, c.autoExpire.Milliseconds())
+		return nil
+	})
+	if err != nil {
+		return cmd, err
+	}
+	return cmd, cmd.Err()
+}
+
+// opError converts the error of a command. The errors of the resilience policy (e.g. an open circuit breaker) are
+// returned as they are.
+func opError(code, op, key string, err error) appError.Typ {
+	var errTy appError.Typ
+	if errors.As(err, &errTy) {
+		return errTy
+	}
+	return appError.NewError(appError.Error, code, fmt.Sprintf("Redis %v on key %v failed: %v", op, key, err))
+}
+
+// ==== Server ====
+
+// Ping checks the connection to redis
+func (c *Client) Ping(ctx context.Context) appError.Typ {
+	if err := c.Connection.Ping(ctx).Err(); err != nil {
+		return opError("3D3MKH", "PING", "-", err)
+	}
+	return appError.BlankError
+}
+
+// Info returns the output of the INFO command for the given sections
+func (c *Client) Info(ctx context.Context, sections ...string) (string, appError.Typ) {
+	info, err := c.Connection.Info(ctx, sections...).Result()
+	if err != nil {
+		return "", opError("3FQM8M", "INFO", "-", err)
+	}
+	return info, appError.BlankError
+}
+
+// ClusterNodes returns the output of the CLUSTER NODES command
+func (c *Client) ClusterNodes(ctx context.Context) (string, appError.Typ) {
+	nodes, err := c.Connection.ClusterNodes(ctx).Result()
+	if err != nil {
+		return "", opError("3F0NG8", "CLUSTER NODES", "-", err)
+	}
+	return nodes, appError.BlankError
+}
+
+// Close stops the monitoring of the connection and closes the connections
+func (c *Client) Close() appError.Typ {
+	if c.stopMonitor != nil {
+		c.stopMonitor()
+	}
+	if err := c.Connection.Close(); err != nil {
+		return appError.NewError(appError.Error, "3D6AYE", fmt.Sprintf("Could not close the redis client: %v", err))
+	}
+	return appError.BlankError
+}
+
+// ==== Keys ====
+
+// Delete removes the keys and returns how many of them existed. In cluster mode, the keys are deleted one by one
+// (pipelined).
+func (c *Client) Delete(ctx context.Context, keys ...string) (int64, appError.Typ) {
+	var n int64
+	var err error
+	if c.perKey() {
+		n, err = c.sumPerKey(ctx, c.keys(keys), func(p goredis.Pipeliner, nsKey string) *goredis.IntCmd {
+			return p.Del(ctx, nsKey)
+		})
+	} else {
+		n, err = c.Connection.Del(ctx, c.keys(keys)...).Result()
+	}
+	if err != nil {
+		return 0, opError("3A0NYY", "DEL", strings.Join(keys, ","), err)
+	}
+	return n, appError.BlankError
+}
+
+// Exists returns how many of the keys exist. In cluster mode, the keys are checked one by one (pipelined).
+func (c *Client) Exists(ctx context.Context, keys ...string) (int64, appError.Typ) {
+	var n int64
+	var err error
+	if c.perKey() {
+		n, err = c.sumPerKey(ctx, c.keys(keys), func(p goredis.Pipeliner, nsKey string) *goredis.IntCmd {
+			return p.Exists(ctx, nsKey)
+		})
+	} else {
+		n, err = c.Connection.Exists(ctx, c.keys(keys)...).Result()
+	}
+	if err != nil {
+		return 0, opError("3F64XG", "EXISTS", strings.Join(keys, ","), err)
+	}
+	return n, appError.BlankError
+}
+
+// Expire sets the time to live of the key. It tells if the key exists.
+func (c *Client) Expire(ctx context.Context, key string, ttl time.Duration) (bool, appError.Typ) {
+	ok, err := c.Connection.Expire(ctx, c.Key(key), ttl).Result()
+	if err != nil {
+		return false, opError("3C71XJ", "EXPIRE", key, err)
+	}
+	return ok, appError.BlankError
+}
+
+// Persist removes the time to live of the key. It tells if the key existed and had one.
+func (c *Client) Persist(ctx context.Context, key string) (bool, appError.Typ) {
+	ok, err := c.Connection.Persist(ctx, c.Key(key)).Result()
+	if err != nil {
+		return false, opError("3E0KE1", "PERSIST", key, err)
+	}
+	return ok, appError.BlankError
+}
+
+// TTL returns the remaining time to live of the key, TTLNoExpiry if it has none or TTLKeyMissing if it does not exist
+func (c *Client) TTL(ctx context.Context, key string) (time.Duration, appError.Typ) {
+	ttl, err := c.Connection.PTTL(ctx, c.Key(key)).Result()
+	if err != nil {
+		return 0, opError("3BPO9X", "PTTL", key, err)
+	}
+	switch ttl {
+	case -1, -1 * time.Millisecond:
+		return TTLNoExpiry, appError.BlankError
+	case -2, -2 * time.Millisecond:
+		return TTLKeyMissing, appError.BlankError
+	}
+	return ttl, appError.BlankError
+}
+
+// ScanEach calls fn for every key matching the pattern (e.g. `user:*`) until it returns false. The keys (and the
+// pattern) do not include the namespace. In cluster mode, all the master nodes are scanned. A key may be seen more
+// than once if the keyspace changes while it is being scanned.
+func (c *Client) ScanEach(ctx context.Context, match string, fn func(key string) bool) appError.Typ {
+	match = c.Key(match)
+	var mu sync.Mutex
+	stopped := false
+	scan := func(ctx context.Context, client goredis.Cmdable) error {
+		iter := client.Scan(ctx, 0, match, 100).Iterator()
+		for iter.Next(ctx) {
+			mu.Lock()
+			if !stopped && !fn(c.stripNamespace(iter.Val())) {
+				stopped = true
+			}
+			done := stopped
+			mu.Unlock()
+			if done {
+				return nil
+			}
+		}
+		return iter.Err()
+	}
+
+	var err error
+	if cluster, ok := c.Connection.(*goredis.ClusterClient); ok {
+		err = cluster.ForEachMaster(ctx, func(ctx context.Context, node *goredis.Client) error {
+			return scan(ctx, node)
+		})
+	} else {
+		err = scan(ctx, c.Connection)
+	}
+	if err != nil {
+		return opError("3AIU46", "SCAN", match, err)
+	}
+	return appError.BlankError
+}
+
+// Scan returns all the keys matching the pattern (see ScanEach)
+func (c *Client) Scan(ctx context.Context, match string) ([]string, appError.Typ) {
+	var keys []string
+	errTy := c.ScanEach(ctx, match, func(key string) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys, errTy
+}
+
+// ==== Strings ====
+
+// Get returns the value of the key and whether it exists
+func (c *Client) Get(ctx context.Context, key string) (string, bool, appError.Typ) {
+	val, err := c.Connection.Get(ctx, c.Key(key)).Result()
+	if errors.Is(err, goredis.Nil) {
+		return "", false, appError.BlankError
+	}
+	if err != nil {
+		return "", false, opError("3G8QA0", "GET", key, err)
+	}
+	return val, true, appError.BlankError
+}
+
+// Set stores the value against the key. A ttl of 0 means the automatic expiry (if configured) or no expiry.
+func (c *Client) Set(ctx context.Context, key string, value any, ttl time.Duration) appError.Typ {
+	if err := c.Connection.Set(ctx, c.Key(key), value, c.expiry(ttl)).Err(); err != nil {
+		return opError("3B59OL", "SET", key, err)
+	}
+	return appError.BlankError
+}
+
+// SetNX stores the value against the key only if the key does not exist. It tells if it did.
+func (c *Client) SetNX(ctx context.Context, key string, value any, ttl time.Duration) (bool, appError.Typ) {
+	ok, err := c.Connection.SetNX(ctx, c.Key(key), value, c.expiry(ttl)).Result()
+	if err != nil {
+		return false, opError("3BI9JJ", "SETNX", key, err)
+	}
+	return ok, appError.BlankError
+}
+
+// GetDel returns the value of the key (and whether it existed) and deletes it. Needs redis 6.2 or later.
+func (c *Client) GetDel(ctx context.Context, key string) (string, bool, appError.Typ) {
+	val, err := c.Connection.GetDel(ctx, c.Key(key)).Result()
+	if errors.Is(err, goredis.Nil) {
+		return "", false, appError.BlankError
+	}
+	if err != nil {
+		return "", false, opError("3EU00K", "GETDEL", key, err)
+	}
+	return val, true, appError.BlankError
+}
+
+// MGet returns the values of the keys. The missing keys are not present in the map. In cluster mode, the keys are
+// read one by one (pipelined).
+func (c *Client) MGet(ctx context.Context, keys ...string) (map[string]string, appError.Typ) {
+	if c.perKey() {
+		return c.getPerKey(ctx, keys)
+	}
+	vals, err := c.Connection.MGet(ctx, c.keys(keys)...).Result()
+	if err != nil {
+		return nil, opError("3FRNPB", "MGET", strings.Join(keys, ","), err)
+	}
+	found := make(map[string]string, len(keys))
+	for i, val := range vals {
+		if s, ok := val.(string); ok {
+			found[keys[i]] = s
+		}
+	}
+	return found, appError.BlankError
+}
+
+// getPerKey is MGet in cluster mode: the keys are read one by one (pipelined), see perKey
+func (c *Client) getPerKey(ctx context.Context, keys []string) (map[string]string, appError.Typ) {
+	cmds := make([]*goredis.StringCmd, len(keys))
+	// The error of the pipeline is that of its first failed command, which can be a missing key
+	_, _ = c.Connection.Pipelined(ctx, func(p goredis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = p.Get(ctx, c.Key(key))
+		}
+		return nil
+	})
+	found := make(map[string]string, len(keys))
+	for i, cmd := range cmds {
+		val, err := cmd.Result()
+		switch {
+		case err == nil:
+			found[keys[i]] = val
+		case !errors.Is(err, goredis.Nil):
+			return nil, opError("3DDQF3", "MGET", strings.Join(keys, ","), err)
+		}
+	}
+	return found, appError.BlankError
+}
+
+// Incr increments the integer value of the key by one and returns the new value
+func (c *Client) Incr(ctx context.Context, key string) (int64, appError.Typ) {
+	return c.IncrBy(ctx, key, 1)
+}
+
+// IncrBy increments the integer value of the key by the given amount and returns the new value. A missing key is
+// taken as 0.
+func (c *Client) IncrBy(ctx context.Context, key string, by int64) (int64, appError.Typ) {
+	cmd, err := withAutoExpire(ctx, c, c.Key(key), func(r goredis.Cmdable) *goredis.IntCmd {
+		return r.IncrBy(ctx, c.Key(key), by)
+	})
+	if err != nil {
+		return 0, opError("3HZB33", "INCRBY", key, err)
+	}
+	return cmd.Val(), appError.BlankError
+}
+
+// ==== Lists ====
+
+// LPush prepends the values to the list and returns its length
+func (c *Client) LPush(ctx context.Context, key string, values ...any) (int64, appError.Typ) {
+	cmd, err := withAutoExpire(ctx, c, c.Key(key), func(r goredis.Cmdable) *goredis.IntCmd {
+		return r.LPush(ctx, c.Key(key), values...)
+	})
+	if err != nil {
+		return 0, opError("3DONKQ", "LPUSH", key, err)
+	}
+	return cmd.Val(), appError.BlankError
+}
+
+// RPush appends the values to the list and returns its length
+func (c *Client) RPush(ctx context.Context, key string, values ...any) (int64, appError.Typ) {
+	cmd, err := withAutoExpire(ctx, c, c.Key(key), func(r goredis.Cmdable) *goredis.IntCmd {
+		return r.RPush(ctx, c.Key(key), values...)
+	})
+	if err != nil {
+		return 0, opError("3HXJGQ", "RPUSH", key, err)
+	}
+	return cmd.Val(), appError.BlankError
+}
+
+// LPop removes and returns the first element of the list (and whether there was one)
+func (c *Client) LPop(ctx context.Context, key string) (string, bool, appError.Typ) {
+	val, err := c.Connection.LPop(ctx, c.Key(key)).Result()
+	if errors.Is(err, goredis.Nil) {
+		return "", false, appError.BlankError
+	}
+	if err != nil {
+		return "", false, opError("3DDUUE", "LPOP", key, err)
+	}
+	return val, true, appError.BlankError
+}
+
+// RPop removes and returns the last element of the list (and whether there was one)
+func (c *Client) RPop(ctx context.Context, key string) (string, bool, appError.Typ) {
+	val, err := c.Connection.RPop(ctx, c.Key(key)).Result()
+	if errors.Is(err, goredis.Nil) {
+		return "", false, appError.BlankError
+	}
+	if err != nil {
+		return "", false, opError("3D52FW", "RPOP", key, err)
+	}
+	return val, true, appError.BlankError
+}
+
+// LRange returns the elements of the list between the two (inclusive, possibly negative) indexes
+func (c *Client) LRange(ctx context.Context, key string, start, stop int64) ([]string, appError.Typ) {
+	vals, err := c.Connection.LRange(ctx, c.Key(key), start, stop).Result()
+	if err != nil {
+		return nil, opError("3F7U5P", "LRANGE", key, err)
+	}
+	return vals, appError.BlankError
+}
+
+// LLen returns the length of the list
+func (c *Client) LLen(ctx context.Context, key string) (int64, appError.Typ) {
+	n, err := c.Connection.LLen(ctx, c.Key(key)).Result()
+	if err != nil {
+		return 0, opError("3DJRPI", "LLEN", key, err)
+	}
+	return n, appError.BlankError
+}
+
+// LRem removes `count` occurrences of the value from the list (all of them if count is 0; from the tail if it is
+// negative) and returns how many were removed
+func (c *Client) LRem(ctx context.Context, key string, count int64, value any) (int64, appError.Typ) {
+	n, err := c.Connection.LRem(ctx, c.Key(key), count, value).Result()
+	if err != nil {
+		return 0, opError("3AFWFX", "LREM", key, err)
+	}
+	return n, appError.BlankError
+}
+
+// LTrim keeps only the elements of the list between the two (inclusive, possibly negative) indexes
+func (c *Client) LTrim(ctx context.Context, key string, start, stop int64) appError.Typ {
+	if err := c.Connection.LTrim(ctx, c.Key(key), start, stop).Err(); err != nil {
+		return opError("3AOT1K", "LTRIM", key, err)
+	}
+	return appError.BlankError
+}
+
+// ==== Hashes ====
+
+// HSet sets the fields of the hash and returns how many of them were added
+func (c *Client) HSet(ctx context.Context, key string, fields map[string]any) (int64, appError.Typ) {
+	cmd, err := withAutoExpire(ctx, c, c.Key(key), func(r goredis.Cmdable) *goredis.IntCmd {
+		return r.HSet(ctx, c.Key(key), fields)
+	})
+	if err != nil {
+		return 0, opError("3F4KZI", "HSET", key, err)
+	}
+	return cmd.Val(), appError.BlankError
+}
+
+// HGet returns the value of the field of the hash (and whether it exists)
+func (c *Client) HGet(ctx context.Context, key, field string) (string, bool, appError.Typ) {
+	val, err := c.Connection.HGet(ctx, c.Key(key), field).Result()
+	if errors.Is(err, goredis.Nil) {
+		return "", false, appError.BlankError
+	}
+	if err != nil {
+		return "", false, opError("3CZVHR", "HGET", key, err)
+	}
+	return val, true, appError.BlankError
+}
+
+// HMGet returns the values of the fields of the hash. The missing fields are not present in the map.
+func (c *Client) HMGet(ctx context.Context, key string, fields ...string) (map[string]string, appError.Typ) {
+	vals, err := c.Connection.HMGet(ctx, c.Key(key), fields...).Result()
+	if err != nil {
+		return nil, opError("3GB5F4", "HMGET", key, err)
+	}
+	found := make(map[string]string, len(fields))
+	for i, val := range vals {
+		if s, ok := val.(string); ok {
+			found[fields[i]] = s
+		}
+	}
+	return found, appError.BlankError
+}
+
+// HGetAll returns all the fields of the hash
+func (c *Client) HGetAll(ctx context.Context, key string) (map[string]string, appError.Typ) {
+	vals, err := c.Connection.HGetAll(ctx, c.Key(key)).Result()
+	if err != nil {
+		return nil, opError("3BNKNX", "HGETALL", key, err)
+	}
+	return vals, appError.BlankError
+}
+
+// HDel removes the fields from the hash and returns how many of them existed
+func (c *Client) HDel(ctx context.Context, key string, fields ...string) (int64, appError.Typ) {
+	n, err := c.Connection.HDel(ctx, c.Key(key), fields...).Result()
+	if err != nil {
+		return 0, opError("3GINYR", "HDEL", key, err)
+	}
+	return n, appError.BlankError
+}
+
+// HExists tells if the field exists in the hash
+func (c *Client) HExists(ctx context.Context, key, field string) (bool, appError.Typ) {
+	ok, err := c.Connection.HExists(ctx, c.Key(key), field).Result()
+	if err != nil {
+		return false, opError("3FCRD0", "HEXISTS", key, err)
+	}
+	return ok, appError.BlankError
+}
+
+// HIncrBy increments the integer value of the field of the hash and returns the new value
+func (c *Client) HIncrBy(ctx context.Context, key, field string, by int64) (int64, appError.Typ) {
+	cmd, err := withAutoExpire(ctx, c, c.Key(key), func(r goredis.Cmdable) *goredis.IntCmd {
+		return r.HIncrBy(ctx, c.Key(key), field, by)
+	})
+	if err != nil {
+		return 0, opError("3B7BMY", "HINCRBY", key, err)
+	}
+	return cmd.Val(), appError.BlankError
+}
+
+// HLen returns the number of fields of the hash
+func (c *Client) HLen(ctx context.Context, key string) (int64, appError.Typ) {
+	n, err := c.Connection.HLen(ctx, c.Key(key)).Result()
+	if err != nil {
+		return 0, opError("3FS6RW", "HLEN", key, err)
+	}
+	return n, appError.BlankError
+}
+
+// ==== Sets ====
+
+// SAdd adds the members to the set and returns how many of them were not there already
+func (c *Client) SAdd(ctx context.Context, key string, members ...any) (int64, appError.Typ) {
+	cmd, err := withAutoExpire(ctx, c, c.Key(key), func(r goredis.Cmdable) *goredis.IntCmd {
+		return r.SAdd(ctx, c.Key(key), members...)
+	})
+	if err != nil {
+		return 0, opError("3C7F4G", "SADD", key, err)
+	}
+	return cmd.Val(), appError.BlankError
+}
+
+// SRem removes the members from the set and returns how many of them were there
+func (c *Client) SRem(ctx context.Context, key string, members ...any) (int64, appError.Typ) {
+	n, err := c.Connection.SRem(ctx, c.Key(key), members...).Result()
+	if err != nil {
+		return 0, opError("3HNC18", "SREM", key, err)
+	}
+	return n, appError.BlankError
+}
+
+// SMembers returns the members of the set
+func (c *Client) SMembers(ctx context.Context, key string) ([]string, appError.Typ) {
+	vals, err := c.Connection.SMembers(ctx, c.Key(key)).Result()
+	if err != nil {
+		return nil, opError("3DADRW", "SMEMBERS", key, err)
+	}
+	return vals, appError.BlankError
+}
+
+// SIsMember tells if the value is a member of the set
+func (c *Client) SIsMember(ctx context.Context, key string, member any) (bool, appError.Typ) {
+	ok, err := c.Connection.SIsMember(ctx, c.Key(key), member).Result()
+	if err != nil {
+		return false, opError("3A2DM5", "SISMEMBER", key, err)
+	}
+	return ok, appError.BlankError
+}
+
+// SCard returns the number of members of the set
+func (c *Client) SCard(ctx context.Context, key string) (int64, appError.Typ) {
+	n, err := c.Connection.SCard(ctx, c.Key(key)).Result()
+	if err != nil {
+		return 0, opError("3CE5IW", "SCARD", key, err)
+	}
+	return n, appError.BlankError
+}
+
+// SPop removes and returns a random member of the set (and whether there was one)
+func (c *Client) SPop(ctx context.Context, key string) (string, bool, appError.Typ) {
+	val, err := c.Connection.SPop(ctx, c.Key(key)).Result()
+	if errors.Is(err, goredis.Nil) {
+		return "", false, appError.BlankError
+	}
+	if err != nil {
+		return "", false, opError("3CAOOZ", "SPOP", key, err)
+	}
+	return val, true, appError.BlankError
+}
+
+// ==== Sorted sets ====
+
+// ZAdd adds the members to the sorted set (updating the scores of the existing ones) and returns how many of them
+// were added
+func (c *Client) ZAdd(ctx context.Context, key string, members ...ZMember) (int64, appError.Typ) {
+	zs := make([]goredis.Z, len(members))
+	for i, m := range members {
+		zs[i] = goredis.Z{Score: m.Score, Member: m.Member}
+	}
+	cmd, err := withAutoExpire(ctx, c, c.Key(key), func(r goredis.Cmdable) *goredis.IntCmd {
+		return r.ZAdd(ctx, c.Key(key), zs...)
+	})
+	if err != nil {
+		return 0, opError("3BMNS6", "ZADD", key, err)
+	}
+	return cmd.Val(), appError.BlankError
+}
+
+// ZRem removes the members from the sorted set and returns how many of them were there
+func (c *Client) ZRem(ctx context.Context, key string, members ...string) (int64, appError.Typ) {
+	anyMembers := make([]any, len(members))
+	for i, m := range members {
+		anyMembers[i] = m
+	}
+	n, err := c.Connection.ZRem(ctx, c.Key(key), anyMembers...).Result()
+	if err != nil {
+		return 0, opError("3B0YHX", "ZREM", key, err)
+	}
+	return n, appError.BlankError
+}
+
+// ZScore returns the score of the member (and whether it is in the sorted set)
+func (c *Client) ZScore(ctx context.Context, key, member string) (float64, bool, appError.Typ) {
+	score, err := c.Connection.ZScore(ctx, c.Key(key), member).Result()
+	if errors.Is(err, goredis.Nil) {
+		return 0, false, appError.BlankError
+	}
+	if err != nil {
+		return 0, false, opError("3C4HD8", "ZSCORE", key, err)
+	}
+	return score, true, appError.BlankError
+}
+
+// ZIncrBy increments the score of the member (adding it if needed) and returns the new score
+func (c *Client) ZIncrBy(ctx context.Context, key, member string, by float64) (float64, appError.Typ) {
+	cmd, err := withAutoExpire(ctx, c, c.Key(key), func(r goredis.Cmdable) *goredis.FloatCmd {
+		return r.ZIncrBy(ctx, c.Key(key), by, member)
+	})
+	if err != nil {
+		return 0, opError("3A7OQJ", "ZINCRBY", key, err)
+	}
+	return cmd.Val(), appError.BlankError
+}
+
+// ZRank returns the rank (by ascending score, starting at 0) of the member (and whether it is in the sorted set)
+func (c *Client) ZRank(ctx context.Context, key, member string) (int64, bool, appError.Typ) {
+	rank, err := c.Connection.ZRank(ctx, c.Key(key), member).Result()
+	if errors.Is(err, goredis.Nil) {
+		return 0, false, appError.BlankError
+	}
+	if err != nil {
+		return 0, false, opError("3HNSMR", "ZRANK", key, err)
+	}
+	return rank, true, appError.BlankError
+}
+
+// ZRange returns the members between the two (inclusive, possibly negative) ranks, by ascending score
+func (c *Client) ZRange(ctx context.Context, key string, start, stop int64) ([]ZMember, appError.Typ) {
+	zs, err := c.Connection.ZRangeWithScores(ctx, c.Key(key), start, stop).Result()
+	if err != nil {
+		return nil, opError("3A3QU6", "ZRANGE", key, err)
+	}
+	return toZMembers(zs), appError.BlankError
+}
+
+// ZRevRange returns the members between the two (inclusive, possibly negative) ranks, by descending score
+func (c *Client) ZRevRange(ctx context.Context, key string, start, stop int64) ([]ZMember, appError.Typ) {
+	zs, err := c.Connection.ZRevRangeWithScores(ctx, c.Key(key), start, stop).Result()
+	if err != nil {
+		return nil, opError("3G80Q0", "ZREVRANGE", key, err)
+	}
+	return toZMembers(zs), appError.BlankError
+}
+
+// ZRangeByScore returns the members with a score between min and max (e.g. "1", "(1" for an exclusive bound, "-inf",
+// "+inf"), by ascending score. A count of 0 means no limit.
+func (c *Client) ZRangeByScore(ctx context.Context, key, min, max string, offset, count int64) ([]ZMember, appError.Typ) {
+	zs, err := c.Connection.ZRangeByScoreWithScores(ctx, c.Key(key), &goredis.ZRangeBy{
+		Min: min, Max: max, Offset: offset, Count: count,
+	}).Result()
+	if err != nil {
+		return nil, opError("3FJDFP", "ZRANGEBYSCORE", key, err)
+	}
+	return toZMembers(zs), appError.BlankError
+}
+
+// ZRemRangeByScore removes the members with a score between min and max (see ZRangeByScore) and returns how many
+// were removed
+func (c *Client) ZRemRangeByScore(ctx context.Context, key, min, max string) (int64, appError.Typ) {
+	n, err := c.Connection.ZRemRangeByScore(ctx, c.Key(key), min, max).Result()
+	if err != nil {
+		return 0, opError("3D7GUH", "ZREMRANGEBYSCORE", key, err)
+	}
+	return n, appError.BlankError
+}
+
+// ZCard returns the number of members of the sorted set
+func (c *Client) ZCard(ctx context.Context, key string) (int64, appError.Typ) {
+	n, err := c.Connection.ZCard(ctx, c.Key(key)).Result()
+	if err != nil {
+		return 0, opError("3EDJEC", "ZCARD", key, err)
+	}
+	return n, appError.BlankError
+}
+
+func toZMembers(zs []goredis.Z) []ZMember {
+	members := make([]ZMember, len(zs))
+	for i, z := range zs {
+		member, _ := z.Member.(string)
+		members[i] = ZMember{Member: member, Score: z.Score}
+	}
+	return members
+}
@@ -0,0 +1,249 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// newTestClient connects a client (in the `app` namespace) to an in-process server
+func newTestClient(t *testing.T, autoExpireSeconds int) (*Client, *miniredis.Miniredis) {
+	srv := miniredis.RunT(t)
+	c, errTy := CreateNewRedisClient(RedisConfig{
+		Enabled:                            true,
+		Url:                                "redis://" + srv.Addr(),
+		OperationMode:                      ModeStandalone,
+		Requirement:                        RequirementHardcore,
+		AppNamespace:                       "app",
+		AutoExpireTopLevelKeysAfterSeconds: autoExpireSeconds,
+	})
+	if errTy.IsNotBlank() {
+		t.Fatalf("E#3A1AEG - Could not connect to the test server: %v", errTy)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c, srv
+}
+
+func TestRedisOps_KeysAndStrings(t *testing.T) {
+	c, srv := newTestClient(t, 0)
+	ctx := context.Background()
+
+	if errTy := c.Set(ctx, "a", "1", time.Minute); errTy.IsNotBlank() {
+		t.Fatalf("E#3HLKJI - Set failed: %v", errTy)
+	}
+	if !srv.Exists("app:a") {
+		t.Errorf("E#3CRFQA - The key was not namespaced")
+	}
+	if val, found, _ := c.Get(ctx, "a"); !found || val != "1" {
+		t.Errorf("E#3E7A7N - Get returned %q (found: %v); want 1", val, found)
+	}
+	if _, found, errTy := c.Get(ctx, "missing"); found || errTy.IsNotBlank() {
+		t.Errorf("E#3HBSKR - Get of a missing key returned found: %v, error: %v", found, errTy)
+	}
+	if ok, _ := c.SetNX(ctx, "a", "2", 0); ok {
+		t.Errorf("E#3A1RUY - SetNX overwrote an existing key")
+	}
+	_ = c.Set(ctx, "b", "2", 0)
+	if vals, _ := c.MGet(ctx, "a", "b", "missing"); len(vals) != 2 || vals["a"] != "1" || vals["b"] != "2" {
+		t.Errorf("E#3EMPYD - MGet returned %v", vals)
+	}
+	if n, _ := c.Exists(ctx, "a", "b", "missing"); n != 2 {
+		t.Errorf("E#3EP42E - Exists returned %v; want 2", n)
+	}
+	if keys, _ := c.Scan(ctx, "*"); !slices.Equal(sorted(keys), []string{"a", "b"}) {
+		t.Errorf("E#3FIMNP - Scan returned %v; want the keys without the namespace", keys)
+	}
+
+	if ttl, _ := c.TTL(ctx, "b"); ttl != TTLNoExpiry {
+		t.Errorf("E#3CY271 - TTL of a key without expiry is %v", ttl)
+	}
+	if ttl, _ := c.TTL(ctx, "missing"); ttl != TTLKeyMissing {
+		t.Errorf("E#3BKMBP - TTL of a missing key is %v", ttl)
+	}
+	if ok, _ := c.Persist(ctx, "a"); !ok {
+		t.Errorf("E#3C4E3O - Persist did not remove the expiry")
+	}
+
+	if n, _ := c.IncrBy(ctx, "counter", 5); n != 5 {
+		t.Errorf("E#3G7HKG - IncrBy returned %v; want 5", n)
+	}
+	if n, _ := c.Incr(ctx, "counter"); n != 6 {
+		t.Errorf("E#3GDBWK - Incr returned %v; want 6", n)
+	}
+	if val, found, _ := c.GetDel(ctx, "counter"); !found || val != "6" || srv.Exists("app:counter") {
+		t.Errorf("E#3EPMHN - GetDel returned %q (found: %v) or did not delete the key", val, found)
+	}
+	if n, _ := c.Delete(ctx, "a", "b", "missing"); n != 2 {
+		t.Errorf("E#3GIALD - Delete returned %v; want 2", n)
+	}
+}
+
+func TestRedisOps_Collections(t *testing.T) {
+	c, _ := newTestClient(t, 0)
+	ctx := context.Background()
+
+	_, _ = c.RPush(ctx, "list", "b", "c")
+	if n, _ := c.LPush(ctx, "list", "a"); n != 3 {
+		t.Errorf("E#3BMTG9 - LPush returned %v; want 3", n)
+	}
+	if vals, _ := c.LRange(ctx, "list", 0, -1); !slices.Equal(vals, []string{"a", "b", "c"}) {
+		t.Errorf("E#3AT7YU - LRange returned %v", vals)
+	}
+	if val, found, _ := c.LPop(ctx, "list"); !found || val != "a" {
+		t.Errorf("E#3AMIXR - LPop returned %q", val)
+	}
+	if val, found, _ := c.RPop(ctx, "list"); !found || val != "c" {
+		t.Errorf("E#3HB8M0 - RPop returned %q", val)
+	}
+
+	if n, _ := c.HSet(ctx, "hash", map[string]any{"x": "1", "y": "2"}); n != 2 {
+		t.Errorf("E#3EWGL9 - HSet returned %v; want 2", n)
+	}
+	if n, _ := c.HIncrBy(ctx, "hash", "x", 2); n != 3 {
+		t.Errorf("E#3BM4D9 - HIncrBy returned %v; want 3", n)
+	}
+	if vals, _ := c.HMGet(ctx, "hash", "x", "missing"); len(vals) != 1 || vals["x"] != "3" {
+		t.Errorf("E#3E1KLN - HMGet returned %v", vals)
+	}
+	if _, found, _ := c.HGet(ctx, "hash", "missing"); found {
+		t.Errorf("E#3CAG28 - HGet found a missing field")
+	}
+
+	if n, _ := c.SAdd(ctx, "set", "a", "b", "a"); n != 2 {
+		t.Errorf("E#3DCF0N - SAdd returned %v; want 2", n)
+	}
+	if ok, _ := c.SIsMember(ctx, "set", "b"); !ok {
+		t.Errorf("E#3EUM4G - SIsMember did not find a member")
+	}
+
+	if n, _ := c.ZAdd(ctx, "zset", ZMember{Member: "a", Score: 2}, ZMember{Member: "b", Score: 1}); n != 2 {
+		t.Errorf("E#3GJW00 - ZAdd returned %v; want 2", n)
+	}
+	if score, _ := c.ZIncrBy(ctx, "zset", "b", 5); score != 6 {
+		t.Errorf("E#3GCQH5 - ZIncrBy returned %v; want 6", score)
+	}
+	if members, _ := c.ZRange(ctx, "zset", 0, -1); len(members) != 2 || members[0].Member != "a" || members[1].Score != 6 {
+		t.Errorf("E#3H9GZX - ZRange returned %v", members)
+	}
+	if rank, found, _ := c.ZRank(ctx, "zset", "b"); !found || rank != 1 {
+		t.Errorf("E#3E6F82 - ZRank returned %v (found: %v); want 1", rank, found)
+	}
+}
+
+func TestRedisOps_AutoExpireKeepsTheExpiryOfTheCaller(t *testing.T) {
+	c, srv := newTestClient(t, 60)
+	ctx := context.Background()
+
+	_, _ = c.LPush(ctx, "fresh", "a")
+	if ttl := srv.TTL("app:fresh"); ttl != time.Minute {
+		t.Errorf("E#3DGUWC - A new list got the expiry %v; want the automatic one", ttl)
+	}
+
+	_, _ = c.LPush(ctx, "list", "a")
+	_, _ = c.Expire(ctx, "list", time.Hour)
+	_, _ = c.LPush(ctx, "list", "b")
+	_, _ = c.HSet(ctx, "hash", map[string]any{"x": 1})
+	_, _ = c.Expire(ctx, "hash", time.Hour)
+	_, _ = c.HSet(ctx, "hash", map[string]any{"y": 2})
+	_, _ = c.SAdd(ctx, "set", "a")
+	_, _ = c.Expire(ctx, "set", time.Hour)
+	_, _ = c.SAdd(ctx, "set", "b")
+	_, _ = c.ZAdd(ctx, "zset", ZMember{Member: "a", Score: 1})
+	_, _ = c.Expire(ctx, "zset", time.Hour)
+	_, _ = c.ZAdd(ctx, "zset", ZMember{Member: "b", Score: 2})
+	_, _ = c.Incr(ctx, "counter")
+	_, _ = c.Expire(ctx, "counter", time.Hour)
+	_, _ = c.Incr(ctx, "counter")
+	for _, key := range []string{"list", "hash", "set", "zset", "counter"} {
+		if ttl := srv.TTL("app:" + key); ttl != time.Hour {
+			t.Errorf("E#3AT122 - The expiry of %v was replaced with %v", key, ttl)
+		}
+	}
+
+	if n, errTy := c.LLen(ctx, "list"); n != 2 || errTy.IsNotBlank() {
+		t.Errorf("E#3C6HO6 - The writes did not go through (length %v, error %v)", n, errTy)
+	}
+}
+
+func sorted(s []string) []string {
+	s = slices.Clone(s)
+	slices.Sort(s)
+	return s
+}
+
+// crossSlotHook refuses the multi-key commands the way a cluster does when the keys are in different slots
+type crossSlotHook struct{}
+
+func (crossSlotHook) DialHook(next goredis.DialHook) goredis.DialHook {
+	return next
+}
+
+func (crossSlotHook) ProcessHook(next goredis.ProcessHook) goredis.ProcessHook {
+	return func(ctx context.Context, cmd goredis.Cmder) error {
+		if err := crossSlot(cmd); err != nil {
+			return err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (crossSlotHook) ProcessPipelineHook(next goredis.ProcessPipelineHook) goredis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []goredis.Cmder) error {
+		for _, cmd := range cmds {
+			if err := crossSlot(cmd); err != nil {
+				return err
+			}
+		}
+		return next(ctx, cmds)
+	}
+}
+
+func crossSlot(cmd goredis.Cmder) error {
+	switch cmd.Name() {
+	case "del", "exists", "mget":
+		if len(cmd.Args()) > 2 {
+			err := errors.New("CROSSSLOT Keys in request don't hash to the same slot")
+			cmd.SetErr(err)
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRedisOps_MultiKeyCommandsInClusterMode(t *testing.T) {
+	srv := miniredis.RunT(t)
+	c, errTy := CreateNewRedisClient(RedisConfig{
+		Enabled:       true,
+		Url:           "redis://" + srv.Addr(),
+		OperationMode: ModeCluster,
+		Requirement:   RequirementHardcore,
+		AppNamespace:  "app",
+	})
+	if errTy.IsNotBlank() {
+		t.Fatalf("E#3GX1CF - Could not connect to the test server: %v", errTy)
+	}
+	t.Cleanup(func() { c.Close() })
+	c.Connection.AddHook(crossSlotHook{})
+	ctx := context.Background()
+
+	_ = srv.Set("app:a", "1")
+	_ = srv.Set("app:b", "2")
+	if n, errTy := c.Exists(ctx, "a", "b", "missing"); errTy.IsNotBlank() || n != 2 {
+		t.Errorf("E#3GKDF7 - Exists gave %v, %v", n, errTy)
+	}
+	vals, errTy := c.MGet(ctx, "a", "missing", "b")
+	if errTy.IsNotBlank() || len(vals) != 2 || vals["a"] != "1" || vals["b"] != "2" {
+		t.Errorf("E#3E5AHI - MGet gave %v, %v", vals, errTy)
+	}
+	if n, errTy := c.Delete(ctx, "a", "b", "missing"); errTy.IsNotBlank() || n != 2 {
+		t.Errorf("E#3HMWL2 - Delete gave %v, %v", n, errTy)
+	}
+	if srv.Exists("app:a") || srv.Exists("app:b") {
+		t.Errorf("E#3HHG41 - The keys were not deleted")
+	}
+}
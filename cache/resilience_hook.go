@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"context"
+	"errors"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/techrail/ground/resilience"
+)
+
+// resilienceHook runs the redis commands (and pipelines) through a resilience policy. A rejected command gets the
+// error of the policy, so that callers see it through the usual `cmd.Err()`.
+type resilienceHook struct {
+	policy *resilience.Policy
+}
+
+func (h resilienceHook) DialHook(next goredis.DialHook) goredis.DialHook {
+	return next
+}
+
+func (h resilienceHook) ProcessHook(next goredis.ProcessHook) goredis.ProcessHook {
+	return func(ctx context.Context, cmd goredis.Cmder) error {
+		err := h.policy.Run(ctx, func(ctx context.Context) error {
+			return next(ctx, cmd)
+		}, isRedisNil)
+		if err != nil && cmd.Err() == nil {
+			cmd.SetErr(err)
+		}
+		return err
+	}
+}
+
+func (h resilienceHook) ProcessPipelineHook(next goredis.ProcessPipelineHook) goredis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []goredis.Cmder) error {
+		err := h.policy.Run(ctx, func(ctx context.Context) error {
+			return next(ctx, cmds)
+		}, isRedisNil)
+		if err != nil {
+			for _, cmd := range cmds {
+				if cmd.Err() == nil {
+					cmd.SetErr(err)
+				}
+			}
+		}
+		return err
+	}
+}
+
+// isRedisNil tells if the error only means that the key does not exist (which is not a failure of redis)
+func isRedisNil(err error) bool {
+	return errors.Is(err, goredis.Nil)
+}
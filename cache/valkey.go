@@ -1,8 +1,9 @@
 // valkey_cache.go
 //
-// Idiomatic Go wrapper around github.com/valkey-io/valkey-go for standalone Valkey servers.
-// Provides utility methods for string and list operations, with context support, error handling,
-// and extensibility for future data types.
+// Idiomatic Go wrapper around github.com/valkey-io/valkey-go for standalone, cluster and sentinel Valkey deployments.
+// Provides utility methods for keys, strings, counters, lists, hashes, sets and sorted sets, along with SCAN
+// iteration, pipelined batches and Lua scripts, with context support and error handling (a missing key or field is
+// reported as ErrNotFound).
 //
 // Usage example:
 //
@@ -17,6 +18,8 @@
 //   val, err := cache.Get(ctx, "foo")
 //   n, err := cache.LPush(ctx, "mylist", "a", "b")
 //   vals, err := cache.LRange(ctx, "mylist", 0, -1)
+//   _, err = cache.HSet(ctx, "user:1", map[string]string{"name": "Ada"})
+//   results, err := cache.DoBatch(ctx, NewBatch().Get("foo").IncrBy("visits", 1))
 //
 
 package cache
@@ -29,13 +32,15 @@ import (
 	"time"
 
 	"github.com/valkey-io/valkey-go"
+
+	"github.com/techrail/ground/resilience"
 )
 
 // ErrNotFound is returned when a key does not exist in Valkey.
 var ErrNotFound = errors.New("valkey: key not found")
 
 // ValkeyCacheAPI defines the interface for the cache wrapper.
-// Useful for dependency injection and testing.
+// Useful for dependency injection and testing; MemoryValkeyCache implements it without a server.
 type ValkeyCacheAPI interface {
 	Set(ctx context.Context, key, value string, opts ...SetOption) error
 	Get(ctx context.Context, key string) (string, error)
@@ -44,18 +49,62 @@ type ValkeyCacheAPI interface {
 	LPop(ctx context.Context, key string) (string, error)
 	RPop(ctx context.Context, key string) (string, error)
 	LRange(ctx context.Context, key string, start, stop int64) ([]string, error)
+	LLen(ctx context.Context, key string) (int64, error)
+
+	Del(ctx context.Context, keys ...string) (int64, error)
+	Exists(ctx context.Context, keys ...string) (int64, error)
+	Expire(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	Scan(ctx context.Context, match string, fn func(key string) bool) error
+
+	MGet(ctx context.Context, keys ...string) (map[string]string, error)
+	MSet(ctx context.Context, values map[string]string) error
+	Incr(ctx context.Context, key string) (int64, error)
+	IncrBy(ctx context.Context, key string, by int64) (int64, error)
+
+	HSet(ctx context.Context, key string, fields map[string]string) (int64, error)
+	HGet(ctx context.Context, key, field string) (string, error)
+	HGetAll(ctx context.Context, key string) (map[string]string, error)
+	HDel(ctx context.Context, key string, fields ...string) (int64, error)
+	HExists(ctx context.Context, key, field string) (bool, error)
+	HIncrBy(ctx context.Context, key, field string, by int64) (int64, error)
+	HLen(ctx context.Context, key string) (int64, error)
+
+	SAdd(ctx context.Context, key string, members ...string) (int64, error)
+	SRem(ctx context.Context, key string, members ...string) (int64, error)
+	SMembers(ctx context.Context, key string) ([]string, error)
+	SIsMember(ctx context.Context, key, member string) (bool, error)
+	SCard(ctx context.Context, key string) (int64, error)
+
+	ZAdd(ctx context.Context, key string, members ...ZMember) (int64, error)
+	ZRem(ctx context.Context, key string, members ...string) (int64, error)
+	ZScore(ctx context.Context, key, member string) (float64, error)
+	ZIncrBy(ctx context.Context, key, member string, by float64) (float64, error)
+	ZRange(ctx context.Context, key string, start, stop int64) ([]ZMember, error)
+	ZRangeByScore(ctx context.Context, key, min, max string) ([]ZMember, error)
+	ZCard(ctx context.Context, key string) (int64, error)
+
+	DoBatch(ctx context.Context, batch *Batch) ([]BatchResult, error)
+	Eval(ctx context.Context, script *Script, keys, args []string) (any, error)
+
 	Close()
 	// Underlying returns the underlying valkey.Client for advanced use.
 	Underlying() valkey.Client
 }
 
-// ValkeyCache is an idiomatic Go wrapper around valkey-go for standalone Valkey servers.
+// ValkeyCache is an idiomatic Go wrapper around valkey-go for Valkey servers (standalone, cluster or sentinel).
 type ValkeyCache struct {
-	client valkey.Client
+	client   valkey.Client
+	policy   *resilience.Policy
+	cacheTTL time.Duration // Local TTL of the client side cached reads (0 means the reads are not cached)
+	stats    cacheCounters
 }
 
+var _ ValkeyCacheAPI = (*ValkeyCache)(nil)
+
 // NewValkeyCache creates a new ValkeyCache instance connected to the given host and port.
-// Supports optional configuration via functional options (e.g., WithAuth, WithTLS).
+// Supports optional configuration via functional options (e.g., WithAuth, WithTLS, WithClusterSeeds, WithSentinel,
+// WithClientSideCache). A cluster is detected automatically, even when a single node is given.
 func NewValkeyCache(host, port string, opts ...Option) (*ValkeyCache, error) {
 	address := fmt.Sprintf("%s:%s", host, port)
 	cfg := &config{
@@ -64,28 +113,55 @@ func NewValkeyCache(host, port string, opts ...Option) (*ValkeyCache, error) {
 	for _, opt := range opts {
 		opt(cfg)
 	}
+	vc := &ValkeyCache{cacheTTL: cfg.cacheTTL}
 	clientOpt := valkey.ClientOption{
-		InitAddress: cfg.addresses,
-		Username:    cfg.username,
-		Password:    cfg.password,
-		TLSConfig:   cfg.tlsConfig,
-		// Add more options as needed (e.g., ClientName, DisableCache, etc.)
+		InitAddress:       cfg.addresses,
+		Username:          cfg.username,
+		Password:          cfg.password,
+		TLSConfig:         cfg.tlsConfig,
+		ClientName:        cfg.clientName,
+		SelectDB:          cfg.db,
+		DisableCache:      cfg.disableCache,
+		CacheSizeEachConn: cfg.cacheSizeEachConn,
+		OnInvalidations:   vc.stats.recordInvalidations,
+		Sentinel: valkey.SentinelOption{
+			MasterSet: cfg.masterSet,
+			Username:  cfg.sentinelUsername,
+			Password:  cfg.sentinelPassword,
+			TLSConfig: cfg.tlsConfig,
+		},
 	}
 	client, err := valkey.NewClient(clientOpt)
 	if err != nil {
 		return nil, fmt.Errorf("valkey: failed to connect: %w", err)
 	}
-	return &ValkeyCache{client: client}, nil
+	vc.client = client
+	if cfg.resilience.Enabled() {
+		if cfg.resilience.Name == "" {
+			cfg.resilience.Name = "valkey"
+		}
+		vc.policy = resilience.NewPolicy(cfg.resilience)
+	}
+	return vc, nil
 }
 
 // Option configures ValkeyCache.
 type Option func(*config)
 
 type config struct {
-	addresses []string
-	username  string
-	password  string
-	tlsConfig *tls.Config
+	addresses         []string
+	username          string
+	password          string
+	tlsConfig         *tls.Config
+	resilience        resilience.Config
+	clientName        string
+	db                int
+	masterSet         string
+	sentinelUsername  string
+	sentinelPassword  string
+	cacheTTL          time.Duration
+	cacheSizeEachConn int
+	disableCache      bool
 }
 
 // WithAuth sets the username and password for Valkey AUTH.
@@ -103,6 +179,75 @@ func WithTLS(tlsConfig *tls.Config) Option {
 	}
 }
 
+// WithResilience protects the commands with a resilience policy (circuit breaker, bulkhead, timeout, retries).
+// A missing key is not counted as a failure.
+func WithResilience(resilienceCfg resilience.Config) Option {
+	return func(cfg *config) {
+		cfg.resilience = resilienceCfg
+	}
+}
+
+// WithClusterSeeds adds seed nodes of a cluster to the address given to NewValkeyCache. The rest of the topology
+// is discovered (and kept up to date) by the client.
+func WithClusterSeeds(addresses ...string) Option {
+	return func(cfg *config) {
+		cfg.addresses = append(cfg.addresses, addresses...)
+	}
+}
+
+// WithSentinel connects to the master of the given master set through the sentinels. The address given to
+// NewValkeyCache is then the one of a sentinel; more sentinels can be given here. Failovers are followed by the
+// client.
+func WithSentinel(masterSet string, sentinelAddresses ...string) Option {
+	return func(cfg *config) {
+		cfg.masterSet = masterSet
+		cfg.addresses = append(cfg.addresses, sentinelAddresses...)
+	}
+}
+
+// WithSentinelAuth sets the username and password for the AUTH of the sentinels (if they differ from the ones of
+// the servers).
+func WithSentinelAuth(username, password string) Option {
+	return func(cfg *config) {
+		cfg.sentinelUsername = username
+		cfg.sentinelPassword = password
+	}
+}
+
+// WithClientName sets the name of the connections (as seen in CLIENT LIST).
+func WithClientName(name string) Option {
+	return func(cfg *config) {
+		cfg.clientName = name
+	}
+}
+
+// WithDB selects the database (standalone and sentinel only; a cluster has only the database 0).
+func WithDB(db int) Option {
+	return func(cfg *config) {
+		cfg.db = db
+	}
+}
+
+// WithClientSideCache serves the reads (Get) from a local cache kept in sync by the server (server-assisted client
+// side caching, RESP3 only). An entry lives locally for at most ttl, or until the server invalidates it. A
+// sizeEachConn of 0 keeps the default size (128 MiB per connection). See ValkeyCache.CacheStats.
+func WithClientSideCache(ttl time.Duration, sizeEachConn int) Option {
+	return func(cfg *config) {
+		cfg.cacheTTL = ttl
+		cfg.cacheSizeEachConn = sizeEachConn
+		cfg.disableCache = false
+	}
+}
+
+// WithoutClientSideCache disables the client side caching (and the tracking it needs) altogether. Use it with servers
+// which do not speak RESP3.
+func WithoutClientSideCache() Option {
+	return func(cfg *config) {
+		cfg.cacheTTL = 0
+		cfg.disableCache = true
+	}
+}
+
 // SetOption configures the Set operation (e.g., expiration).
 type SetOption func(*setOptions)
 
@@ -123,21 +268,27 @@ func (c *ValkeyCache) Set(ctx context.Context, key, value string, opts ...SetOpt
 	for _, opt := range opts {
 		opt(&so)
 	}
-	builder := c.client.B().Set().Key(key).Value(value)
-	if so.expiration > 0 {
-		b := builder.Ex(time.Duration(so.expiration.Seconds()))
-		cmd := b.Build()
-		return c.client.Do(ctx, cmd).Error()
-	}
-	cmd := builder.Build()
-	return c.client.Do(ctx, cmd).Error()
+	return c.run(ctx, func(ctx context.Context) error {
+		builder := c.client.B().Set().Key(key).Value(value)
+		if so.expiration > 0 {
+			return c.client.Do(ctx, builder.Px(so.expiration).Build()).Error()
+		}
+		return c.client.Do(ctx, builder.Build()).Error()
+	})
 }
 
-// Get retrieves the string value for a key.
+// Get retrieves the string value for a key (from the local cache when WithClientSideCache is used).
 // Returns ErrNotFound if the key does not exist.
 func (c *ValkeyCache) Get(ctx context.Context, key string) (string, error) {
-	cmd := c.client.B().Get().Key(key).Build()
-	val, err := c.client.Do(ctx, cmd).ToString()
+	var val string
+	err := c.run(ctx, func(ctx context.Context) (err error) {
+		if c.cacheTTL > 0 {
+			val, err = c.doCache(ctx, c.client.B().Get().Key(key).Cache()).ToString()
+			return err
+		}
+		val, err = c.client.Do(ctx, c.client.B().Get().Key(key).Build()).ToString()
+		return err
+	})
 	if valkey.IsValkeyNil(err) {
 		return "", ErrNotFound
 	}
@@ -150,8 +301,12 @@ func (c *ValkeyCache) LPush(ctx context.Context, key string, values ...string) (
 	if len(values) == 0 {
 		return 0, errors.New("valkey: LPush requires at least one value")
 	}
-	cmd := c.client.B().Lpush().Key(key).Element(values...).Build()
-	return c.client.Do(ctx, cmd).AsInt64()
+	var n int64
+	err := c.run(ctx, func(ctx context.Context) (err error) {
+		n, err = c.client.Do(ctx, c.client.B().Lpush().Key(key).Element(values...).Build()).AsInt64()
+		return err
+	})
+	return n, err
 }
 
 // RPush pushes values to the tail of the list at key.
@@ -160,15 +315,22 @@ func (c *ValkeyCache) RPush(ctx context.Context, key string, values ...string) (
 	if len(values) == 0 {
 		return 0, errors.New("valkey: RPush requires at least one value")
 	}
-	cmd := c.client.B().Rpush().Key(key).Element(values...).Build()
-	return c.client.Do(ctx, cmd).AsInt64()
+	var n int64
+	err := c.run(ctx, func(ctx context.Context) (err error) {
+		n, err = c.client.Do(ctx, c.client.B().Rpush().Key(key).Element(values...).Build()).AsInt64()
+		return err
+	})
+	return n, err
 }
 
 // LPop pops a value from the head of the list at key.
 // Returns ErrNotFound if the list is empty or the key does not exist.
 func (c *ValkeyCache) LPop(ctx context.Context, key string) (string, error) {
-	cmd := c.client.B().Lpop().Key(key).Build()
-	val, err := c.client.Do(ctx, cmd).ToString()
+	var val string
+	err := c.run(ctx, func(ctx context.Context) (err error) {
+		val, err = c.client.Do(ctx, c.client.B().Lpop().Key(key).Build()).ToString()
+		return err
+	})
 	if valkey.IsValkeyNil(err) {
 		return "", ErrNotFound
 	}
@@ -178,8 +340,11 @@ func (c *ValkeyCache) LPop(ctx context.Context, key string) (string, error) {
 // RPop pops a value from the tail of the list at key.
 // Returns ErrNotFound if the list is empty or the key does not exist.
 func (c *ValkeyCache) RPop(ctx context.Context, key string) (string, error) {
-	cmd := c.client.B().Rpop().Key(key).Build()
-	val, err := c.client.Do(ctx, cmd).ToString()
+	var val string
+	err := c.run(ctx, func(ctx context.Context) (err error) {
+		val, err = c.client.Do(ctx, c.client.B().Rpop().Key(key).Build()).ToString()
+		return err
+	})
 	if valkey.IsValkeyNil(err) {
 		return "", ErrNotFound
 	}
@@ -189,14 +354,22 @@ func (c *ValkeyCache) RPop(ctx context.Context, key string) (string, error) {
 // LRange returns the elements of the list at key between start and stop (inclusive).
 // Returns an empty slice if the key does not exist.
 func (c *ValkeyCache) LRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
-	cmd := c.client.B().Lrange().Key(key).Start(start).Stop(stop).Build()
-	vals, err := c.client.Do(ctx, cmd).AsStrSlice()
+	var vals []string
+	err := c.run(ctx, func(ctx context.Context) (err error) {
+		vals, err = c.client.Do(ctx, c.client.B().Lrange().Key(key).Start(start).Stop(stop).Build()).AsStrSlice()
+		return err
+	})
 	if valkey.IsValkeyNil(err) {
 		return []string{}, nil
 	}
 	return vals, err
 }
 
+// run runs the command function through the resilience policy (if any)
+func (c *ValkeyCache) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	return c.policy.Run(ctx, fn, valkey.IsValkeyNil)
+}
+
 // Close closes the underlying Valkey client and releases resources.
 func (c *ValkeyCache) Close() {
 	c.client.Close()
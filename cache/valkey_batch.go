@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// Batch collects commands which are sent together (pipelined) by DoBatch. The commands are not run atomically, and
+// in cluster mode they are sent to the nodes owning their keys.
+type Batch struct {
+	cmds []batchCmd
+}
+
+type batchCmd struct {
+	name string
+	keys []string
+	args []string
+}
+
+// BatchResult is the result of one command of a batch. Err is ErrNotFound when the command replied with nil (e.g.
+// a GET of a missing key).
+type BatchResult struct {
+	Str string // Reply of the commands replying with a string (GET, HGET)
+	Int int64  // Reply of the commands replying with an integer (DEL, INCRBY, HSET, SADD, ...)
+	Err error
+}
+
+// NewBatch creates an empty batch
+func NewBatch() *Batch {
+	return &Batch{}
+}
+
+// Len returns the number of commands in the batch
+func (b *Batch) Len() int {
+	return len(b.cmds)
+}
+
+func (b *Batch) add(name string, keys []string, args ...string) *Batch {
+	b.cmds = append(b.cmds, batchCmd{name: name, keys: keys, args: args})
+	return b
+}
+
+// Set adds a SET (with an expiration unless ttl is 0)
+func (b *Batch) Set(key, value string, ttl time.Duration) *Batch {
+	if ttl > 0 {
+		return b.add("SET", []string{key}, value, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	return b.add("SET", []string{key}, value)
+}
+
+// Get adds a GET
+func (b *Batch) Get(key string) *Batch {
+	return b.add("GET", []string{key})
+}
+
+// Del adds a DEL
+func (b *Batch) Del(keys ...string) *Batch {
+	return b.add("DEL", keys)
+}
+
+// Expire adds a PEXPIRE
+func (b *Batch) Expire(key string, ttl time.Duration) *Batch {
+	return b.add("PEXPIRE", []string{key}, strconv.FormatInt(ttl.Milliseconds(), 10))
+}
+
+// IncrBy adds an INCRBY
+func (b *Batch) IncrBy(key string, by int64) *Batch {
+	return b.add("INCRBY", []string{key}, strconv.FormatInt(by, 10))
+}
+
+// RPush adds an RPUSH
+func (b *Batch) RPush(key string, values ...string) *Batch {
+	return b.add("RPUSH", []string{key}, values...)
+}
+
+// HSet adds an HSET of a single field
+func (b *Batch) HSet(key, field, value string) *Batch {
+	return b.add("HSET", []string{key}, field, value)
+}
+
+// HGet adds an HGET
+func (b *Batch) HGet(key, field string) *Batch {
+	return b.add("HGET", []string{key}, field)
+}
+
+// SAdd adds an SADD
+func (b *Batch) SAdd(key string, members ...string) *Batch {
+	return b.add("SADD", []string{key}, members...)
+}
+
+// ZAdd adds a ZADD of a single member
+func (b *Batch) ZAdd(key string, member ZMember) *Batch {
+	return b.add("ZADD", []string{key}, strconv.FormatFloat(member.Score, 'f', -1, 64), member.Member)
+}
+
+// DoBatch sends the commands of the batch in one round trip (per node) and returns their results in order. The
+// error is returned only when the batch could not be sent at all (e.g. the circuit breaker is open); the errors of
+// the individual commands are in their results.
+func (c *ValkeyCache) DoBatch(ctx context.Context, batch *Batch) ([]BatchResult, error) {
+	if batch.Len() == 0 {
+		return nil, nil
+	}
+	var replies []valkey.ValkeyResult
+	err := c.run(ctx, func(ctx context.Context) error {
+		cmds := make(valkey.Commands, len(batch.cmds))
+		for i, cmd := range batch.cmds {
+			cmds[i] = c.client.B().Arbitrary(cmd.name).Keys(cmd.keys...).Args(cmd.args...).Build()
+		}
+		replies = c.client.DoMulti(ctx, cmds...)
+		for _, reply := range replies {
+			// Only the failures of the connection count against the resilience policy
+			if err := reply.NonValkeyError(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]BatchResult, len(replies))
+	for i, reply := range replies {
+		msg, err := reply.ToMessage()
+		switch {
+		case valkey.IsValkeyNil(err):
+			results[i].Err = ErrNotFound
+		case err != nil:
+			results[i].Err = err
+		case msg.IsInt64():
+			results[i].Int, _ = msg.AsInt64()
+		default:
+			results[i].Str, _ = msg.ToString()
+		}
+	}
+	return results, nil
+}
+
+// Script is a Lua script run with Eval. It is sent by its SHA1 (EVALSHA) and loaded on the first use.
+type Script struct {
+	src string
+	lua *valkey.Lua
+}
+
+// NewScript creates a script out of its Lua source
+func NewScript(src string) *Script {
+	return &Script{src: src, lua: valkey.NewLuaScript(src)}
+}
+
+// Source returns the Lua source of the script
+func (s *Script) Source() string {
+	return s.src
+}
+
+// Eval runs the script with the given keys (KEYS) and arguments (ARGV) and returns its reply converted to Go values
+// (string, int64, float64, []any, map[string]any...). Returns ErrNotFound if the script replied with nil (or false).
+func (c *ValkeyCache) Eval(ctx context.Context, script *Script, keys, args []string) (any, error) {
+	var val any
+	err := c.run(ctx, func(ctx context.Context) (err error) {
+		val, err = script.lua.Exec(ctx, c.client, keys, args).ToAny()
+		return err
+	})
+	if valkey.IsValkeyNil(err) {
+		return nil, ErrNotFound
+	}
+	return val, err
+}
@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// CacheStats are the counters of the client side cache (see WithClientSideCache)
+type CacheStats struct {
+	Hits          int64 // Reads served from the local cache
+	Misses        int64 // Cacheable reads which went to the server
+	Invalidations int64 // Keys invalidated by the server
+	Flushes       int64 // Times the whole local cache was dropped (e.g. on reconnection)
+}
+
+type cacheCounters struct {
+	hits          atomic.Int64
+	misses        atomic.Int64
+	invalidations atomic.Int64
+	flushes       atomic.Int64
+}
+
+// recordInvalidations is called by valkey-go with the invalidated keys (or nil when everything was invalidated)
+func (cc *cacheCounters) recordInvalidations(messages []valkey.ValkeyMessage) {
+	if messages == nil {
+		cc.flushes.Add(1)
+		return
+	}
+	cc.invalidations.Add(int64(len(messages)))
+}
+
+// CacheStats returns the counters of the client side cache
+func (c *ValkeyCache) CacheStats() CacheStats {
+	return CacheStats{
+		Hits:          c.stats.hits.Load(),
+		Misses:        c.stats.misses.Load(),
+		Invalidations: c.stats.invalidations.Load(),
+		Flushes:       c.stats.flushes.Load(),
+	}
+}
+
+// doCache sends a cacheable command through the client side cache, keeping the hit and miss counters
+func (c *ValkeyCache) doCache(ctx context.Context, cmd valkey.Cacheable) valkey.ValkeyResult {
+	result := c.client.DoCache(ctx, cmd, c.cacheTTL)
+	if result.Error() == nil || valkey.IsValkeyNil(result.Error()) {
+		if result.IsCacheHit() {
+			c.stats.hits.Add(1)
+		} else {
+			c.stats.misses.Add(1)
+		}
+	}
+	return result
+}
@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClientSideCacheOptions(t *testing.T) {
+	cfg := &config{}
+	for _, opt := range []Option{WithoutClientSideCache(), WithClientSideCache(time.Minute, 1<<20)} {
+		opt(cfg)
+	}
+	if cfg.disableCache || cfg.cacheTTL != time.Minute || cfg.cacheSizeEachConn != 1<<20 {
+		t.Errorf("E#3D3ULK - WithClientSideCache did not override WithoutClientSideCache: %+v", cfg)
+	}
+	WithoutClientSideCache()(cfg)
+	if !cfg.disableCache || cfg.cacheTTL != 0 {
+		t.Errorf("E#3HJ9LD - WithoutClientSideCache did not disable the cache: %+v", cfg)
+	}
+
+	srv := newFakeValkey(t)
+	host, port := srv.hostPort()
+	c, err := NewValkeyCache(host, port, WithoutClientSideCache(), WithClientName("ground-test"), WithDB(2))
+	if err != nil {
+		t.Fatalf("E#3CNKWG - Could not connect to the test server: %v", err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+	_ = c.Set(ctx, "a", "1")
+	_, _ = c.Get(ctx, "a")
+	_, _ = c.Get(ctx, "a")
+	if srv.received("CLIENT TRACKING") || srv.received("CLIENT CACHING") {
+		t.Errorf("E#3ET21P - The tracking was turned on although the client side cache is disabled")
+	}
+	if !srv.received("HELLO 3 SETNAME ground-test") || !srv.received("SELECT 2") {
+		t.Errorf("E#3B2TXL - The client name or the database was not set")
+	}
+	if stats := c.CacheStats(); stats != (CacheStats{}) {
+		t.Errorf("E#3CKG4Q - The counters moved without a client side cache: %+v", stats)
+	}
+}
+
+func TestClientSideCacheStats(t *testing.T) {
+	srv := newFakeValkey(t)
+	host, port := srv.hostPort()
+	c, err := NewValkeyCache(host, port, WithClientSideCache(time.Minute, 0))
+	if err != nil {
+		t.Fatalf("E#3CBUYI - Could not connect to the test server: %v", err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "a", "1")
+	for i := 0; i < 3; i++ {
+		if v, err := c.Get(ctx, "a"); err != nil || v != "1" {
+			t.Fatalf("E#3FTR79 - Get gave %q, %v", v, err)
+		}
+	}
+	if _, err := c.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("E#3CM48F - Get of a missing key gave %v instead of ErrNotFound", err)
+	}
+	if stats := c.CacheStats(); stats.Hits != 2 || stats.Misses != 2 {
+		t.Errorf("E#3H750I - Got %+v; want 2 hits (the repeated reads) and 2 misses", stats)
+	}
+	if !srv.received("CLIENT TRACKING ON OPTIN") {
+		t.Errorf("E#3E6KHU - The tracking was not turned on")
+	}
+
+	// A write makes the server invalidate the key, so the next read goes to the server again
+	_ = c.Set(ctx, "a", "2")
+	if !eventually(func() bool { return c.CacheStats().Invalidations == 1 }) {
+		t.Fatalf("E#3FT2JN - The invalidation was not counted: %+v", c.CacheStats())
+	}
+	if v, _ := c.Get(ctx, "a"); v != "2" || c.CacheStats().Misses != 3 {
+		t.Errorf("E#3CUNZY - Got %q after the invalidation (%+v); want the new value from the server", v, c.CacheStats())
+	}
+
+	srv.flushClients()
+	if !eventually(func() bool { return c.CacheStats().Flushes == 1 }) {
+		t.Errorf("E#3FI74A - The flush of the local cache was not counted: %+v", c.CacheStats())
+	}
+	_, _ = c.Get(ctx, "a")
+	if stats := c.CacheStats(); stats.Misses != 4 || stats.Hits != 2 {
+		t.Errorf("E#3E1NN1 - Got %+v after the flush; want the read to go to the server", stats)
+	}
+}
@@ -0,0 +1,153 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// The conformance suite runs against both implementations of ValkeyCacheAPI, so that the in-memory one keeps
+// behaving like Valkey. The real server (127.0.0.1:6379) is skipped when it can not be reached.
+
+func TestMemoryValkeyConformance(t *testing.T) {
+	runValkeyConformance(t, NewMemoryValkeyCache())
+}
+
+func TestValkeyConformance(t *testing.T) {
+	c, err := NewValkeyCache("127.0.0.1", "6379")
+	if err != nil {
+		t.Skipf("No valkey server available: %v", err)
+	}
+	defer c.Close()
+	runValkeyConformance(t, c)
+}
+
+func runValkeyConformance(t *testing.T, c ValkeyCacheAPI) {
+	ctx := context.Background()
+	p := "conformance:" + time.Now().Format("150405.000000") + ":"
+	t.Cleanup(func() {
+		var keys []string
+		_ = c.Scan(ctx, p+"*", func(key string) bool {
+			keys = append(keys, key)
+			return true
+		})
+		if len(keys) > 0 {
+			_, _ = c.Del(ctx, keys...)
+		}
+	})
+
+	t.Run("strings", func(t *testing.T) {
+		if _, err := c.Get(ctx, p+"missing"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("E#3DUAUV - Get of a missing key gave %v instead of ErrNotFound", err)
+		}
+		_ = c.Set(ctx, p+"s", "v")
+		if v, err := c.Get(ctx, p+"s"); err != nil || v != "v" {
+			t.Errorf("E#3GXI04 - Get gave %q, %v", v, err)
+		}
+		if n, err := c.IncrBy(ctx, p+"n", 5); err != nil || n != 5 {
+			t.Errorf("E#3BNPKQ - IncrBy of a missing key gave %v, %v", n, err)
+		}
+		if _, err := c.Incr(ctx, p+"s"); err == nil {
+			t.Errorf("E#3AYB9W - Incr of a non integer value succeeded")
+		}
+		_ = c.MSet(ctx, map[string]string{p + "a": "1", p + "b": "2"})
+		vals, err := c.MGet(ctx, p+"a", p+"b", p+"missing")
+		if err != nil || len(vals) != 2 || vals[p+"b"] != "2" {
+			t.Errorf("E#3AS6ZM - MGet gave %v, %v", vals, err)
+		}
+	})
+
+	t.Run("keys and expiry", func(t *testing.T) {
+		_ = c.Set(ctx, p+"t", "v", WithExpiration(time.Minute))
+		if ttl, err := c.TTL(ctx, p+"t"); err != nil || ttl <= 59*time.Second || ttl > time.Minute {
+			t.Errorf("E#3DB1ZZ - TTL gave %v, %v", ttl, err)
+		}
+		_ = c.Set(ctx, p+"t", "v2")
+		if ttl, err := c.TTL(ctx, p+"t"); err != nil || ttl != TTLNoExpiry {
+			t.Errorf("E#3AS6PA - Set without expiration kept the TTL: %v, %v", ttl, err)
+		}
+		if _, err := c.TTL(ctx, p+"missing"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("E#3CW4UE - TTL of a missing key gave %v", err)
+		}
+		if ok, _ := c.Expire(ctx, p+"missing", time.Minute); ok {
+			t.Errorf("E#3DIR8P - Expire of a missing key succeeded")
+		}
+		_ = c.Set(ctx, p+"short", "v", WithExpiration(50*time.Millisecond))
+		time.Sleep(120 * time.Millisecond)
+		if _, err := c.Get(ctx, p+"short"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("E#3H92ZA - An expired key was still found: %v", err)
+		}
+		if n, _ := c.Exists(ctx, p+"t", p+"short", p+"t"); n != 2 {
+			t.Errorf("E#3DF8J8 - Exists gave %v instead of 2", n)
+		}
+		if n, _ := c.Del(ctx, p+"t", p+"missing"); n != 1 {
+			t.Errorf("E#3E3OS5 - Del gave %v instead of 1", n)
+		}
+	})
+
+	t.Run("lists", func(t *testing.T) {
+		_, _ = c.LPush(ctx, p+"l", "a", "b")
+		_, _ = c.RPush(ctx, p+"l", "c")
+		if vals, _ := c.LRange(ctx, p+"l", 0, -1); !slices.Equal(vals, []string{"b", "a", "c"}) {
+			t.Errorf("E#3DBIJL - LRange gave %v", vals)
+		}
+		if vals, _ := c.LRange(ctx, p+"l", -2, 10); !slices.Equal(vals, []string{"a", "c"}) {
+			t.Errorf("E#3AX8GU - LRange with negative start gave %v", vals)
+		}
+		_, _ = c.LPop(ctx, p+"l")
+		_, _ = c.RPop(ctx, p+"l")
+		if v, err := c.LPop(ctx, p+"l"); err != nil || v != "a" {
+			t.Errorf("E#3F6P6T - LPop gave %q, %v", v, err)
+		}
+		if _, err := c.LPop(ctx, p+"l"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("E#3GZPCJ - LPop of an empty list gave %v", err)
+		}
+		if n, _ := c.Exists(ctx, p+"l"); n != 0 {
+			t.Errorf("E#3FARSH - An empty list still exists")
+		}
+		_ = c.Set(ctx, p+"str", "v")
+		if _, err := c.LPush(ctx, p+"str", "x"); err == nil {
+			t.Errorf("E#3GC2O1 - LPush against a string succeeded")
+		}
+	})
+
+	t.Run("hashes and sets", func(t *testing.T) {
+		if n, _ := c.HSet(ctx, p+"h", map[string]string{"a": "1", "b": "2"}); n != 2 {
+			t.Errorf("E#3HAXW1 - HSet gave %v instead of 2", n)
+		}
+		if _, err := c.HGet(ctx, p+"h", "missing"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("E#3CR0VF - HGet of a missing field gave %v", err)
+		}
+		if n, _ := c.HIncrBy(ctx, p+"h", "a", 2); n != 3 {
+			t.Errorf("E#3GTBRW - HIncrBy gave %v instead of 3", n)
+		}
+		_, _ = c.SAdd(ctx, p+"set", "x", "y", "x")
+		if n, _ := c.SCard(ctx, p+"set"); n != 2 {
+			t.Errorf("E#3CIJ5N - SCard gave %v instead of 2", n)
+		}
+	})
+
+	t.Run("sorted sets", func(t *testing.T) {
+		_, _ = c.ZAdd(ctx, p+"z", ZMember{Member: "b", Score: 1}, ZMember{Member: "a", Score: 1}, ZMember{Member: "c", Score: 3})
+		members, _ := c.ZRange(ctx, p+"z", 0, -1)
+		if len(members) != 3 || members[0].Member != "a" || members[2].Score != 3 {
+			t.Errorf("E#3B1XRR - ZRange gave %v", members)
+		}
+		if members, _ = c.ZRangeByScore(ctx, p+"z", "(1", "+inf"); len(members) != 1 || members[0].Member != "c" {
+			t.Errorf("E#3C9MW8 - ZRangeByScore gave %v", members)
+		}
+		if _, err := c.ZScore(ctx, p+"z", "missing"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("E#3EOFYV - ZScore of a missing member gave %v", err)
+		}
+	})
+
+	t.Run("batches", func(t *testing.T) {
+		results, err := c.DoBatch(ctx, NewBatch().Set(p+"bs", "v", time.Minute).Get(p+"bs").Get(p+"missing").IncrBy(p+"bn", 2))
+		if err != nil || len(results) != 4 || results[1].Str != "v" || !errors.Is(results[2].Err, ErrNotFound) ||
+			results[3].Int != 2 {
+			t.Errorf("E#3BIMU8 - DoBatch gave %+v, %v", results, err)
+		}
+	})
+}
@@ -0,0 +1,217 @@
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeValkey is an in-process server speaking just enough RESP3 for the client side caching of valkey-go: the
+// handshake (HELLO, CLIENT TRACKING), the cached reads (CLIENT CACHING, MULTI, PTTL, GET, EXEC), SET and DEL. Like
+// the real server, it pushes an invalidation to the connections which read a key when the key is written.
+type fakeValkey struct {
+	listener net.Listener
+	mu       sync.Mutex
+	data     map[string]string
+	conns    map[*fakeConn]bool
+	commands []string // Every command received (upper cased name and arguments)
+}
+
+type fakeConn struct {
+	mu      sync.Mutex // Guards the writes (replies and pushes)
+	w       *bufio.Writer
+	tracked map[string]bool // Keys read by a cached read, guarded by fakeValkey.mu
+}
+
+func newFakeValkey(t *testing.T) *fakeValkey {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("E#3CC8J7 - Could not listen: %v", err)
+	}
+	s := &fakeValkey{listener: l, data: map[string]string{}, conns: map[*fakeConn]bool{}}
+	t.Cleanup(func() { _ = l.Close() })
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(c)
+		}
+	}()
+	return s
+}
+
+// hostPort returns the host and the port to give to NewValkeyCache
+func (s *fakeValkey) hostPort() (string, string) {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return host, port
+}
+
+// received tells if a command starting with the given words was received
+func (s *fakeValkey) received(prefix string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, cmd := range s.commands {
+		if strings.HasPrefix(cmd, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// flushClients tells the connections which track keys to drop their whole local cache (as after a FLUSHALL)
+func (s *fakeValkey) flushClients() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for fc := range s.conns {
+		if len(fc.tracked) > 0 {
+			fc.tracked = map[string]bool{}
+			fc.write(">2\r\n$10\r\ninvalidate\r\n_\r\n")
+		}
+	}
+}
+
+func (s *fakeValkey) serve(c net.Conn) {
+	defer c.Close()
+	fc := &fakeConn{w: bufio.NewWriter(c), tracked: map[string]bool{}}
+	s.mu.Lock()
+	s.conns[fc] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, fc)
+		s.mu.Unlock()
+	}()
+
+	r := bufio.NewReader(c)
+	var queued [][]string
+	inMulti := false
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		name := strings.ToUpper(args[0])
+		s.mu.Lock()
+		s.commands = append(s.commands, strings.Join(append([]string{name}, args[1:]...), " "))
+		s.mu.Unlock()
+
+		switch {
+		case name == "MULTI":
+			inMulti = true
+			fc.write("+OK\r\n")
+		case name == "EXEC":
+			reply := fmt.Sprintf("*%d\r\n", len(queued))
+			for _, q := range queued {
+				reply += s.execute(fc, q, true)
+			}
+			queued, inMulti = nil, false
+			fc.write(reply)
+		case inMulti:
+			queued = append(queued, args)
+			fc.write("+QUEUED\r\n")
+		default:
+			fc.write(s.execute(fc, args, false))
+		}
+	}
+}
+
+// execute runs a command and returns its reply. The reads in a transaction are the cached reads of valkey-go.
+func (s *fakeValkey) execute(fc *fakeConn, args []string, inMulti bool) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch strings.ToUpper(args[0]) {
+	case "HELLO":
+		return "%3\r\n+server\r\n+valkey\r\n+version\r\n+8.0.0\r\n+proto\r\n:3\r\n"
+	case "CLIENT", "SELECT":
+		return "+OK\r\n"
+	case "CLUSTER":
+		return "-ERR This instance has cluster support disabled\r\n"
+	case "PING":
+		return "+PONG\r\n"
+	case "PTTL":
+		return ":-1\r\n"
+	case "GET":
+		if inMulti {
+			fc.tracked[args[1]] = true
+		}
+		val, found := s.data[args[1]]
+		if !found {
+			return "_\r\n"
+		}
+		return bulk(val)
+	case "SET":
+		s.data[args[1]] = args[2]
+		s.invalidate(args[1])
+		return "+OK\r\n"
+	case "DEL":
+		n := 0
+		for _, key := range args[1:] {
+			if _, found := s.data[key]; found {
+				delete(s.data, key)
+				n++
+			}
+			s.invalidate(key)
+		}
+		return ":" + strconv.Itoa(n) + "\r\n"
+	}
+	return "-ERR unknown command '" + args[0] + "'\r\n"
+}
+
+// invalidate pushes the invalidation of the key to the connections tracking it. The lock must be held.
+func (s *fakeValkey) invalidate(key string) {
+	for fc := range s.conns {
+		if fc.tracked[key] {
+			delete(fc.tracked, key)
+			fc.write(">2\r\n$10\r\ninvalidate\r\n*1\r\n" + bulk(key))
+		}
+	}
+}
+
+func (fc *fakeConn) write(reply string) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	_, _ = fc.w.WriteString(reply)
+	_ = fc.w.Flush()
+}
+
+func bulk(s string) string {
+	return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n"
+}
+
+// readCommand reads a command sent as an array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("unexpected line %q", line)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err = io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
@@ -0,0 +1,888 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// ErrWrongType is returned when an operation is used against a key holding a different kind of value (the WRONGTYPE
+// error of Valkey).
+var ErrWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+// ErrUnsupported is returned by MemoryValkeyCache for the operations it can not perform (Lua scripts).
+var ErrUnsupported = errors.New("cache: operation not supported by the in-memory cache")
+
+var errNotInteger = errors.New("ERR value is not an integer or out of range")
+var errNotFloat = errors.New("ERR value is not a valid float")
+
+// MemoryValkeyCache is an in-process implementation of ValkeyCacheAPI, for tests and single node use. It follows
+// the semantics of Valkey: keys expire (the expired keys are removed when read and, by sampling, on the writes),
+// empty collections disappear, the operations against a key of a different kind fail with ErrWrongType and the
+// missing keys give ErrNotFound where Valkey replies with nil. Lua scripts are not supported. It is safe for
+// concurrent use; create it using NewMemoryValkeyCache.
+type MemoryValkeyCache struct {
+	mu   sync.Mutex
+	data map[string]*memoryValue
+	now  func() time.Time
+}
+
+type memoryKind int
+
+const (
+	kindString memoryKind = iota
+	kindList
+	kindHash
+	kindSet
+	kindZSet
+)
+
+type memoryValue struct {
+	kind      memoryKind
+	str       string
+	list      []string
+	hash      map[string]string
+	set       map[string]struct{}
+	zset      map[string]float64
+	expiresAt time.Time // Zero means no expiry
+}
+
+var _ ValkeyCacheAPI = (*MemoryValkeyCache)(nil)
+
+// NewMemoryValkeyCache creates an empty in-memory cache
+func NewMemoryValkeyCache() *MemoryValkeyCache {
+	return &MemoryValkeyCache{data: map[string]*memoryValue{}, now: time.Now}
+}
+
+// lookup returns the live value of the key (removing it if it has expired). The lock must be held.
+func (m *MemoryValkeyCache) lookup(key string) *memoryValue {
+	v, ok := m.data[key]
+	if !ok {
+		return nil
+	}
+	if !v.expiresAt.IsZero() && !m.now().Before(v.expiresAt) {
+		delete(m.data, key)
+		return nil
+	}
+	return v
+}
+
+// typed returns the live value of the key if it is of the given kind (nil if the key does not exist). The lock must
+// be held.
+func (m *MemoryValkeyCache) typed(key string, kind memoryKind) (*memoryValue, error) {
+	v := m.lookup(key)
+	if v != nil && v.kind != kind {
+		return nil, ErrWrongType
+	}
+	return v, nil
+}
+
+// create returns the value of the key, creating an empty one of the given kind if needed. The lock must be held.
+func (m *MemoryValkeyCache) create(key string, kind memoryKind) (*memoryValue, error) {
+	v, err := m.typed(key, kind)
+	if err != nil || v != nil {
+		return v, err
+	}
+	v = &memoryValue{kind: kind}
+	switch kind {
+	case kindHash:
+		v.hash = map[string]string{}
+	case kindSet:
+		v.set = map[string]struct{}{}
+	case kindZSet:
+		v.zset = map[string]float64{}
+	}
+	m.store(key, v)
+	return v, nil
+}
+
+// expireSampleSize is how many keys a write checks for expiry (as many as the active expiry of Valkey samples)
+const expireSampleSize = 20
+
+// store sets the value of the key. Like Valkey, every write also removes the expired keys among a sample of the
+// keys, so that the keys which are never read again do not stay in memory forever. The lock must be held.
+func (m *MemoryValkeyCache) store(key string, v *memoryValue) {
+	m.expireSample()
+	m.data[key] = v
+}
+
+// expireSample removes the expired keys among a sample of the keys, sampling again while more than a quarter of the
+// sample had expired. The lock must be held.
+func (m *MemoryValkeyCache) expireSample() {
+	now := m.now()
+	for {
+		checked, expired := 0, 0
+		for key, v := range m.data {
+			if checked == expireSampleSize {
+				break
+			}
+			checked++
+			if !v.expiresAt.IsZero() && !now.Before(v.expiresAt) {
+				delete(m.data, key)
+				expired++
+			}
+		}
+		if expired*4 <= checked {
+			return
+		}
+	}
+}
+
+// dropIfEmpty removes the key if its collection became empty. The lock must be held.
+func (m *MemoryValkeyCache) dropIfEmpty(key string, v *memoryValue) {
+	if len(v.list) == 0 && len(v.hash) == 0 && len(v.set) == 0 && len(v.zset) == 0 && v.kind != kindString {
+		delete(m.data, key)
+	}
+}
+
+// --- Strings ---
+
+func (m *MemoryValkeyCache) Set(ctx context.Context, key, value string, opts ...SetOption) error {
+	var so setOptions
+	for _, opt := range opts {
+		opt(&so)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v := &memoryValue{kind: kindString, str: value}
+	if so.expiration > 0 {
+		v.expiresAt = m.now().Add(so.expiration)
+	}
+	m.store(key, v)
+	return nil
+}
+
+func (m *MemoryValkeyCache) Get(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.typed(key, kindString)
+	if err != nil {
+		return "", err
+	}
+	if v == nil {
+		return "", ErrNotFound
+	}
+	return v.str, nil
+}
+
+func (m *MemoryValkeyCache) MGet(ctx context.Context, keys ...string) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	vals := map[string]string{}
+	for _, key := range keys {
+		if v := m.lookup(key); v != nil && v.kind == kindString {
+			vals[key] = v.str
+		}
+	}
+	return vals, nil
+}
+
+func (m *MemoryValkeyCache) MSet(ctx context.Context, values map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, value := range values {
+		m.store(key, &memoryValue{kind: kindString, str: value})
+	}
+	return nil
+}
+
+func (m *MemoryValkeyCache) Incr(ctx context.Context, key string) (int64, error) {
+	return m.IncrBy(ctx, key, 1)
+}
+
+func (m *MemoryValkeyCache) IncrBy(ctx context.Context, key string, by int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.typed(key, kindString)
+	if err != nil {
+		return 0, err
+	}
+	n := int64(0)
+	if v != nil {
+		if n, err = strconv.ParseInt(v.str, 10, 64); err != nil {
+			return 0, errNotInteger
+		}
+	} else {
+		v = &memoryValue{kind: kindString}
+		m.store(key, v)
+	}
+	if (by > 0 && n > math.MaxInt64-by) || (by < 0 && n < math.MinInt64-by) {
+		return 0, errors.New("ERR increment or decrement would overflow")
+	}
+	n += by
+	v.str = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+// --- Keys ---
+
+func (m *MemoryValkeyCache) Del(ctx context.Context, keys ...string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := int64(0)
+	for _, key := range keys {
+		if m.lookup(key) != nil {
+			delete(m.data, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemoryValkeyCache) Exists(ctx context.Context, keys ...string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := int64(0)
+	for _, key := range keys {
+		if m.lookup(key) != nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemoryValkeyCache) Expire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v := m.lookup(key)
+	if v == nil {
+		return false, nil
+	}
+	if ttl <= 0 {
+		delete(m.data, key)
+		return true, nil
+	}
+	v.expiresAt = m.now().Add(ttl)
+	return true, nil
+}
+
+func (m *MemoryValkeyCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v := m.lookup(key)
+	if v == nil {
+		return 0, ErrNotFound
+	}
+	if v.expiresAt.IsZero() {
+		return TTLNoExpiry, nil
+	}
+	return v.expiresAt.Sub(m.now()).Truncate(time.Millisecond), nil
+}
+
+func (m *MemoryValkeyCache) Scan(ctx context.Context, match string, fn func(key string) bool) error {
+	m.mu.Lock()
+	var keys []string
+	for key := range m.data {
+		if m.lookup(key) != nil && globMatch(match, key) {
+			keys = append(keys, key)
+		}
+	}
+	m.mu.Unlock()
+	// fn is called without the lock, so that it can use the cache
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !fn(key) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// --- Lists ---
+
+func (m *MemoryValkeyCache) LPush(ctx context.Context, key string, values ...string) (int64, error) {
+	if len(values) == 0 {
+		return 0, errors.New("valkey: LPush requires at least one value")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.create(key, kindList)
+	if err != nil {
+		return 0, err
+	}
+	head := slices.Clone(values)
+	slices.Reverse(head)
+	v.list = append(head, v.list...)
+	return int64(len(v.list)), nil
+}
+
+func (m *MemoryValkeyCache) RPush(ctx context.Context, key string, values ...string) (int64, error) {
+	if len(values) == 0 {
+		return 0, errors.New("valkey: RPush requires at least one value")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.create(key, kindList)
+	if err != nil {
+		return 0, err
+	}
+	v.list = append(v.list, values...)
+	return int64(len(v.list)), nil
+}
+
+func (m *MemoryValkeyCache) LPop(ctx context.Context, key string) (string, error) {
+	return m.pop(key, true)
+}
+
+func (m *MemoryValkeyCache) RPop(ctx context.Context, key string) (string, error) {
+	return m.pop(key, false)
+}
+
+func (m *MemoryValkeyCache) pop(key string, head bool) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.typed(key, kindList)
+	if err != nil {
+		return "", err
+	}
+	if v == nil {
+		return "", ErrNotFound
+	}
+	var val string
+	if head {
+		val, v.list = v.list[0], v.list[1:]
+	} else {
+		val, v.list = v.list[len(v.list)-1], v.list[:len(v.list)-1]
+	}
+	m.dropIfEmpty(key, v)
+	return val, nil
+}
+
+func (m *MemoryValkeyCache) LRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.typed(key, kindList)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return []string{}, nil
+	}
+	from, to, ok := rangeBounds(start, stop, len(v.list))
+	if !ok {
+		return []string{}, nil
+	}
+	return slices.Clone(v.list[from:to]), nil
+}
+
+func (m *MemoryValkeyCache) LLen(ctx context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.typed(key, kindList)
+	if err != nil || v == nil {
+		return 0, err
+	}
+	return int64(len(v.list)), nil
+}
+
+// --- Hashes ---
+
+func (m *MemoryValkeyCache) HSet(ctx context.Context, key string, fields map[string]string) (int64, error) {
+	if len(fields) == 0 {
+		return 0, errors.New("valkey: HSet requires at least one field")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.create(key, kindHash)
+	if err != nil {
+		return 0, err
+	}
+	added := int64(0)
+	for field, value := range fields {
+		if _, exists := v.hash[field]; !exists {
+			added++
+		}
+		v.hash[field] = value
+	}
+	return added, nil
+}
+
+func (m *MemoryValkeyCache) HGet(ctx context.Context, key, field string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.typed(key, kindHash)
+	if err != nil {
+		return "", err
+	}
+	if v == nil {
+		return "", ErrNotFound
+	}
+	val, ok := v.hash[field]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return val, nil
+}
+
+func (m *MemoryValkeyCache) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.typed(key, kindHash)
+	if err != nil {
+		return nil, err
+	}
+	vals := map[string]string{}
+	if v != nil {
+		for field, value := range v.hash {
+			vals[field] = value
+		}
+	}
+	return vals, nil
+}
+
+func (m *MemoryValkeyCache) HDel(ctx context.Context, key string, fields ...string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.typed(key, kindHash)
+	if err != nil || v == nil {
+		return 0, err
+	}
+	n := int64(0)
+	for _, field := range fields {
+		if _, ok := v.hash[field]; ok {
+			delete(v.hash, field)
+			n++
+		}
+	}
+	m.dropIfEmpty(key, v)
+	return n, nil
+}
+
+func (m *MemoryValkeyCache) HExists(ctx context.Context, key, field string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.typed(key, kindHash)
+	if err != nil || v == nil {
+		return false, err
+	}
+	_, ok := v.hash[field]
+	return ok, nil
+}
+
+func (m *MemoryValkeyCache) HIncrBy(ctx context.Context, key, field string, by int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.create(key, kindHash)
+	if err != nil {
+		return 0, err
+	}
+	n := int64(0)
+	if current, ok := v.hash[field]; ok {
+		if n, err = strconv.ParseInt(current, 10, 64); err != nil {
+			return 0, errors.New("ERR hash value is not an integer")
+		}
+	}
+	n += by
+	v.hash[field] = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+func (m *MemoryValkeyCache) HLen(ctx context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.typed(key, kindHash)
+	if err != nil || v == nil {
+		return 0, err
+	}
+	return int64(len(v.hash)), nil
+}
+
+// --- Sets ---
+
+func (m *MemoryValkeyCache) SAdd(ctx context.Context, key string, members ...string) (int64, error) {
+	if len(members) == 0 {
+		return 0, errors.New("valkey: SAdd requires at least one member")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.create(key, kindSet)
+	if err != nil {
+		return 0, err
+	}
+	added := int64(0)
+	for _, member := range members {
+		if _, ok := v.set[member]; !ok {
+			v.set[member] = struct{}{}
+			added++
+		}
+	}
+	return added, nil
+}
+
+func (m *MemoryValkeyCache) SRem(ctx context.Context, key string, members ...string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.typed(key, kindSet)
+	if err != nil || v == nil {
+		return 0, err
+	}
+	n := int64(0)
+	for _, member := range members {
+		if _, ok := v.set[member]; ok {
+			delete(v.set, member)
+			n++
+		}
+	}
+	m.dropIfEmpty(key, v)
+	return n, nil
+}
+
+func (m *MemoryValkeyCache) SMembers(ctx context.Context, key string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.typed(key, kindSet)
+	if err != nil {
+		return nil, err
+	}
+	members := []string{}
+	if v != nil {
+		for member := range v.set {
+			members = append(members, member)
+		}
+	}
+	return members, nil
+}
+
+func (m *MemoryValkeyCache) SIsMember(ctx context.Context, key, member string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.typed(key, kindSet)
+	if err != nil || v == nil {
+		return false, err
+	}
+	_, ok := v.set[member]
+	return ok, nil
+}
+
+func (m *MemoryValkeyCache) SCard(ctx context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.typed(key, kindSet)
+	if err != nil || v == nil {
+		return 0, err
+	}
+	return int64(len(v.set)), nil
+}
+
+// --- Sorted sets ---
+
+func (m *MemoryValkeyCache) ZAdd(ctx context.Context, key string, members ...ZMember) (int64, error) {
+	if len(members) == 0 {
+		return 0, errors.New("valkey: ZAdd requires at least one member")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.create(key, kindZSet)
+	if err != nil {
+		return 0, err
+	}
+	added := int64(0)
+	for _, member := range members {
+		if _, ok := v.zset[member.Member]; !ok {
+			added++
+		}
+		v.zset[member.Member] = member.Score
+	}
+	return added, nil
+}
+
+func (m *MemoryValkeyCache) ZRem(ctx context.Context, key string, members ...string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.typed(key, kindZSet)
+	if err != nil || v == nil {
+		return 0, err
+	}
+	n := int64(0)
+	for _, member := range members {
+		if _, ok := v.zset[member]; ok {
+			delete(v.zset, member)
+			n++
+		}
+	}
+	m.dropIfEmpty(key, v)
+	return n, nil
+}
+
+func (m *MemoryValkeyCache) ZScore(ctx context.Context, key, member string) (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.typed(key, kindZSet)
+	if err != nil {
+		return 0, err
+	}
+	if v == nil {
+		return 0, ErrNotFound
+	}
+	score, ok := v.zset[member]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return score, nil
+}
+
+func (m *MemoryValkeyCache) ZIncrBy(ctx context.Context, key, member string, by float64) (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.create(key, kindZSet)
+	if err != nil {
+		return 0, err
+	}
+	v.zset[member] += by
+	return v.zset[member], nil
+}
+
+func (m *MemoryValkeyCache) ZRange(ctx context.Context, key string, start, stop int64) ([]ZMember, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.typed(key, kindZSet)
+	if err != nil || v == nil {
+		return []ZMember{}, err
+	}
+	sorted := sortedZMembers(v.zset)
+	from, to, ok := rangeBounds(start, stop, len(sorted))
+	if !ok {
+		return []ZMember{}, nil
+	}
+	return sorted[from:to], nil
+}
+
+func (m *MemoryValkeyCache) ZRangeByScore(ctx context.Context, key, min, max string) ([]ZMember, error) {
+	minScore, minExclusive, err := parseScoreBound(min)
+	if err != nil {
+		return nil, err
+	}
+	maxScore, maxExclusive, err := parseScoreBound(max)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.typed(key, kindZSet)
+	if err != nil || v == nil {
+		return []ZMember{}, err
+	}
+	members := []ZMember{}
+	for _, z := range sortedZMembers(v.zset) {
+		aboveMin := z.Score > minScore || (!minExclusive && z.Score == minScore)
+		belowMax := z.Score < maxScore || (!maxExclusive && z.Score == maxScore)
+		if aboveMin && belowMax {
+			members = append(members, z)
+		}
+	}
+	return members, nil
+}
+
+func (m *MemoryValkeyCache) ZCard(ctx context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.typed(key, kindZSet)
+	if err != nil || v == nil {
+		return 0, err
+	}
+	return int64(len(v.zset)), nil
+}
+
+// --- Batches and scripts ---
+
+// DoBatch runs the commands of the batch one after the other
+func (m *MemoryValkeyCache) DoBatch(ctx context.Context, batch *Batch) ([]BatchResult, error) {
+	if batch.Len() == 0 {
+		return nil, nil
+	}
+	results := make([]BatchResult, len(batch.cmds))
+	for i, cmd := range batch.cmds {
+		results[i] = m.runBatchCmd(ctx, cmd)
+	}
+	return results, nil
+}
+
+func (m *MemoryValkeyCache) runBatchCmd(ctx context.Context, cmd batchCmd) (r BatchResult) {
+	switch cmd.name {
+	case "SET":
+		var opts []SetOption
+		if len(cmd.args) == 3 {
+			ms, _ := strconv.ParseInt(cmd.args[2], 10, 64)
+			opts = append(opts, WithExpiration(time.Duration(ms)*time.Millisecond))
+		}
+		r.Err = m.Set(ctx, cmd.keys[0], cmd.args[0], opts...)
+		r.Str = "OK"
+	case "GET":
+		r.Str, r.Err = m.Get(ctx, cmd.keys[0])
+	case "DEL":
+		r.Int, r.Err = m.Del(ctx, cmd.keys...)
+	case "PEXPIRE":
+		ms, _ := strconv.ParseInt(cmd.args[0], 10, 64)
+		var ok bool
+		if ok, r.Err = m.Expire(ctx, cmd.keys[0], time.Duration(ms)*time.Millisecond); ok {
+			r.Int = 1
+		}
+	case "INCRBY":
+		by, _ := strconv.ParseInt(cmd.args[0], 10, 64)
+		r.Int, r.Err = m.IncrBy(ctx, cmd.keys[0], by)
+	case "RPUSH":
+		r.Int, r.Err = m.RPush(ctx, cmd.keys[0], cmd.args...)
+	case "HSET":
+		r.Int, r.Err = m.HSet(ctx, cmd.keys[0], map[string]string{cmd.args[0]: cmd.args[1]})
+	case "HGET":
+		r.Str, r.Err = m.HGet(ctx, cmd.keys[0], cmd.args[0])
+	case "SADD":
+		r.Int, r.Err = m.SAdd(ctx, cmd.keys[0], cmd.args...)
+	case "ZADD":
+		score, _ := strconv.ParseFloat(cmd.args[0], 64)
+		r.Int, r.Err = m.ZAdd(ctx, cmd.keys[0], ZMember{Member: cmd.args[1], Score: score})
+	default:
+		r.Err = ErrUnsupported
+	}
+	return r
+}
+
+// Eval is not supported by the in-memory cache
+func (m *MemoryValkeyCache) Eval(ctx context.Context, script *Script, keys, args []string) (any, error) {
+	return nil, ErrUnsupported
+}
+
+// Close does nothing
+func (m *MemoryValkeyCache) Close() {}
+
+// Underlying returns nil: there is no valkey client behind the in-memory cache
+func (m *MemoryValkeyCache) Underlying() valkey.Client {
+	return nil
+}
+
+// --- Helpers ---
+
+// rangeBounds converts the inclusive (possibly negative) start and stop indexes of LRANGE and ZRANGE into the
+// bounds of a slice of the given length
+func rangeBounds(start, stop int64, length int) (int, int, bool) {
+	n := int64(length)
+	if start < 0 {
+		start = max(n+start, 0)
+	}
+	if stop < 0 {
+		stop = n + stop
+	}
+	stop = min(stop, n-1)
+	if start > stop || start >= n {
+		return 0, 0, false
+	}
+	return int(start), int(stop) + 1, true
+}
+
+// sortedZMembers orders the members by score, and by member for the same score
+func sortedZMembers(zset map[string]float64) []ZMember {
+	members := make([]ZMember, 0, len(zset))
+	for member, score := range zset {
+		members = append(members, ZMember{Member: member, Score: score})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Score != members[j].Score {
+			return members[i].Score < members[j].Score
+		}
+		return members[i].Member < members[j].Member
+	})
+	return members
+}
+
+// parseScoreBound parses a bound of ZRANGEBYSCORE (e.g. "1.5", "(1.5", "-inf", "+inf")
+func parseScoreBound(bound string) (float64, bool, error) {
+	exclusive := strings.HasPrefix(bound, "(")
+	bound = strings.TrimPrefix(bound, "(")
+	switch strings.ToLower(bound) {
+	case "-inf":
+		return math.Inf(-1), exclusive, nil
+	case "+inf", "inf":
+		return math.Inf(1), exclusive, nil
+	}
+	score, err := strconv.ParseFloat(bound, 64)
+	if err != nil {
+		return 0, false, errNotFloat
+	}
+	return score, exclusive, nil
+}
+
+// globMatch implements the glob style patterns of SCAN and KEYS: `*`, `?`, `[abc]`, `[^abc]`, `[a-z]` and `\` to
+// escape a character
+func globMatch(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if globMatch(pattern, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			end := strings.IndexByte(pattern[1:], ']')
+			if end < 0 {
+				// An unterminated class matches the `[` literally
+				if s[0] != '[' {
+					return false
+				}
+				pattern, s = pattern[1:], s[1:]
+				continue
+			}
+			class := pattern[1 : end+1]
+			negate := strings.HasPrefix(class, "^")
+			if negate {
+				class = class[1:]
+			}
+			if classMatches(class, s[0]) == negate {
+				return false
+			}
+			pattern, s = pattern[end+2:], s[1:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		}
+	}
+	return len(s) == 0
+}
+
+func classMatches(class string, c byte) bool {
+	for i := 0; i < len(class); i++ {
+		if i+2 < len(class) && class[i+1] == '-' {
+			if c >= class[i] && c <= class[i+2] {
+				return true
+			}
+			i += 2
+			continue
+		}
+		if class[i] == c {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMemoryValkey_WritesRemoveTheExpiredKeys(t *testing.T) {
+	m := NewMemoryValkeyCache()
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 100; i++ {
+		_ = m.Set(ctx, "session:"+strconv.Itoa(i), "v", WithExpiration(time.Second))
+	}
+	_ = m.Set(ctx, "kept", "v")
+	now = now.Add(2 * time.Second)
+
+	// The expired keys are never read again; a write to another key must clean them up
+	_, _ = m.SAdd(ctx, "other", "a")
+	m.mu.Lock()
+	left := len(m.data)
+	m.mu.Unlock()
+	if left != 2 {
+		t.Errorf("E#3DU592 - %v keys are left after the write; want 2 (the expired ones removed)", left)
+	}
+	if v, err := m.Get(ctx, "kept"); err != nil || v != "v" {
+		t.Errorf("E#3BA6RT - A key without expiry was removed (%q, %v)", v, err)
+	}
+}
@@ -0,0 +1,427 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// --- Keys ---
+
+// Del removes the keys and returns how many of them existed. In cluster mode, the keys may be in different slots
+// (which a single DEL rejects with CROSSSLOT), so they are deleted one by one (and not atomically).
+func (c *ValkeyCache) Del(ctx context.Context, keys ...string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	if c.client.Mode() == valkey.ClientModeCluster {
+		return c.sumPerKey(ctx, keys, func(key string) valkey.Completed {
+			return c.client.B().Del().Key(key).Build()
+		})
+	}
+	return c.int64(ctx, func() valkey.Completed {
+		return c.client.B().Del().Key(keys...).Build()
+	})
+}
+
+// Exists returns how many of the keys exist. In cluster mode, the keys are checked one by one (see Del).
+func (c *ValkeyCache) Exists(ctx context.Context, keys ...string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	if c.client.Mode() == valkey.ClientModeCluster {
+		return c.sumPerKey(ctx, keys, func(key string) valkey.Completed {
+			return c.client.B().Exists().Key(key).Build()
+		})
+	}
+	return c.int64(ctx, func() valkey.Completed {
+		return c.client.B().Exists().Key(keys...).Build()
+	})
+}
+
+// Expire sets the time to live of the key. It tells if the key exists.
+func (c *ValkeyCache) Expire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	n, err := c.int64(ctx, func() valkey.Completed {
+		return c.client.B().Pexpire().Key(key).Milliseconds(ttl.Milliseconds()).Build()
+	})
+	return n == 1, err
+}
+
+// TTL returns the remaining time to live of the key, or TTLNoExpiry if it has none.
+// Returns ErrNotFound if the key does not exist.
+func (c *ValkeyCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ms, err := c.int64(ctx, func() valkey.Completed {
+		return c.client.B().Pttl().Key(key).Build()
+	})
+	switch {
+	case err != nil:
+		return 0, err
+	case ms == -2:
+		return 0, ErrNotFound
+	case ms == -1:
+		return TTLNoExpiry, nil
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// Scan calls fn for every key matching the pattern (e.g. `user:*`) until it returns false. In cluster mode, all
+// the master nodes are scanned. A key may be seen more than once if the keyspace changes while it is being scanned.
+func (c *ValkeyCache) Scan(ctx context.Context, match string, fn func(key string) bool) error {
+	nodes := []valkey.Client{c.client}
+	if c.client.Mode() == valkey.ClientModeCluster {
+		var err error
+		if nodes, err = c.masterNodes(ctx); err != nil {
+			return err
+		}
+	}
+	for _, node := range nodes {
+		cursor := uint64(0)
+		for {
+			var entry valkey.ScanEntry
+			err := c.run(ctx, func(ctx context.Context) (err error) {
+				entry, err = node.Do(ctx, node.B().Scan().Cursor(cursor).Match(match).Count(100).Build()).AsScanEntry()
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			for _, key := range entry.Elements {
+				if !fn(key) {
+					return nil
+				}
+			}
+			if cursor = entry.Cursor; cursor == 0 {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// masterNodes returns the clients of the master nodes of the cluster
+func (c *ValkeyCache) masterNodes(ctx context.Context) ([]valkey.Client, error) {
+	var masters []valkey.Client
+	for _, node := range c.client.Nodes() {
+		role, err := node.Do(ctx, node.B().Role().Build()).ToArray()
+		if err != nil {
+			return nil, err
+		}
+		if len(role) > 0 {
+			if name, _ := role[0].ToString(); name == "master" {
+				masters = append(masters, node)
+			}
+		}
+	}
+	return masters, nil
+}
+
+// --- Strings and counters ---
+
+// MGet returns the values of the keys. The missing keys are not present in the map. In cluster mode, the keys
+// are grouped by slot.
+func (c *ValkeyCache) MGet(ctx context.Context, keys ...string) (map[string]string, error) {
+	var msgs map[string]valkey.ValkeyMessage
+	err := c.run(ctx, func(ctx context.Context) (err error) {
+		msgs, err = valkey.MGet(c.client, ctx, keys)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	vals := make(map[string]string, len(msgs))
+	for key, msg := range msgs {
+		if val, err := msg.ToString(); err == nil {
+			vals[key] = val
+		}
+	}
+	return vals, nil
+}
+
+// MSet sets the values of the keys. In cluster mode, the keys are grouped by slot (so the whole operation is not
+// atomic).
+func (c *ValkeyCache) MSet(ctx context.Context, values map[string]string) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return c.run(ctx, func(ctx context.Context) error {
+		for _, err := range valkey.MSet(c.client, ctx, values) {
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Incr increments the integer value of the key by one and returns the new value. A missing key is taken as 0.
+func (c *ValkeyCache) Incr(ctx context.Context, key string) (int64, error) {
+	return c.IncrBy(ctx, key, 1)
+}
+
+// IncrBy increments the integer value of the key by the given amount and returns the new value. A missing key is
+// taken as 0.
+func (c *ValkeyCache) IncrBy(ctx context.Context, key string, by int64) (int64, error) {
+	return c.int64(ctx, func() valkey.Completed {
+		return c.client.B().Incrby().Key(key).Increment(by).Build()
+	})
+}
+
+// --- Lists ---
+
+// LLen returns the length of the list at key (0 if the key does not exist).
+func (c *ValkeyCache) LLen(ctx context.Context, key string) (int64, error) {
+	return c.int64(ctx, func() valkey.Completed {
+		return c.client.B().Llen().Key(key).Build()
+	})
+}
+
+// --- Hashes ---
+
+// HSet sets the fields of the hash and returns how many of them were added.
+func (c *ValkeyCache) HSet(ctx context.Context, key string, fields map[string]string) (int64, error) {
+	if len(fields) == 0 {
+		return 0, errors.New("valkey: HSet requires at least one field")
+	}
+	return c.int64(ctx, func() valkey.Completed {
+		cmd := c.client.B().Hset().Key(key).FieldValue()
+		for field, value := range fields {
+			cmd = cmd.FieldValue(field, value)
+		}
+		return cmd.Build()
+	})
+}
+
+// HGet returns the value of the field of the hash (from the local cache when WithClientSideCache is used).
+// Returns ErrNotFound if the key or the field does not exist.
+func (c *ValkeyCache) HGet(ctx context.Context, key, field string) (string, error) {
+	var val string
+	err := c.run(ctx, func(ctx context.Context) (err error) {
+		if c.cacheTTL > 0 {
+			val, err = c.doCache(ctx, c.client.B().Hget().Key(key).Field(field).Cache()).ToString()
+			return err
+		}
+		val, err = c.client.Do(ctx, c.client.B().Hget().Key(key).Field(field).Build()).ToString()
+		return err
+	})
+	if valkey.IsValkeyNil(err) {
+		return "", ErrNotFound
+	}
+	return val, err
+}
+
+// HGetAll returns all the fields of the hash (an empty map if the key does not exist).
+func (c *ValkeyCache) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	var vals map[string]string
+	err := c.run(ctx, func(ctx context.Context) (err error) {
+		vals, err = c.client.Do(ctx, c.client.B().Hgetall().Key(key).Build()).AsStrMap()
+		return err
+	})
+	return vals, err
+}
+
+// HDel removes the fields from the hash and returns how many of them existed.
+func (c *ValkeyCache) HDel(ctx context.Context, key string, fields ...string) (int64, error) {
+	if len(fields) == 0 {
+		return 0, nil
+	}
+	return c.int64(ctx, func() valkey.Completed {
+		return c.client.B().Hdel().Key(key).Field(fields...).Build()
+	})
+}
+
+// HExists tells if the field exists in the hash.
+func (c *ValkeyCache) HExists(ctx context.Context, key, field string) (bool, error) {
+	n, err := c.int64(ctx, func() valkey.Completed {
+		return c.client.B().Hexists().Key(key).Field(field).Build()
+	})
+	return n == 1, err
+}
+
+// HIncrBy increments the integer value of the field of the hash and returns the new value.
+func (c *ValkeyCache) HIncrBy(ctx context.Context, key, field string, by int64) (int64, error) {
+	return c.int64(ctx, func() valkey.Completed {
+		return c.client.B().Hincrby().Key(key).Field(field).Increment(by).Build()
+	})
+}
+
+// HLen returns the number of fields of the hash.
+func (c *ValkeyCache) HLen(ctx context.Context, key string) (int64, error) {
+	return c.int64(ctx, func() valkey.Completed {
+		return c.client.B().Hlen().Key(key).Build()
+	})
+}
+
+// --- Sets ---
+
+// SAdd adds the members to the set and returns how many of them were not there already.
+func (c *ValkeyCache) SAdd(ctx context.Context, key string, members ...string) (int64, error) {
+	if len(members) == 0 {
+		return 0, errors.New("valkey: SAdd requires at least one member")
+	}
+	return c.int64(ctx, func() valkey.Completed {
+		return c.client.B().Sadd().Key(key).Member(members...).Build()
+	})
+}
+
+// SRem removes the members from the set and returns how many of them were there.
+func (c *ValkeyCache) SRem(ctx context.Context, key string, members ...string) (int64, error) {
+	if len(members) == 0 {
+		return 0, nil
+	}
+	return c.int64(ctx, func() valkey.Completed {
+		return c.client.B().Srem().Key(key).Member(members...).Build()
+	})
+}
+
+// SMembers returns the members of the set (an empty slice if the key does not exist).
+func (c *ValkeyCache) SMembers(ctx context.Context, key string) ([]string, error) {
+	var vals []string
+	err := c.run(ctx, func(ctx context.Context) (err error) {
+		vals, err = c.client.Do(ctx, c.client.B().Smembers().Key(key).Build()).AsStrSlice()
+		return err
+	})
+	return vals, err
+}
+
+// SIsMember tells if the value is a member of the set.
+func (c *ValkeyCache) SIsMember(ctx context.Context, key, member string) (bool, error) {
+	n, err := c.int64(ctx, func() valkey.Completed {
+		return c.client.B().Sismember().Key(key).Member(member).Build()
+	})
+	return n == 1, err
+}
+
+// SCard returns the number of members of the set.
+func (c *ValkeyCache) SCard(ctx context.Context, key string) (int64, error) {
+	return c.int64(ctx, func() valkey.Completed {
+		return c.client.B().Scard().Key(key).Build()
+	})
+}
+
+// --- Sorted sets ---
+
+// ZAdd adds the members to the sorted set (updating the scores of the existing ones) and returns how many of them
+// were added.
+func (c *ValkeyCache) ZAdd(ctx context.Context, key string, members ...ZMember) (int64, error) {
+	if len(members) == 0 {
+		return 0, errors.New("valkey: ZAdd requires at least one member")
+	}
+	return c.int64(ctx, func() valkey.Completed {
+		cmd := c.client.B().Zadd().Key(key).ScoreMember()
+		for _, m := range members {
+			cmd = cmd.ScoreMember(m.Score, m.Member)
+		}
+		return cmd.Build()
+	})
+}
+
+// ZRem removes the members from the sorted set and returns how many of them were there.
+func (c *ValkeyCache) ZRem(ctx context.Context, key string, members ...string) (int64, error) {
+	if len(members) == 0 {
+		return 0, nil
+	}
+	return c.int64(ctx, func() valkey.Completed {
+		return c.client.B().Zrem().Key(key).Member(members...).Build()
+	})
+}
+
+// ZScore returns the score of the member.
+// Returns ErrNotFound if the key or the member does not exist.
+func (c *ValkeyCache) ZScore(ctx context.Context, key, member string) (float64, error) {
+	var score float64
+	err := c.run(ctx, func(ctx context.Context) (err error) {
+		score, err = c.client.Do(ctx, c.client.B().Zscore().Key(key).Member(member).Build()).AsFloat64()
+		return err
+	})
+	if valkey.IsValkeyNil(err) {
+		return 0, ErrNotFound
+	}
+	return score, err
+}
+
+// ZIncrBy increments the score of the member (adding it if needed) and returns the new score.
+func (c *ValkeyCache) ZIncrBy(ctx context.Context, key, member string, by float64) (float64, error) {
+	var score float64
+	err := c.run(ctx, func(ctx context.Context) (err error) {
+		score, err = c.client.Do(ctx, c.client.B().Zincrby().Key(key).Increment(by).Member(member).Build()).AsFloat64()
+		return err
+	})
+	return score, err
+}
+
+// ZRange returns the members between the two (inclusive, possibly negative) ranks, by ascending score.
+func (c *ValkeyCache) ZRange(ctx context.Context, key string, start, stop int64) ([]ZMember, error) {
+	return c.zscores(ctx, func() valkey.Completed {
+		return c.client.B().Zrange().Key(key).
+			Min(strconv.FormatInt(start, 10)).Max(strconv.FormatInt(stop, 10)).Withscores().Build()
+	})
+}
+
+// ZRangeByScore returns the members with a score between min and max (e.g. "1", "(1" for an exclusive bound,
+// "-inf", "+inf"), by ascending score.
+func (c *ValkeyCache) ZRangeByScore(ctx context.Context, key, min, max string) ([]ZMember, error) {
+	return c.zscores(ctx, func() valkey.Completed {
+		return c.client.B().Zrange().Key(key).Min(min).Max(max).Byscore().Withscores().Build()
+	})
+}
+
+// ZCard returns the number of members of the sorted set.
+func (c *ValkeyCache) ZCard(ctx context.Context, key string) (int64, error) {
+	return c.int64(ctx, func() valkey.Completed {
+		return c.client.B().Zcard().Key(key).Build()
+	})
+}
+
+// --- Helpers ---
+
+// int64 runs a command replying with an integer. The command is built for every attempt, since valkey-go recycles
+// the commands it has sent.
+func (c *ValkeyCache) int64(ctx context.Context, build func() valkey.Completed) (int64, error) {
+	var n int64
+	err := c.run(ctx, func(ctx context.Context) (err error) {
+		n, err = c.client.Do(ctx, build()).AsInt64()
+		return err
+	})
+	return n, err
+}
+
+// sumPerKey sends one command per key (pipelined, each to the node of its slot) and returns the sum of their replies
+func (c *ValkeyCache) sumPerKey(ctx context.Context, keys []string, build func(key string) valkey.Completed) (int64, error) {
+	var sum int64
+	err := c.run(ctx, func(ctx context.Context) error {
+		sum = 0
+		cmds := make(valkey.Commands, len(keys))
+		for i, key := range keys {
+			cmds[i] = build(key)
+		}
+		for _, result := range c.client.DoMulti(ctx, cmds...) {
+			n, err := result.AsInt64()
+			if err != nil {
+				return err
+			}
+			sum += n
+		}
+		return nil
+	})
+	return sum, err
+}
+
+// zscores runs a command replying with members and their scores
+func (c *ValkeyCache) zscores(ctx context.Context, build func() valkey.Completed) ([]ZMember, error) {
+	var scores []valkey.ZScore
+	err := c.run(ctx, func(ctx context.Context) (err error) {
+		scores, err = c.client.Do(ctx, build()).AsZScores()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	members := make([]ZMember, len(scores))
+	for i, s := range scores {
+		members[i] = ZMember{Member: s.Member, Score: s.Score}
+	}
+	return members, nil
+}
@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/valkey-io/valkey-go"
+)
+
+// newMiniValkey connects a ValkeyCache to an in-process server (which does not support the client side caching)
+func newMiniValkey(t *testing.T) (*ValkeyCache, *miniredis.Miniredis) {
+	srv := miniredis.RunT(t)
+	host, port, _ := strings.Cut(srv.Addr(), ":")
+	c, err := NewValkeyCache(host, port, WithoutClientSideCache())
+	if err != nil {
+		t.Fatalf("E#3BU8W2 - Could not connect to the test server: %v", err)
+	}
+	t.Cleanup(c.Close)
+	return c, srv
+}
+
+func TestValkeyCache_SetKeepsTheMilliseconds(t *testing.T) {
+	c, srv := newMiniValkey(t)
+	ctx := context.Background()
+
+	// A sub-second expiration used to be sent as EX 0, which the server rejects
+	if err := c.Set(ctx, "short", "v", WithExpiration(500*time.Millisecond)); err != nil {
+		t.Fatalf("E#3HHC1H - Set with a sub-second expiration failed: %v", err)
+	}
+	if ttl := srv.TTL("short"); ttl != 500*time.Millisecond {
+		t.Errorf("E#3EH4YX - The expiration is %v; want 500ms", ttl)
+	}
+	_ = c.Set(ctx, "long", "v", WithExpiration(1500*time.Millisecond))
+	if ttl := srv.TTL("long"); ttl != 1500*time.Millisecond {
+		t.Errorf("E#3HCY6X - The expiration is %v; want 1.5s (not truncated to the second)", ttl)
+	}
+}
+
+func TestValkeyCache_SumPerKey(t *testing.T) {
+	c, _ := newMiniValkey(t)
+	ctx := context.Background()
+	_ = c.Set(ctx, "a", "1")
+	_ = c.Set(ctx, "b", "2")
+
+	// The way Del and Exists send the keys in cluster mode
+	n, err := c.sumPerKey(ctx, []string{"a", "b", "missing"}, func(key string) valkey.Completed {
+		return c.client.B().Del().Key(key).Build()
+	})
+	if err != nil || n != 2 {
+		t.Errorf("E#3G0N0Z - Deleting the keys one by one gave %v, %v; want 2", n, err)
+	}
+	if n, _ := c.Exists(ctx, "a", "b"); n != 0 {
+		t.Errorf("E#3E1X9V - %v of the keys still exist", n)
+	}
+}
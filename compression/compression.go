@@ -0,0 +1,211 @@
+// Package compression contains the content negotiation and the encoders used by the response compression
+// middlewares of both the fasthttp (webServer) and the net/http (netserver) flavours of the servers.
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/techrail/ground/typs/appError"
+)
+
+const (
+	Gzip     = "gzip"
+	Deflate  = "deflate"
+	Brotli   = "br"
+	Zstd     = "zstd"
+	Identity = "identity"
+)
+
+// Config controls which responses are compressed and how
+type Config struct {
+	MinSize      int      // Responses smaller than this (in bytes) are not compressed
+	ContentTypes []string // Allowed content types. An entry ending with "/" (e.g. "text/") matches the whole family
+	Encodings    []string // Supported encodings in the order of server preference (used to break ties)
+}
+
+// DefaultConfig returns the configuration which is used by the middlewares when none is supplied
+func DefaultConfig() Config {
+	return Config{
+		MinSize: 1024,
+		ContentTypes: []string{
+			"application/json",
+			"application/problem+json",
+			"application/xml",
+			"application/javascript",
+			"image/svg+xml",
+			"text/",
+		},
+		Encodings: []string{Zstd, Brotli, Gzip, Deflate},
+	}
+}
+
+// mediaType returns the media type of the content type, without its parameters
+func mediaType(contentType string) string {
+	return strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+}
+
+// IsEventStream tells if the content type is the one of an event stream (whatever its parameters)
+func IsEventStream(contentType string) bool {
+	return mediaType(contentType) == "text/event-stream"
+}
+
+// ContentTypeAllowed tells if a response with the given content type should be compressed
+func (c Config) ContentTypeAllowed(contentType string) bool {
+	mediaType := mediaType(contentType)
+	if mediaType == "" || mediaType == "text/event-stream" {
+		// Streams are never compressed, they have to reach the client as soon as they are written
+		return false
+	}
+	for _, allowed := range c.ContentTypes {
+		allowed = strings.ToLower(allowed)
+		if strings.HasSuffix(allowed, "/") && strings.HasPrefix(mediaType, allowed) {
+			return true
+		}
+		if mediaType == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// Negotiate picks the encoding to use for the given Accept-Encoding header value. The encoding with the highest
+// q-value wins and ties are broken using the order of Config.Encodings. A blank string is returned when the
+// response should not be compressed.
+func (c Config) Negotiate(acceptEncoding string) string {
+	if strings.TrimSpace(acceptEncoding) == "" {
+		return ""
+	}
+
+	qValues := map[string]float64{}
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if parsed, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if name == "*" {
+			wildcard = q
+		} else {
+			qValues[name] = q
+		}
+	}
+
+	best := ""
+	bestQ := 0.0
+	for _, enc := range c.Encodings {
+		q, ok := qValues[enc]
+		if !ok {
+			// x-gzip is an alias of gzip
+			if enc == Gzip {
+				q, ok = qValues["x-gzip"]
+			}
+			if !ok {
+				if wildcard < 0 {
+					continue
+				}
+				q = wildcard
+			}
+		}
+		if q > bestQ {
+			best = enc
+			bestQ = q
+		}
+	}
+	return best
+}
+
+var (
+	gzipPool = sync.Pool{New: func() any {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	}}
+	zlibPool = sync.Pool{New: func() any {
+		w, _ := zlib.NewWriterLevel(nil, zlib.DefaultCompression)
+		return w
+	}}
+	brotliPool = sync.Pool{New: func() any {
+		return brotli.NewWriterLevel(nil, brotli.DefaultCompression)
+	}}
+	zstdPool = sync.Pool{New: func() any {
+		w, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+		return w
+	}}
+)
+
+type resettableWriter interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// Compress compresses the body using the given encoding
+func Compress(encoding string, body []byte) ([]byte, appError.Typ) {
+	var pool *sync.Pool
+	switch encoding {
+	case Gzip:
+		pool = &gzipPool
+	case Deflate:
+		pool = &zlibPool
+	case Brotli:
+		pool = &brotliPool
+	case Zstd:
+		pool = &zstdPool
+	default:
+		return nil, appError.NewError(appError.Error, "3AT3IS", fmt.Sprintf("Unsupported encoding: %v", encoding))
+	}
+
+	var buf bytes.Buffer
+	w := pool.Get().(resettableWriter)
+	defer pool.Put(w)
+	w.Reset(&buf)
+
+	if _, err := w.Write(body); err != nil {
+		return nil, appError.NewError(appError.Error, "3B68JI", fmt.Sprintf("Could not compress the body: %v", err))
+	}
+	if err := w.Close(); err != nil {
+		return nil, appError.NewError(appError.Error, "3HF2AE", fmt.Sprintf("Could not compress the body: %v", err))
+	}
+	return buf.Bytes(), appError.BlankError
+}
+
+// AddVary returns the value of the Vary header with the given header name added to it (if not already present)
+func AddVary(existing string, header string) string {
+	if strings.TrimSpace(existing) == "" {
+		return header
+	}
+	for _, v := range strings.Split(existing, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || strings.EqualFold(v, header) {
+			return existing
+		}
+	}
+	return existing + ", " + header
+}
+
+// WeakETag returns the ETag of a compressed response: a strong ETag is made weak, as it identifies the bytes of the
+// uncompressed body (the compressed representation is only semantically equivalent to it).
+func WeakETag(etag string) string {
+	if etag == "" || strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return "W/" + etag
+}
@@ -0,0 +1,73 @@
+package compression
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/gzip"
+)
+
+func TestConfig_Negotiate(t *testing.T) {
+	cfg := DefaultConfig()
+	cases := map[string]string{
+		"":                        "",
+		"gzip":                    Gzip,
+		"gzip, deflate, br":       Brotli,
+		"gzip, deflate, br, zstd": Zstd,
+		"br;q=0.5, gzip":          Gzip,
+		"zstd;q=0, gzip;q=0.1":    Gzip,
+		"*":                       Zstd,
+		"*;q=0, deflate":          Deflate,
+		"identity":                "",
+		"x-gzip":                  Gzip,
+	}
+	for acceptEncoding, expected := range cases {
+		if got := cfg.Negotiate(acceptEncoding); got != expected {
+			t.Errorf("E#3FQ2D5 - For `%v` expected `%v`, got `%v`", acceptEncoding, expected, got)
+		}
+	}
+}
+
+func TestConfig_ContentTypeAllowed(t *testing.T) {
+	cfg := DefaultConfig()
+	if !cfg.ContentTypeAllowed("application/json; charset=utf-8") {
+		t.Errorf("E#3F71U9 - Expected JSON to be compressible")
+	}
+	if !cfg.ContentTypeAllowed("text/html") {
+		t.Errorf("E#3FJAUM - Expected text/html to be compressible")
+	}
+	if cfg.ContentTypeAllowed("image/png") || cfg.ContentTypeAllowed("text/event-stream") {
+		t.Errorf("E#3AWTME - Did not expect images or event streams to be compressible")
+	}
+}
+
+func TestCompress_Gzip(t *testing.T) {
+	body := bytes.Repeat([]byte(`{"data":"ground"}`), 100)
+	compressed, e := Compress(Gzip, body)
+	if e.IsNotBlank() {
+		t.Fatalf("E#3G2XWB - Compression failed: %v", e)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("E#3F7BPD - Could not read the gzip stream: %v", err)
+	}
+	decompressed, _ := io.ReadAll(r)
+	if !bytes.Equal(body, decompressed) {
+		t.Errorf("E#3FQ2D6 - Round trip did not return the original body")
+	}
+}
+
+func TestIsEventStreamAndWeakETag(t *testing.T) {
+	if !IsEventStream("text/event-stream; charset=utf-8") || IsEventStream("text/plain") {
+		t.Errorf("E#3EL5X7 - The event streams must be recognised whatever their parameters")
+	}
+	if DefaultConfig().ContentTypeAllowed("text/event-stream; charset=utf-8") {
+		t.Errorf("E#3AZTWD - Did not expect an event stream with a charset to be compressible")
+	}
+	for etag, expected := range map[string]string{`"abc"`: `W/"abc"`, `W/"abc"`: `W/"abc"`, "": ""} {
+		if got := WeakETag(etag); got != expected {
+			t.Errorf("E#3FNYYE - For `%v` expected `%v`, got `%v`", etag, expected, got)
+		}
+	}
+}
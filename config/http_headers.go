@@ -0,0 +1,63 @@
+package config
+
+// HeaderValueOff can be used as the value of any of the string header settings to stop that header from being sent
+const HeaderValueOff = "off"
+
+type httpHeaders struct {
+	EmitServerIdentity bool   // Should the Server and X-Powered-By headers be sent at all
+	Server             string // Value of the Server header
+	PoweredBy          string // Value of the X-Powered-By header
+	Security           securityHeaders
+}
+
+type securityHeaders struct {
+	HstsMaxAgeInSeconds   int64  // Strict-Transport-Security max-age. Zero disables the header
+	HstsIncludeSubdomains bool   // Add includeSubDomains to the Strict-Transport-Security header
+	HstsPreload           bool   // Add preload to the Strict-Transport-Security header
+	ContentSecurityPolicy string // Value of the Content-Security-Policy header
+	FrameOptions          string // Value of the X-Frame-Options header
+	ContentTypeNoSniff    bool   // Send `X-Content-Type-Options: nosniff`
+	ReferrerPolicy        string // Value of the Referrer-Policy header
+	PermissionsPolicy     string // Value of the Permissions-Policy header
+}
+
+func init() {
+	// NOTE: Default config
+	config.HttpHeaders = httpHeaders{
+		EmitServerIdentity: true,
+		Server:             "Apache 2.4",
+		PoweredBy:          "PHP/7.2.12",
+		Security: securityHeaders{
+			HstsMaxAgeInSeconds:   0,
+			HstsIncludeSubdomains: false,
+			HstsPreload:           false,
+			ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+			FrameOptions:          "DENY",
+			ContentTypeNoSniff:    true,
+			ReferrerPolicy:        "no-referrer",
+			PermissionsPolicy:     HeaderValueOff,
+		},
+	}
+}
+
+func initializeHttpHeadersConfig() {
+	config.HttpHeaders.EmitServerIdentity = envOrViperOrDefaultBool("httpHeaders.emitServerIdentity",
+		config.HttpHeaders.EmitServerIdentity)
+	config.HttpHeaders.Server = envOrViperOrDefaultString("httpHeaders.server", config.HttpHeaders.Server)
+	config.HttpHeaders.PoweredBy = envOrViperOrDefaultString("httpHeaders.poweredBy", config.HttpHeaders.PoweredBy)
+
+	sec := &config.HttpHeaders.Security
+	sec.HstsMaxAgeInSeconds = envOrViperOrDefaultInt64("httpHeaders.security.hstsMaxAgeInSeconds",
+		sec.HstsMaxAgeInSeconds)
+	sec.HstsIncludeSubdomains = envOrViperOrDefaultBool("httpHeaders.security.hstsIncludeSubdomains",
+		sec.HstsIncludeSubdomains)
+	sec.HstsPreload = envOrViperOrDefaultBool("httpHeaders.security.hstsPreload", sec.HstsPreload)
+	sec.ContentSecurityPolicy = envOrViperOrDefaultString("httpHeaders.security.contentSecurityPolicy",
+		sec.ContentSecurityPolicy)
+	sec.FrameOptions = envOrViperOrDefaultString("httpHeaders.security.frameOptions", sec.FrameOptions)
+	sec.ContentTypeNoSniff = envOrViperOrDefaultBool("httpHeaders.security.contentTypeNoSniff",
+		sec.ContentTypeNoSniff)
+	sec.ReferrerPolicy = envOrViperOrDefaultString("httpHeaders.security.referrerPolicy", sec.ReferrerPolicy)
+	sec.PermissionsPolicy = envOrViperOrDefaultString("httpHeaders.security.permissionsPolicy",
+		sec.PermissionsPolicy)
+}
@@ -1,19 +1,31 @@
 package config
 
+import "sync/atomic"
+
 type masterConf struct {
-	AppName  string
-	Startup  startup
-	Time     tym
-	Database database
-	Logging  loggingConfig
+	AppName     string
+	Startup     startup
+	Time        tym
+	Database    database
+	Logging     loggingConfig
+	HttpHeaders httpHeaders
 }
 
 var config masterConf
 
+// generation is incremented each time the config is initialized
+var generation atomic.Uint64
+
 func Store() *masterConf {
 	return &config
 }
 
+// Generation changes each time the config is initialized, so that the values derived from the config can be
+// computed once and rebuilt only when the config is loaded again
+func Generation() uint64 {
+	return generation.Load()
+}
+
 func InitializeConfig() {
 	// Should the startup variables be printed or not
 	determineLaunchInfoPrintSetting()
@@ -23,4 +35,6 @@ func InitializeConfig() {
 	initializeTimeConfig()
 	initializeDatabaseConfig()
 	initializeLoggingConfig()
+	initializeHttpHeadersConfig()
+	generation.Add(1)
 }
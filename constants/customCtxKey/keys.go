@@ -16,3 +16,12 @@ const StackTraceRequested = "ctx_StackTraceRequested"
 
 // DevMsgAllowedInFailure tells whether the dev msg was expected by client in case of an error
 const DevMsgAllowedInFailure = "ctx_DevMsgAllowedInFailure"
+
+// ProblemDetailsPreferred tells the failure renderers to respond with RFC 7807 problem details
+const ProblemDetailsPreferred = "ctx_ProblemDetailsPreferred"
+
+// TraceParent holds the W3C `traceparent` header received with the request (forwarded by the outbound HTTP client)
+const TraceParent = "ctx_TraceParent"
+
+// TraceState holds the W3C `tracestate` header received with the request (forwarded by the outbound HTTP client)
+const TraceState = "ctx_TraceState"
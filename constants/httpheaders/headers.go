@@ -50,6 +50,7 @@ const (
 	Forwarded                       = "Forwarded"
 	From                            = "From"
 	Host                            = "Host"
+	IdempotencyKey                  = "Idempotency-Key"
 	IfMatch                         = "If-Match"
 	IfModifiedSince                 = "If-Modified-Since"
 	IfNoneMatch                     = "If-None-Match"
@@ -95,6 +96,8 @@ const (
 	TE                              = "TE"
 	TimingAllowOrigin               = "Timing-Allow-Origin"
 	Tk                              = "Tk"
+	Traceparent                     = "Traceparent"
+	Tracestate                      = "Tracestate"
 	Trailer                         = "Trailer"
 	TransferEncoding                = "Transfer-Encoding"
 	Upgrade                         = "Upgrade"
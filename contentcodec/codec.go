@@ -0,0 +1,153 @@
+// Package contentcodec contains the encoders (and decoders) for the content types ground can speak: JSON,
+// MessagePack, CBOR, XML and protobuf. The renderers use it to pick the encoding of the response based on the
+// `Accept` header of the request.
+package contentcodec
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	NameJson     = "json"
+	NameMsgPack  = "msgpack"
+	NameCbor     = "cbor"
+	NameXml      = "xml"
+	NameProtobuf = "protobuf"
+)
+
+// Codec encodes values to (and decodes values from) one content type
+type Codec interface {
+	Name() string
+	ContentType() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	Json     Codec = jsonCodec{}
+	MsgPack  Codec = msgPackCodec{}
+	Cbor     Codec = cborCodec{}
+	Xml      Codec = xmlCodec{}
+	Protobuf Codec = protobufCodec{}
+)
+
+// ==== JSON ====
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string        { return NameJson }
+func (jsonCodec) ContentType() string { return "application/json; charset=utf-8" }
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// ==== MessagePack ====
+
+// msgPackCodec uses the `json` struct tags so that the field names are the same as in the JSON responses
+type msgPackCodec struct{}
+
+func (msgPackCodec) Name() string        { return NameMsgPack }
+func (msgPackCodec) ContentType() string { return "application/msgpack" }
+
+func (msgPackCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.SetOmitEmpty(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgPackCodec) Unmarshal(data []byte, v any) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+// ==== CBOR ====
+
+// cborCodec falls back to the `json` struct tags (when there are no `cbor` tags), so the field names are the same
+// as in the JSON responses
+type cborCodec struct{}
+
+func (cborCodec) Name() string        { return NameCbor }
+func (cborCodec) ContentType() string { return "application/cbor" }
+
+func (cborCodec) Marshal(v any) ([]byte, error) {
+	return cbor.Marshal(v)
+}
+
+func (cborCodec) Unmarshal(data []byte, v any) error {
+	return cbor.Unmarshal(data, v)
+}
+
+// ==== XML ====
+
+type xmlCodec struct{}
+
+func (xmlCodec) Name() string        { return NameXml }
+func (xmlCodec) ContentType() string { return "application/xml; charset=utf-8" }
+
+func (xmlCodec) Marshal(v any) ([]byte, error) {
+	b, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), b...), nil
+}
+
+func (xmlCodec) Unmarshal(data []byte, v any) error {
+	return xml.Unmarshal(data, v)
+}
+
+// ==== Protobuf ====
+
+// protobufCodec marshals proto.Message values as they are. Any other value (e.g. the failure envelope) is converted
+// to a google.protobuf.Struct (through its JSON representation) and then marshalled.
+type protobufCodec struct{}
+
+func (protobufCodec) Name() string        { return NameProtobuf }
+func (protobufCodec) ContentType() string { return "application/x-protobuf" }
+
+func (protobufCodec) Marshal(v any) ([]byte, error) {
+	if m, ok := v.(proto.Message); ok {
+		return proto.Marshal(m)
+	}
+
+	jsonBytes, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var asMap map[string]any
+	if err = json.Unmarshal(jsonBytes, &asMap); err != nil {
+		return nil, errors.New("only proto.Message values and JSON objects can be encoded as protobuf")
+	}
+	s, err := structpb.NewStruct(asMap)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(s)
+}
+
+func (protobufCodec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return errors.New("protobuf can only be decoded into a proto.Message")
+	}
+	return proto.Unmarshal(data, m)
+}
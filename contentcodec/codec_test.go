@@ -0,0 +1,80 @@
+package contentcodec
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/techrail/ground/typs/jsonObject"
+)
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		accept   string
+		payload  any
+		expected Codec
+	}{
+		{"", struct{}{}, Json},
+		{"*/*", struct{}{}, Json},
+		{"application/msgpack", struct{}{}, MsgPack},
+		{"application/cbor;q=0.5, application/xml", struct{}{}, Xml},
+		{"text/html, application/cbor;q=0.9", struct{}{}, Cbor},
+		{"application/x-protobuf", struct{}{}, Json},
+		{"application/x-protobuf", wrapperspb.String("ground"), Protobuf},
+	}
+	for _, c := range cases {
+		if got := Negotiate(c.accept, c.payload); got != c.expected {
+			t.Errorf("E#3CWG49 - For `%v` expected %v, got %v", c.accept, c.expected.Name(), got.Name())
+		}
+	}
+
+	if NegotiateForFailure("application/x-protobuf") != Protobuf {
+		t.Errorf("E#3B2PKL - Expected failures to be encodable as protobuf")
+	}
+}
+
+type envelope struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func TestCodecs_RoundTripUsesJsonNames(t *testing.T) {
+	for _, codec := range []Codec{Json, MsgPack, Cbor} {
+		b, err := codec.Marshal(envelope{Code: "1ABCDE", Message: "hello"})
+		if err != nil {
+			t.Fatalf("E#3F13AG - %v marshal failed: %v", codec.Name(), err)
+		}
+		var m map[string]any
+		if err = codec.Unmarshal(b, &m); err != nil {
+			t.Fatalf("E#3AEY9J - %v unmarshal failed: %v", codec.Name(), err)
+		}
+		if m["code"] != "1ABCDE" || m["message"] != "hello" {
+			t.Errorf("E#3GPXR0 - %v did not use the json field names: %v", codec.Name(), m)
+		}
+	}
+
+	b, err := Protobuf.Marshal(envelope{Code: "1ABCDE", Message: "hello"})
+	if err != nil || len(b) == 0 {
+		t.Errorf("E#3CJIT9 - Expected the envelope to be encodable as protobuf. Error: %v", err)
+	}
+}
+
+func TestNegotiate_XmlNeedsAnEncodablePayload(t *testing.T) {
+	accept := "application/xml, application/cbor;q=0.5"
+	for _, payload := range []any{
+		map[string]any{"name": "ground"},
+		jsonObject.EmptyNotNullJsonObject(),
+		struct{ Tags map[string]string }{Tags: map[string]string{}},
+	} {
+		codec := Negotiate(accept, payload)
+		if codec != Cbor {
+			t.Errorf("E#3A3UM5 - Expected the next best codec for %T, got %v", payload, codec.Name())
+		}
+		if _, err := codec.Marshal(payload); err != nil {
+			t.Errorf("E#3CE2FO - Could not encode %T with the negotiated codec: %v", payload, err)
+		}
+	}
+	if codec := Negotiate(accept, envelope{Code: "1ABCDE"}); codec != Xml {
+		t.Errorf("E#3B9OOR - Expected XML for a struct payload, got %v", codec.Name())
+	}
+}
@@ -0,0 +1,132 @@
+package contentcodec
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// mediaTypes maps the media types we understand to the codecs which produce them
+var mediaTypes = map[string]Codec{
+	"application/json":                Json,
+	"text/json":                       Json,
+	"application/msgpack":             MsgPack,
+	"application/x-msgpack":           MsgPack,
+	"application/vnd.msgpack":         MsgPack,
+	"application/cbor":                Cbor,
+	"application/xml":                 Xml,
+	"text/xml":                        Xml,
+	"application/x-protobuf":          Protobuf,
+	"application/protobuf":            Protobuf,
+	"application/vnd.google.protobuf": Protobuf,
+}
+
+// Negotiate picks the codec for the response based on the value of the `Accept` header. The media type with the
+// highest q-value that we understand wins; ties are broken by the order in which the client listed them. Protobuf
+// is only chosen when the payload is a proto.Message, and XML when the payload can be encoded as XML (it has no
+// maps, e.g. a jsonObject.Typ). JSON is returned when nothing else matches.
+func Negotiate(accept string, payload any) Codec {
+	return negotiate(accept, func(codec Codec) bool {
+		switch codec {
+		case Protobuf:
+			_, isProto := payload.(proto.Message)
+			return isProto
+		case Xml:
+			return xmlEncodable(reflect.ValueOf(payload), 0)
+		}
+		return true
+	})
+}
+
+// NegotiateForFailure picks the codec for a failure response. The failure envelope can be sent in every format
+// (including protobuf, as a google.protobuf.Struct).
+func NegotiateForFailure(accept string) Codec {
+	return negotiate(accept, func(Codec) bool { return true })
+}
+
+// ForContentType returns the codec for the given Content-Type header value (nil if we can't handle it)
+func ForContentType(contentType string) Codec {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	return mediaTypes[mediaType]
+}
+
+func negotiate(accept string, allowed func(codec Codec) bool) Codec {
+	best := Json
+	bestQ := 0.0
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if parsed, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q <= bestQ {
+			continue
+		}
+
+		if mediaType == "*/*" || mediaType == "application/*" {
+			// JSON is our default
+			best, bestQ = Json, q
+			continue
+		}
+		codec, ok := mediaTypes[mediaType]
+		if !ok || !allowed(codec) {
+			continue
+		}
+		best, bestQ = codec, q
+	}
+	return best
+}
+
+// maxXmlCheckDepth bounds the walk of xmlEncodable (deeper values, or cyclic ones, are assumed encodable)
+const maxXmlCheckDepth = 32
+
+var xmlMarshalerType = reflect.TypeFor[xml.Marshaler]()
+
+// xmlEncodable tells if encoding/xml can marshal the value: it can not marshal maps, channels and functions
+func xmlEncodable(v reflect.Value, depth int) bool {
+	if !v.IsValid() || depth > maxXmlCheckDepth {
+		return true
+	}
+	if v.Type().Implements(xmlMarshalerType) {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Map, reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128:
+		return false
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return true
+		}
+		return xmlEncodable(v.Elem(), depth+1)
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return true
+		}
+		for i := 0; i < v.Len(); i++ {
+			if !xmlEncodable(v.Index(i), depth+1) {
+				return false
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() || f.Tag.Get("xml") == "-" {
+				continue
+			}
+			if !xmlEncodable(v.Field(i), depth+1) {
+				return false
+			}
+		}
+	}
+	return true
+}
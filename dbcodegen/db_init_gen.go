@@ -74,3 +74,70 @@ func (g *Generator) buildInitCode(importList []string, tables map[string]DbTable
 
 	return initCode, importList
 }
+
+// buildDbType builds the type holding the DB connection. When ResilienceInDb is set, the type gets a Resilience
+// field and its Get, Select and Exec methods (and their Context variants, which keep the deadline and the
+// cancellation of the caller) run the queries through the policy (if one is set).
+func (g *Generator) buildDbType(importList []string) (string, []string) {
+	dbType := "type db struct {\n"
+	dbType += "*sqlx.DB\n"
+	dbType += "sync.Mutex\n"
+	if !g.Config.ResilienceInDb {
+		dbType += "}\n"
+		return dbType, importList
+	}
+
+	dbType += "// Resilience (when set) protects the queries run through Get, Select and Exec (and their Context variants).\n"
+	dbType += "// Do not configure retries unless all the statements run through Exec are safe to repeat.\n"
+	dbType += "Resilience *resilience.Policy\n"
+	dbType += "}\n\n"
+
+	dbType += "// GetContext works like sqlx.DB.GetContext, but runs the query through the resilience policy (if any)\n"
+	dbType += "func (d *db) GetContext(ctx context.Context, dest any, query string, args ...any) error {\n"
+	dbType += "return d.Resilience.Run(ctx, func(ctx context.Context) error {\n"
+	dbType += "return d.DB.GetContext(ctx, dest, query, args...)\n"
+	dbType += "}, isNoRows)\n"
+	dbType += "}\n\n"
+
+	dbType += "// Get works like GetContext with the background context\n"
+	dbType += "func (d *db) Get(dest any, query string, args ...any) error {\n"
+	dbType += "return d.GetContext(context.Background(), dest, query, args...)\n"
+	dbType += "}\n\n"
+
+	dbType += "// SelectContext works like sqlx.DB.SelectContext, but runs the query through the resilience policy (if any)\n"
+	dbType += "func (d *db) SelectContext(ctx context.Context, dest any, query string, args ...any) error {\n"
+	dbType += "return d.Resilience.Run(ctx, func(ctx context.Context) error {\n"
+	dbType += "return d.DB.SelectContext(ctx, dest, query, args...)\n"
+	dbType += "}, isNoRows)\n"
+	dbType += "}\n\n"
+
+	dbType += "// Select works like SelectContext with the background context\n"
+	dbType += "func (d *db) Select(dest any, query string, args ...any) error {\n"
+	dbType += "return d.SelectContext(context.Background(), dest, query, args...)\n"
+	dbType += "}\n\n"
+
+	dbType += "// ExecContext works like sqlx.DB.ExecContext, but runs the statement through the resilience policy (if any)\n"
+	dbType += "func (d *db) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {\n"
+	dbType += "var result sql.Result\n"
+	dbType += "err := d.Resilience.Run(ctx, func(ctx context.Context) (err error) {\n"
+	dbType += "result, err = d.DB.ExecContext(ctx, query, args...)\n"
+	dbType += "return err\n"
+	dbType += "}, nil)\n"
+	dbType += "return result, err\n"
+	dbType += "}\n\n"
+
+	dbType += "// Exec works like ExecContext with the background context\n"
+	dbType += "func (d *db) Exec(query string, args ...any) (sql.Result, error) {\n"
+	dbType += "return d.ExecContext(context.Background(), query, args...)\n"
+	dbType += "}\n\n"
+
+	dbType += "// isNoRows tells if the error only means that nothing was found (which is not a failure of the DB)\n"
+	dbType += "func isNoRows(err error) bool {\n"
+	dbType += "return errors.Is(err, sql.ErrNoRows)\n"
+	dbType += "}\n"
+
+	for _, imp := range []string{"context", "database/sql", "errors", "github.com/techrail/ground/resilience"} {
+		importList = g.addToImports(imp, importList)
+	}
+	return dbType, importList
+}
@@ -299,6 +299,7 @@ type CodegenConfig struct {
 	ColumnOrderAlphabetic    bool   // Column order in generated code will be alphabetic if this is set to true, ordinal otherwise
 	Enumerations             map[string]EnumDefinition
 	SkipTablesIfIsolated     []string // Skip code generation of these tables (schema.table format) if they are isolated
+	ResilienceInDb           bool     // Should the generated db type run Get, Select and Exec through an (optional) resilience policy?
 }
 
 // Generator is the structure we return to a client which needs a generator.
@@ -1271,10 +1272,7 @@ func (g *Generator) Generate() appError.Typ {
 //{{PACKAGE_NAME}}
 
 //{{IMPORT_LIST}}
-type db struct {
-  *sqlx.DB
-  sync.Mutex
-}
+//{{DB_TYPE}}
 //{{INIT_CODE}}
 
 //{{MAGIC_COMMENT}}
@@ -1283,6 +1281,7 @@ type db struct {
 	importList = []string{}
 	importsString = ""
 	initCode, importList := g.buildInitCode([]string{"fmt", "os", "sync", "github.com/jmoiron/sqlx"}, tables)
+	dbTypeCode, importList := g.buildDbType(importList)
 	if len(importList) > 0 {
 		importsString += "\nimport (\n"
 		for _, impo := range importList {
@@ -1298,6 +1297,7 @@ type db struct {
 	fileContent = initFileTemplate
 	fileContent = strings.ReplaceAll(fileContent, "//{{PACKAGE_CONTENT}}", fmt.Sprintf("// Package %v contains the model code against the DB", g.Config.DbModelPackageName))
 	fileContent = strings.ReplaceAll(fileContent, "//{{PACKAGE_NAME}}", fmt.Sprintf("package %v", g.Config.DbModelPackageName))
+	fileContent = strings.ReplaceAll(fileContent, "//{{DB_TYPE}}", dbTypeCode)
 	fileContent = strings.ReplaceAll(fileContent, "//{{INIT_CODE}}", initCode)
 	fileContent = strings.ReplaceAll(fileContent, "//{{IMPORT_LIST}}", importsString)
 	fileContent = strings.ReplaceAll(fileContent, "//{{MAGIC_COMMENT}}", g.Config.MagicComment)
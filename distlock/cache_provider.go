@@ -0,0 +1,138 @@
+package distlock
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/techrail/ground/cache"
+	"github.com/techrail/ground/typs/appError"
+	"github.com/techrail/ground/uuid"
+)
+
+// The lock key holds the id of the owner; the fence key holds the last token given out and never expires. The name
+// is in a hash tag, so that both keys are on the same node of a cluster.
+
+const acquireScript = `
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return redis.call('INCR', KEYS[2])
+end
+return 0`
+
+const refreshScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0`
+
+const releaseScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`
+
+// evalFunc runs one of the scripts and returns its integer reply
+type evalFunc func(ctx context.Context, script string, keys []string, args ...string) (int64, error)
+
+// cacheProvider implements the locks of redis and valkey, which only differ by how the scripts are run
+type cacheProvider struct {
+	backend string
+	eval    evalFunc
+}
+
+// NewRedisProvider creates a provider keeping the locks in redis (in the namespace of the application)
+func NewRedisProvider(c *cache.Client) Provider {
+	scripts := map[string]*goredis.Script{}
+	for _, src := range []string{acquireScript, refreshScript, releaseScript} {
+		scripts[src] = goredis.NewScript(src)
+	}
+	return &cacheProvider{
+		backend: "redis",
+		eval: func(ctx context.Context, script string, keys []string, args ...string) (int64, error) {
+			namespaced := make([]string, len(keys))
+			for i, key := range keys {
+				namespaced[i] = c.Key(key)
+			}
+			argv := make([]any, len(args))
+			for i, arg := range args {
+				argv[i] = arg
+			}
+			return scripts[script].Run(ctx, c.Connection, namespaced, argv...).Int64()
+		},
+	}
+}
+
+// NewValkeyProvider creates a provider keeping the locks in valkey. The in-memory ValkeyCacheAPI can not run
+// scripts; use NewMemoryProvider instead.
+func NewValkeyProvider(c cache.ValkeyCacheAPI) Provider {
+	scripts := map[string]*cache.Script{}
+	for _, src := range []string{acquireScript, refreshScript, releaseScript} {
+		scripts[src] = cache.NewScript(src)
+	}
+	return &cacheProvider{
+		backend: "valkey",
+		eval: func(ctx context.Context, script string, keys []string, args ...string) (int64, error) {
+			reply, err := c.Eval(ctx, scripts[script], keys, args)
+			if err != nil {
+				return 0, err
+			}
+			n, ok := reply.(int64)
+			if !ok {
+				return 0, fmt.Errorf("unexpected reply %v", reply)
+			}
+			return n, nil
+		},
+	}
+}
+
+func (p *cacheProvider) TryAcquire(ctx context.Context, name string, ttl time.Duration) (Lock, bool, appError.Typ) {
+	l := &cacheLock{provider: p, name: name, owner: uuid.GetNewUlidAsString()}
+	token, err := p.eval(ctx, acquireScript, []string{l.key(), l.key() + ":fence"}, l.owner, millis(ttl))
+	if err != nil {
+		return nil, false, appError.NewError(appError.Error, "3AJVPF",
+			fmt.Sprintf("Could not acquire the lock %v in %v: %v", name, p.backend, err))
+	}
+	if token == 0 {
+		return nil, false, appError.BlankError
+	}
+	l.token = token
+	return l, true, appError.BlankError
+}
+
+type cacheLock struct {
+	provider *cacheProvider
+	name     string
+	owner    string // Random id of this acquisition, stored in the lock key
+	token    int64
+}
+
+func (l *cacheLock) Name() string { return l.name }
+func (l *cacheLock) Token() int64 { return l.token }
+
+func (l *cacheLock) Refresh(ctx context.Context, ttl time.Duration) (bool, appError.Typ) {
+	n, err := l.provider.eval(ctx, refreshScript, []string{l.key()}, l.owner, millis(ttl))
+	if err != nil {
+		return false, appError.NewError(appError.Error, "3EOAA6",
+			fmt.Sprintf("Could not refresh the lock %v in %v: %v", l.name, l.provider.backend, err))
+	}
+	return n == 1, appError.BlankError
+}
+
+func (l *cacheLock) Release(ctx context.Context) appError.Typ {
+	if _, err := l.provider.eval(ctx, releaseScript, []string{l.key()}, l.owner); err != nil {
+		return appError.NewError(appError.Error, "3HZ58N",
+			fmt.Sprintf("Could not release the lock %v in %v: %v", l.name, l.provider.backend, err))
+	}
+	return appError.BlankError
+}
+
+func (l *cacheLock) key() string {
+	return "lock:{" + l.name + "}"
+}
+
+func millis(d time.Duration) string {
+	return strconv.FormatInt(max(d.Milliseconds(), 1), 10)
+}
@@ -0,0 +1,73 @@
+package distlock
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/techrail/ground/cache"
+)
+
+func TestRedisProvider(t *testing.T) {
+	srv := miniredis.RunT(t)
+	c, errTy := cache.CreateNewRedisClient(cache.RedisConfig{
+		Enabled:       true,
+		Url:           "redis://" + srv.Addr(),
+		OperationMode: cache.ModeStandalone,
+		Requirement:   cache.RequirementHardcore,
+		AppNamespace:  "app",
+	})
+	if errTy.IsNotBlank() {
+		t.Fatalf("E#3FVUH3 - Could not connect to the test server: %v", errTy)
+	}
+	t.Cleanup(func() { c.Close() })
+	testCacheProvider(t, NewRedisProvider(c), srv, "app:")
+}
+
+func TestValkeyProvider(t *testing.T) {
+	srv := miniredis.RunT(t)
+	host, port, _ := strings.Cut(srv.Addr(), ":")
+	c, err := cache.NewValkeyCache(host, port, cache.WithoutClientSideCache())
+	if err != nil {
+		t.Fatalf("E#3BSSU3 - Could not connect to the test server: %v", err)
+	}
+	t.Cleanup(c.Close)
+	testCacheProvider(t, NewValkeyProvider(c), srv, "")
+}
+
+// testCacheProvider checks the scripts of the provider against the server, whose keys start with the prefix
+func testCacheProvider(t *testing.T, p Provider, srv *miniredis.Miniredis, prefix string) {
+	ctx := context.Background()
+	first, acquired, errTy := p.TryAcquire(ctx, "job", time.Minute)
+	if errTy.IsNotBlank() || !acquired {
+		t.Fatalf("E#3FM0ME - The free lock was not acquired: %v", errTy)
+	}
+	if _, acquired, _ = p.TryAcquire(ctx, "job", time.Minute); acquired {
+		t.Errorf("E#3A2332 - The held lock was acquired again")
+	}
+	if held, _ := first.Refresh(ctx, 2*time.Minute); !held || srv.TTL(prefix+"lock:{job}") != 2*time.Minute {
+		t.Errorf("E#3C4GHN - The lock was not refreshed by its holder")
+	}
+
+	srv.FastForward(3 * time.Minute)
+	second, acquired, _ := p.TryAcquire(ctx, "job", time.Minute)
+	if !acquired || second.Token() <= first.Token() {
+		t.Fatalf("E#3A9KEX - The expired lock was not acquired with a greater token")
+	}
+	if held, _ := first.Refresh(ctx, time.Hour); held || srv.TTL(prefix+"lock:{job}") != time.Minute {
+		t.Errorf("E#3HJ5LK - The stale owner refreshed the lock")
+	}
+	_ = first.Release(ctx)
+	if _, acquired, _ = p.TryAcquire(ctx, "job", time.Minute); acquired {
+		t.Errorf("E#3G2TB2 - The stale owner released the lock of its new holder")
+	}
+
+	_ = second.Release(ctx)
+	third, acquired, _ := p.TryAcquire(ctx, "job", time.Minute)
+	if !acquired || third.Token() <= second.Token() {
+		t.Errorf("E#3D8754 - The released lock was not acquired with a greater token")
+	}
+}
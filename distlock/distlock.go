@@ -0,0 +1,67 @@
+// Package distlock contains the distributed locks used to run something on one replica at a time (e.g. a cron job
+// which must run exactly once cluster-wide) and a leader election built on them. The locks are provided by redis or
+// valkey (SET NX PX, released safely with a Lua script), by PostgreSQL (advisory locks) or, for tests and single
+// node deployments, by the process itself.
+//
+// Every acquisition gets a fencing token, which is greater than the tokens of the earlier acquisitions of the lock.
+// A lock can be lost without its holder noticing (a long GC pause, a network partition...), so the writes guarded by
+// a lock should carry the token and the storage should reject the tokens lower than the last one it saw.
+package distlock
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/techrail/ground/typs/appError"
+)
+
+// Provider hands out the locks. The implementations must be safe for concurrent use.
+type Provider interface {
+	// TryAcquire tries to acquire the named lock once, for the given time. It tells whether the lock was acquired.
+	TryAcquire(ctx context.Context, name string, ttl time.Duration) (Lock, bool, appError.Typ)
+}
+
+// Expiring is implemented by the providers which can tell whether their locks expire once their ttl is over. The
+// locks of the providers which do not implement it expire.
+type Expiring interface {
+	// Expiring tells whether the locks expire once their ttl is over. Those which do not are held until released.
+	Expiring() bool
+}
+
+// LocksExpire tells whether the locks of the provider expire once their ttl is over (see Expiring)
+func LocksExpire(p Provider) bool {
+	e, ok := p.(Expiring)
+	return !ok || e.Expiring()
+}
+
+// Lock is an acquired lock
+type Lock interface {
+	// Name returns the name of the lock
+	Name() string
+	// Token returns the fencing token of the acquisition
+	Token() int64
+	// Refresh extends the lock by the given time. It returns false if the lock was lost meanwhile.
+	Refresh(ctx context.Context, ttl time.Duration) (bool, appError.Typ)
+	// Release gives the lock up, if it is still held
+	Release(ctx context.Context) appError.Typ
+}
+
+// Acquire waits until the named lock is acquired, trying every retryInterval, or until the context is done
+func Acquire(ctx context.Context, p Provider, name string, ttl, retryInterval time.Duration) (Lock, appError.Typ) {
+	for {
+		lock, acquired, errTy := p.TryAcquire(ctx, name, ttl)
+		if errTy.IsNotBlank() {
+			return nil, errTy
+		}
+		if acquired {
+			return lock, appError.BlankError
+		}
+		select {
+		case <-ctx.Done():
+			return nil, appError.NewError(appError.Error, "3GC81S",
+				fmt.Sprintf("Gave up waiting for the lock %v: %v", name, ctx.Err()))
+		case <-time.After(retryInterval):
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package distlock
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryLock(t *testing.T) {
+	p := NewMemoryProvider()
+	ctx := context.Background()
+	first, acquired, _ := p.TryAcquire(ctx, "job", 30*time.Millisecond)
+	if !acquired {
+		t.Fatalf("E#3G27AW - The free lock was not acquired")
+	}
+	if _, acquired, _ = p.TryAcquire(ctx, "job", time.Minute); acquired {
+		t.Errorf("E#3A8E4P - The held lock was acquired again")
+	}
+	time.Sleep(50 * time.Millisecond)
+	second, acquired, _ := p.TryAcquire(ctx, "job", time.Minute)
+	if !acquired || second.Token() <= first.Token() {
+		t.Fatalf("E#3DVOU1 - The expired lock was not acquired with a greater token")
+	}
+	if held, _ := first.Refresh(ctx, time.Minute); held {
+		t.Errorf("E#3BLURY - The lost lock was refreshed")
+	}
+	_ = first.Release(ctx)
+	if _, acquired, _ = p.TryAcquire(ctx, "job", time.Minute); acquired {
+		t.Errorf("E#3BIML8 - The release of a lost lock freed the lock of its new holder")
+	}
+}
+
+func TestElectionHandoff(t *testing.T) {
+	p := NewMemoryProvider()
+	newElector := func() (*Elector, context.CancelFunc) {
+		cfg := DefaultElectionConfig("leader")
+		cfg.TTL = 60 * time.Millisecond
+		e := NewElector(p, cfg)
+		ctx, cancel := context.WithCancel(context.Background())
+		go e.Run(ctx)
+		return e, cancel
+	}
+	a, stopA := newElector()
+	time.Sleep(10 * time.Millisecond)
+	b, stopB := newElector()
+	defer stopB()
+	time.Sleep(50 * time.Millisecond)
+	if !a.IsLeader() || b.IsLeader() {
+		t.Fatalf("E#3HYTU3 - Expected only the first elector to lead (%v, %v)", a.IsLeader(), b.IsLeader())
+	}
+	token := a.Token()
+	stopA()
+	time.Sleep(100 * time.Millisecond)
+	if a.IsLeader() || !b.IsLeader() || b.Token() <= token {
+		t.Errorf("E#3CH8O5 - The leadership was not handed off (%v, %v)", a.IsLeader(), b.IsLeader())
+	}
+}
+
+func TestAdvisoryKey(t *testing.T) {
+	if advisoryKey("a") == advisoryKey("b") || advisoryKey("a") != advisoryKey("a") {
+		t.Errorf("E#3C8QMO - The advisory keys are not derived from the names")
+	}
+}
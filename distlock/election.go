@@ -0,0 +1,140 @@
+package distlock
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/techrail/ground/logger"
+)
+
+// ElectionConfig configures an Elector; see DefaultElectionConfig
+type ElectionConfig struct {
+	Name string        // Name of the lock held by the leader
+	TTL  time.Duration // Time after which the leadership of a dead leader ends
+	// RenewInterval is how often the leader extends its lock (TTL/3 by default)
+	RenewInterval time.Duration
+	// RetryInterval is how often the other replicas try to take the lock (TTL/3 by default)
+	RetryInterval time.Duration
+	// OnElected is called when this replica becomes the leader, and OnDemoted when it stops being the leader (with
+	// the reason). They are called by the election loop, so they must return quickly.
+	OnElected func(lock Lock)
+	OnDemoted func(reason string)
+}
+
+// DefaultElectionConfig returns the configuration of an election with a TTL of 15 seconds
+func DefaultElectionConfig(name string) ElectionConfig {
+	return ElectionConfig{Name: name, TTL: 15 * time.Second}
+}
+
+// Elector takes part in the election of a leader among the replicas: the replica holding the lock is the leader.
+// Create it using NewElector and run it using Run.
+type Elector struct {
+	provider Provider
+	cfg      ElectionConfig
+	mu       sync.Mutex
+	lock     Lock // Held while leader
+}
+
+// NewElector creates an elector using the lock provider
+func NewElector(p Provider, cfg ElectionConfig) *Elector {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 15 * time.Second
+	}
+	if cfg.RenewInterval <= 0 {
+		cfg.RenewInterval = cfg.TTL / 3
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = cfg.TTL / 3
+	}
+	return &Elector{provider: p, cfg: cfg}
+}
+
+// IsLeader tells if this replica is the leader
+func (e *Elector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.lock != nil
+}
+
+// Token returns the fencing token of the current leadership (0 when not the leader)
+func (e *Elector) Token() int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.lock == nil {
+		return 0
+	}
+	return e.lock.Token()
+}
+
+// Run takes part in the election until the context is done, when it gives the leadership up (if held)
+func (e *Elector) Run(ctx context.Context) {
+	var validUntil time.Time // When the lock expires unless it is renewed
+	for {
+		wait := e.cfg.RetryInterval
+		started := time.Now()
+		if lock := e.currentLock(); lock == nil {
+			lock, acquired, errTy := e.provider.TryAcquire(ctx, e.cfg.Name, e.cfg.TTL)
+			if errTy.IsNotBlank() && ctx.Err() == nil {
+				logger.Warn(fmt.Sprintf("W#3FPLPP - Could not take part in the election %v: %v", e.cfg.Name, errTy))
+			}
+			if acquired {
+				validUntil = started.Add(e.cfg.TTL)
+				e.setLock(lock)
+				logger.Info(fmt.Sprintf("I#3H71L0 - Elected as the leader of %v (token %v)", e.cfg.Name, lock.Token()))
+				if e.cfg.OnElected != nil {
+					e.cfg.OnElected(lock)
+				}
+				wait = e.cfg.RenewInterval
+			}
+		} else {
+			held, errTy := lock.Refresh(ctx, e.cfg.TTL)
+			switch {
+			case held:
+				validUntil = started.Add(e.cfg.TTL)
+				wait = e.cfg.RenewInterval
+			case errTy.IsBlank():
+				e.demote("the lock was lost")
+			case time.Now().Add(e.cfg.RenewInterval).After(validUntil):
+				// The lock may expire before the next attempt
+				e.demote(fmt.Sprintf("the lock could not be renewed: %v", errTy))
+			default:
+				logger.Warn(fmt.Sprintf("W#3AZZYB - Could not renew the leadership of %v, retrying: %v", e.cfg.Name, errTy))
+				wait = e.cfg.RenewInterval
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if lock := e.currentLock(); lock != nil {
+				e.demote("the elector was stopped")
+				if errTy := lock.Release(context.WithoutCancel(ctx)); errTy.IsNotBlank() {
+					logger.Warn(fmt.Sprintf("W#3HAJOY - Could not give the leadership of %v up: %v", e.cfg.Name, errTy))
+				}
+			}
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (e *Elector) currentLock() Lock {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.lock
+}
+
+func (e *Elector) setLock(lock Lock) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lock = lock
+}
+
+func (e *Elector) demote(reason string) {
+	e.setLock(nil)
+	logger.Warn(fmt.Sprintf("W#3DQT9J - No longer the leader of %v: %v", e.cfg.Name, reason))
+	if e.cfg.OnDemoted != nil {
+		e.cfg.OnDemoted(reason)
+	}
+}
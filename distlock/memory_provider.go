@@ -0,0 +1,69 @@
+package distlock
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/techrail/ground/typs/appError"
+)
+
+// MemoryProvider keeps the locks in the process, for tests and single node deployments
+type MemoryProvider struct {
+	mu     sync.Mutex
+	held   map[string]memoryHold
+	tokens map[string]int64 // Last token given out, per name
+}
+
+type memoryHold struct {
+	token     int64
+	expiresAt time.Time
+}
+
+// NewMemoryProvider creates an in-process provider
+func NewMemoryProvider() *MemoryProvider {
+	return &MemoryProvider{held: map[string]memoryHold{}, tokens: map[string]int64{}}
+}
+
+func (p *MemoryProvider) TryAcquire(ctx context.Context, name string, ttl time.Duration) (Lock, bool, appError.Typ) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if h, found := p.held[name]; found && time.Now().Before(h.expiresAt) {
+		return nil, false, appError.BlankError
+	}
+	p.tokens[name]++
+	token := p.tokens[name]
+	p.held[name] = memoryHold{token: token, expiresAt: time.Now().Add(ttl)}
+	return &memoryLock{provider: p, name: name, token: token}, true, appError.BlankError
+}
+
+type memoryLock struct {
+	provider *MemoryProvider
+	name     string
+	token    int64
+}
+
+func (l *memoryLock) Name() string { return l.name }
+func (l *memoryLock) Token() int64 { return l.token }
+
+func (l *memoryLock) Refresh(ctx context.Context, ttl time.Duration) (bool, appError.Typ) {
+	p := l.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	h, found := p.held[l.name]
+	if !found || h.token != l.token || !time.Now().Before(h.expiresAt) {
+		return false, appError.BlankError
+	}
+	p.held[l.name] = memoryHold{token: l.token, expiresAt: time.Now().Add(ttl)}
+	return true, appError.BlankError
+}
+
+func (l *memoryLock) Release(ctx context.Context) appError.Typ {
+	p := l.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if h, found := p.held[l.name]; found && h.token == l.token {
+		delete(p.held, l.name)
+	}
+	return appError.BlankError
+}
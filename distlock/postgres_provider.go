@@ -0,0 +1,115 @@
+package distlock
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/techrail/ground/typs/appError"
+)
+
+// PostgresProvider uses the session level advisory locks of PostgreSQL. A lock holds a connection of the pool until
+// it is released, and is released by the server when the connection (or the process) dies, so the TTLs do not
+// apply: a lock is held until it is released or lost. The fencing tokens are transaction ids (txid_current), which
+// only go up. For sqlx, pass the embedded *sql.DB.
+type PostgresProvider struct {
+	db *sql.DB
+}
+
+// NewPostgresProvider creates a provider using the database
+func NewPostgresProvider(db *sql.DB) *PostgresProvider {
+	return &PostgresProvider{db: db}
+}
+
+// Expiring returns false: the locks are held until released (see Expiring)
+func (p *PostgresProvider) Expiring() bool {
+	return false
+}
+
+func (p *PostgresProvider) TryAcquire(ctx context.Context, name string, ttl time.Duration) (Lock, bool, appError.Typ) {
+	conn, err := p.db.Conn(ctx)
+	if err != nil {
+		return nil, false, appError.NewError(appError.Error, "3DDLY0",
+			fmt.Sprintf("Could not get a connection for the lock %v: %v", name, err))
+	}
+	key := advisoryKey(name)
+	var acquired bool
+	if err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+		discard(conn)
+		return nil, false, appError.NewError(appError.Error, "3EFC7Y",
+			fmt.Sprintf("Could not acquire the lock %v: %v", name, err))
+	}
+	if !acquired {
+		_ = conn.Close()
+		return nil, false, appError.BlankError
+	}
+
+	l := &postgresLock{conn: conn, name: name, key: key}
+	if err = conn.QueryRowContext(ctx, "SELECT txid_current()").Scan(&l.token); err != nil {
+		_ = l.Release(context.WithoutCancel(ctx))
+		return nil, false, appError.NewError(appError.Error, "3HFNYU",
+			fmt.Sprintf("Could not get the fencing token of the lock %v: %v", name, err))
+	}
+	return l, true, appError.BlankError
+}
+
+type postgresLock struct {
+	mu    sync.Mutex
+	conn  *sql.Conn // nil once released
+	name  string
+	key   int64
+	token int64
+}
+
+func (l *postgresLock) Name() string { return l.name }
+func (l *postgresLock) Token() int64 { return l.token }
+
+// Refresh checks that the connection holding the lock is still alive (the ttl is not used)
+func (l *postgresLock) Refresh(ctx context.Context, ttl time.Duration) (bool, appError.Typ) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn == nil {
+		return false, appError.BlankError
+	}
+	if err := l.conn.PingContext(ctx); err != nil {
+		// The session is gone and the server released the lock with it
+		discard(l.conn)
+		l.conn = nil
+		return false, appError.BlankError
+	}
+	return true, appError.BlankError
+}
+
+func (l *postgresLock) Release(ctx context.Context) appError.Typ {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn == nil {
+		return appError.BlankError
+	}
+	conn := l.conn
+	l.conn = nil
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", l.key); err != nil {
+		// The connection must not go back to the pool while its session holds the lock
+		discard(conn)
+		return appError.NewError(appError.Error, "3C0YT5", fmt.Sprintf("Could not release the lock %v: %v", l.name, err))
+	}
+	_ = conn.Close()
+	return appError.BlankError
+}
+
+// discard closes the underlying connection instead of returning it to the pool, ending its session
+func discard(conn *sql.Conn) {
+	_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+	_ = conn.Close()
+}
+
+// advisoryKey maps the name of the lock to the 64 bit key of the advisory lock
+func advisoryKey(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64())
+}
@@ -1,6 +1,7 @@
 package redis
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -11,15 +12,24 @@ import (
 func TestRedisConnection(t *testing.T) {
 	c := cache.RedisConfig{}
 	c.Enabled = true
-	c.Url = ""
+	c.Url = "redis://127.0.0.1:6379"
 	c.OperationMode = cache.ModeAuto
+	c.Requirement = cache.RequirementSoftcore
 
-	r := cache.CreateNewRedisClient(c)
+	r, errTy := cache.CreateNewRedisClient(c)
+	if errTy.IsNotBlank() {
+		t.Fatalf("E#3HF6O4 - Could not create the redis client: %v", errTy)
+	}
+	defer r.Close()
+	if !r.Connected() {
+		t.Skip("No redis server available at 127.0.0.1:6379")
+	}
+	ctx := context.Background()
 
 	fmt.Println("-------Setting string--------")
-	r.SetStringWithExpiry("name", "Techrail", 3*time.Second)
+	r.Set(ctx, "name", "Techrail", 3*time.Second)
 	fmt.Printf("-------Getting string-------")
-	fmt.Println(r.Get("name"))
+	fmt.Println(r.Get(ctx, "name"))
 
 	var redislist [5]string
 	redislist[0] = "Golang"
@@ -28,7 +38,7 @@ func TestRedisConnection(t *testing.T) {
 	redislist[3] = "Typescript"
 	redislist[4] = "Python"
 
-	redismap := make(map[string]string)
+	redismap := make(map[string]any)
 	redismap["OS"] = "Ubuntu"
 	redismap["Container"] = "Docker"
 	redismap["Orchestration"] = "K8S"
@@ -37,33 +47,30 @@ func TestRedisConnection(t *testing.T) {
 
 	fmt.Println("-------Setting list contents-------")
 	for i := 0; i < 5; i++ {
-		r.SetListContents("TestList", redislist[i])
+		r.LPush(ctx, "TestList", redislist[i])
 	}
 	fmt.Println("-------Getting list contents-------")
-	fmt.Println(r.GetListRange("TestList", 0, 4))
+	fmt.Println(r.LRange(ctx, "TestList", 0, 4))
 
 	fmt.Println("-------Setting hash contents-------")
-	r.SetHash("TestMap", redismap)
+	r.HSet(ctx, "TestMap", redismap)
 	fmt.Println("-------Getting hash contents-------")
-	fmt.Println(r.GetHashVals("TestMap"))
+	fmt.Println(r.HGetAll(ctx, "TestMap"))
+	fmt.Println(r.HGet(ctx, "TestMap", "OS"))
 
 	fmt.Println("-------Setting set contents-------")
-	fmt.Println(r.SetAdd("TestSet", "RedisConnectionTest"))
+	fmt.Println(r.SAdd(ctx, "TestSet", "RedisConnectionTest"))
 	fmt.Println("-------Getting set contents-------")
-	fmt.Println(r.GetSetMembers("TestSet"))
+	fmt.Println(r.SMembers(ctx, "TestSet"))
 
-	fmt.Println("-------Deleting list contents-------")
-	for i := 0; i < 5; i++ {
-		r.DeleteListElements("TestList")
-	}
+	fmt.Println("-------Setting sorted set contents-------")
+	fmt.Println(r.ZAdd(ctx, "TestZSet", cache.ZMember{Member: "a", Score: 2}, cache.ZMember{Member: "b", Score: 1}))
+	fmt.Println("-------Getting sorted set contents-------")
+	fmt.Println(r.ZRange(ctx, "TestZSet", 0, -1))
 
-	fmt.Println("-------Deleting hash contents-------")
-	r.DeleteHash("TestMap", "OS")
-	r.DeleteHash("TestMap", "Container")
-	r.DeleteHash("TestMap", "Orchestration")
-	r.DeleteHash("TestMap", "Cloud")
-	r.DeleteHash("TestMap", "DB")
+	fmt.Println("-------Scanning keys-------")
+	fmt.Println(r.Scan(ctx, "Test*"))
 
-	fmt.Println("-------Deleting set contents-------")
-	r.DeleteSet("TestSet")
+	fmt.Println("-------Deleting keys-------")
+	fmt.Println(r.Delete(ctx, "TestList", "TestMap", "TestSet", "TestZSet"))
 }
@@ -0,0 +1,87 @@
+// Package health keeps a registry of health checks. The subsystems which can degrade (e.g. the circuit breakers of
+// the resilience package) register a check here; the application exposes the results the way it wants.
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Status of a check
+type Status string
+
+const (
+	StatusUp       Status = "up"
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+)
+
+// Result of a check
+type Result struct {
+	Name    string         `json:"name"`
+	Status  Status         `json:"status"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// Checker returns the current health of a component. It must be cheap and safe for concurrent use.
+type Checker func(ctx context.Context) Result
+
+var (
+	mu       sync.RWMutex
+	checkers = map[string]Checker{}
+)
+
+// Register adds (or replaces) the check with the given name
+func Register(name string, checker Checker) {
+	mu.Lock()
+	defer mu.Unlock()
+	checkers[name] = checker
+}
+
+// Unregister removes the check with the given name
+func Unregister(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(checkers, name)
+}
+
+// Check runs all the registered checks and returns the results sorted by name. The name of a result is always the
+// name the check was registered with.
+func Check(ctx context.Context) []Result {
+	mu.RLock()
+	names := make([]string, 0, len(checkers))
+	for name := range checkers {
+		names = append(names, name)
+	}
+	mu.RUnlock()
+	sort.Strings(names)
+
+	results := make([]Result, 0, len(names))
+	for _, name := range names {
+		mu.RLock()
+		checker, ok := checkers[name]
+		mu.RUnlock()
+		if !ok {
+			continue
+		}
+		r := checker(ctx)
+		r.Name = name
+		results = append(results, r)
+	}
+	return results
+}
+
+// Overall returns the worst status among the results (StatusUp when there are none)
+func Overall(results []Result) Status {
+	overall := StatusUp
+	for _, r := range results {
+		switch r.Status {
+		case StatusDown:
+			return StatusDown
+		case StatusDegraded:
+			overall = StatusDegraded
+		}
+	}
+	return overall
+}
@@ -0,0 +1,258 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/techrail/ground/constants/customHeaders"
+	"github.com/techrail/ground/constants/httpheaders"
+	"github.com/techrail/ground/logger"
+	"github.com/techrail/ground/requestid"
+	"github.com/techrail/ground/resilience"
+	"github.com/techrail/ground/typs/appError"
+)
+
+// Client calls other services over HTTP. It is safe for concurrent use. Create it using New.
+type Client struct {
+	cfg      Config
+	breakers *resilience.BreakerSet
+	std      *http.Client
+	fast     *fasthttp.Client
+	fastOnce sync.Once
+}
+
+type callOptionsKey struct{}
+
+// New creates a new client with the given configuration (DefaultConfig if none is given)
+func New(cfg ...Config) *Client {
+	c := &Client{cfg: DefaultConfig()}
+	if len(cfg) > 0 {
+		c.cfg = cfg[0]
+	}
+	c.breakers = resilience.NewBreakerSet(c.cfg.Breaker)
+	c.std = &http.Client{Transport: &transport{client: c}}
+	return c
+}
+
+// HttpClient returns the *http.Client doing the retries, the circuit breaking and the propagation. The timeout of
+// the returned client is not set since the timeout applies to every attempt (see Config.Timeout); use the context
+// of the request to bound the call as a whole.
+func (c *Client) HttpClient() *http.Client {
+	return c.std
+}
+
+// Do sends the request. The error is returned only if no response could be obtained; a response with a non-2xx
+// status is returned as it is (see DecodeFailure).
+func (c *Client) Do(rq *http.Request, opts ...CallOption) (*http.Response, appError.Typ) {
+	if len(opts) > 0 {
+		rq = rq.WithContext(context.WithValue(rq.Context(), callOptionsKey{}, c.cfg.callOptions(opts)))
+	}
+	resp, err := c.std.Do(rq)
+	if err != nil {
+		var errTy appError.Typ
+		if errors.As(err, &errTy) {
+			// e.g. the circuit breaker is open
+			return nil, errTy
+		}
+		return nil, appError.NewError(appError.Error, "3B9SZO",
+			fmt.Sprintf("%v %v failed: %v", rq.Method, rq.URL.Redacted(), err))
+	}
+	return resp, appError.BlankError
+}
+
+// DoJson sends the value of `in` (unless it is nil) as JSON and decodes the JSON response into `out` (unless it is
+// nil). If the response uses ground's success envelope (`{"data": ...}`), the contents of `data` are decoded. A
+// non-2xx response is converted into an error using DecodeFailure.
+func (c *Client) DoJson(ctx context.Context, method, url string, in any, out any, opts ...CallOption) appError.Typ {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return appError.NewError(appError.Error, "3FIHB0", fmt.Sprintf("Could not marshal the request body: %v", err))
+		}
+		body = bytes.NewReader(b)
+	}
+	rq, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return appError.NewError(appError.Error, "3EE0ZQ", fmt.Sprintf("Could not create the request: %v", err))
+	}
+	rq.Header.Set(httpheaders.Accept, "application/json")
+	if in != nil {
+		rq.Header.Set(httpheaders.ContentType, "application/json")
+	}
+
+	resp, errTy := c.Do(rq, opts...)
+	if errTy.IsNotBlank() {
+		return errTy
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return appError.NewError(appError.Error, "3ARMMI", fmt.Sprintf("Could not read the response body: %v", err))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return DecodeFailure(resp.StatusCode, resp.Header.Get(httpheaders.ContentType), respBody)
+	}
+	if out == nil || len(respBody) == 0 {
+		return appError.BlankError
+	}
+
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if json.Unmarshal(respBody, &envelope) == nil && len(envelope.Data) > 0 {
+		respBody = envelope.Data
+	}
+	if err = json.Unmarshal(respBody, out); err != nil {
+		return appError.NewError(appError.Error, "3AJBXW", fmt.Sprintf("Could not unmarshal the response body: %v", err))
+	}
+	return appError.BlankError
+}
+
+// transport does the retries and the circuit breaking on top of the base transport
+type transport struct {
+	client *Client
+}
+
+func (t *transport) RoundTrip(rq *http.Request) (*http.Response, error) {
+	c := t.client
+	base := c.cfg.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	opts, ok := rq.Context().Value(callOptionsKey{}).(callOptions)
+	if !ok {
+		opts = c.cfg.callOptions(nil)
+	}
+
+	// A RoundTripper must not modify the request it was given
+	rq = rq.Clone(rq.Context())
+	if c.cfg.PropagateIds {
+		c.propagate(rq.Context(), rq.Header.Get, rq.Header.Set)
+	}
+	retryable := idempotent(rq.Method, rq.Header.Get(httpheaders.IdempotencyKey) != "") &&
+		(rq.Body == nil || rq.Body == http.NoBody || rq.GetBody != nil)
+	b := c.breakers.ForKey(rq.URL.Host)
+
+	for attempt := 0; ; attempt++ {
+		if errTy := b.Allow(); errTy.IsNotBlank() {
+			return nil, errTy
+		}
+
+		attemptRq := rq
+		if attempt > 0 && rq.GetBody != nil {
+			body, err := rq.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptRq = rq.Clone(rq.Context())
+			attemptRq.Body = body
+		}
+		var ctx context.Context
+		var cancel context.CancelFunc
+		if opts.timeout > 0 {
+			ctx, cancel = context.WithTimeout(rq.Context(), opts.timeout)
+		} else {
+			ctx, cancel = context.WithCancel(rq.Context())
+		}
+
+		resp, err := base.RoundTrip(attemptRq.WithContext(ctx))
+		if rq.Context().Err() != nil {
+			// The caller gave up: the call is neither a success nor a failure of the upstream
+			b.Release()
+		} else {
+			b.Record(err == nil && resp.StatusCode < 500)
+		}
+
+		if attempt < opts.maxRetries && retryable && rq.Context().Err() == nil &&
+			(err != nil || c.retryableStatus(resp.StatusCode)) {
+			retryAfter := ""
+			if resp != nil {
+				retryAfter = resp.Header.Get(httpheaders.RetryAfter)
+				_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+				_ = resp.Body.Close()
+			}
+			cancel()
+			wait := c.backoff(attempt, retryAfter)
+			logger.Println(fmt.Sprintf("D#3D2UWY - Retrying %v %v in %v (attempt %v failed)",
+				rq.Method, rq.URL.Redacted(), wait, attempt+1))
+			if errTy := resilience.Sleep(rq.Context(), wait); errTy.IsNotBlank() {
+				return nil, errTy
+			}
+			continue
+		}
+
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		// The attempt context must live as long as the body is being read
+		resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+		return resp, nil
+	}
+}
+
+// propagate sets the request ID and the trace context headers (unless they were already set)
+func (c *Client) propagate(ctx context.Context, get func(string) string, set func(string, string)) {
+	if get(customHeaders.RequestId) == "" {
+		if id := requestid.FromContext(ctx); id != "" {
+			set(customHeaders.RequestId, id)
+		}
+	}
+	if get(httpheaders.Traceparent) == "" {
+		if tp := childTraceParent(ctx); tp != "" {
+			set(httpheaders.Traceparent, tp)
+			if ts := traceState(ctx); ts != "" {
+				set(httpheaders.Tracestate, ts)
+			}
+		}
+	}
+}
+
+func (c *Client) retryableStatus(statusCode int) bool {
+	return slices.Contains(c.cfg.RetryStatusCodes, statusCode)
+}
+
+// backoff returns the time to wait before the retry after the given attempt (counted from 0), honouring the delay
+// asked for in the Retry-After header (in seconds)
+func (c *Client) backoff(attempt int, retryAfter string) time.Duration {
+	override := time.Duration(0)
+	if secs, err := strconv.Atoi(retryAfter); err == nil && secs >= 0 {
+		if secs == 0 {
+			return 0
+		}
+		override = time.Duration(secs) * time.Second
+	}
+	return c.cfg.retryPolicy().Backoff(attempt, override)
+}
+
+// idempotent tells if the request can be retried safely
+func idempotent(method string, hasIdempotencyKey bool) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return hasIdempotencyKey
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -0,0 +1,166 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/techrail/ground/constants/customCtxKey"
+	"github.com/techrail/ground/constants/customHeaders"
+	"github.com/techrail/ground/constants/httpheaders"
+	"github.com/techrail/ground/requestid"
+	"github.com/techrail/ground/resilience"
+)
+
+func testConfig() Config {
+	cfg := DefaultConfig()
+	cfg.BackoffBase = time.Millisecond
+	cfg.BackoffMax = 5 * time.Millisecond
+	return cfg
+}
+
+func TestRetriesIdempotentRequests(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"name":"ground"}}`))
+	}))
+	defer srv.Close()
+
+	var out struct {
+		Name string `json:"name"`
+	}
+	errTy := New(testConfig()).DoJson(context.Background(), http.MethodGet, srv.URL, nil, &out)
+	if errTy.IsNotBlank() {
+		t.Fatalf("E#3C0PK3 - Call failed: %v", errTy)
+	}
+	if calls.Load() != 3 || out.Name != "ground" {
+		t.Errorf("E#3BKK59 - Got %v calls and name %q; want 3 calls and name %q", calls.Load(), out.Name, "ground")
+	}
+
+	// A POST without an idempotency key is not retried
+	calls.Store(0)
+	errTy = New(testConfig()).DoJson(context.Background(), http.MethodPost, srv.URL, map[string]int{"a": 1}, nil)
+	if errTy.IsBlank() || errTy.HttpResponseCode != http.StatusServiceUnavailable || calls.Load() != 1 {
+		t.Errorf("E#3EG0SA - Got error %v after %v calls; want a 503 after 1 call", errTy, calls.Load())
+	}
+}
+
+func TestDecodesFailureEnvelope(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
+		w.Header().Set(httpheaders.ContentType, "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"code":"1ABCDE","message":"User not found","devMsg":"no row"}`))
+	}))
+	defer srv.Close()
+
+	errTy := New(testConfig()).DoJson(context.Background(), http.MethodGet, srv.URL, nil, nil)
+	if errTy.Code != "1ABCDE" || errTy.Message != "User not found" || errTy.DevMsg != "no row" ||
+		errTy.HttpResponseCode != http.StatusNotFound {
+		t.Errorf("E#3D4TLA - Unexpected error: %+v", errTy)
+	}
+}
+
+func TestBreakerOpensAfterFailures(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig()
+	cfg.MaxRetries = 0
+	cfg.Breaker = BreakerConfig{FailureThreshold: 2, OpenDuration: time.Hour, HalfOpenProbes: 1}
+	c := New(cfg)
+	for i := 0; i < 4; i++ {
+		_ = c.DoJson(context.Background(), http.MethodGet, srv.URL, nil, nil)
+	}
+	errTy := c.DoJson(context.Background(), http.MethodGet, srv.URL, nil, nil)
+	if calls.Load() != 2 || errTy.Code != resilience.CodeBreakerOpen {
+		t.Errorf("E#3HG31X - Got %v calls and error %v; want 2 calls and an open breaker", calls.Load(), errTy)
+	}
+}
+
+func TestPropagatesIds(t *testing.T) {
+	got := make(chan http.Header, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
+		got <- rq.Header.Clone()
+	}))
+	defer srv.Close()
+
+	ctx := requestid.NewContext(context.Background(), "req-1")
+	ctx = context.WithValue(ctx, customCtxKey.TraceParent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if errTy := New(testConfig()).DoJson(ctx, http.MethodGet, srv.URL, nil, nil); errTy.IsNotBlank() {
+		t.Fatalf("E#3EUP46 - Call failed: %v", errTy)
+	}
+	h := <-got
+	tp := h.Get(httpheaders.Traceparent)
+	if h.Get(customHeaders.RequestId) != "req-1" ||
+		!strings.HasPrefix(tp, "00-4bf92f3577b34da6a3ce929d0e0e4736-") || strings.Contains(tp, "00f067aa0ba902b7") {
+		t.Errorf("E#3CYF7W - Unexpected propagated headers: %v", h)
+	}
+}
+
+func TestFastDoRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
+		if calls.Add(1) < 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	rq := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(rq)
+	defer fasthttp.ReleaseResponse(resp)
+	rq.SetRequestURI(srv.URL)
+
+	errTy := New(testConfig()).FastDo(context.Background(), rq, resp)
+	if errTy.IsNotBlank() || resp.StatusCode() != http.StatusOK || calls.Load() != 2 {
+		t.Errorf("E#3BQY71 - Got error %v, status %v after %v calls", errTy, resp.StatusCode(), calls.Load())
+	}
+	if FastDecodeFailure(resp).IsNotBlank() {
+		t.Errorf("E#3CIDHB - A 200 response was decoded as a failure")
+	}
+}
+
+func TestCancelledCallsDoNotOpenTheBreaker(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig()
+	cfg.MaxRetries = 0
+	cfg.Breaker = BreakerConfig{FailureThreshold: 2, OpenDuration: time.Hour, HalfOpenProbes: 1}
+	c := New(cfg)
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		_ = c.DoJson(ctx, http.MethodGet, srv.URL, nil, nil)
+
+		rq := fasthttp.AcquireRequest()
+		resp := fasthttp.AcquireResponse()
+		rq.SetRequestURI(srv.URL)
+		_ = c.FastDo(ctx, rq, resp)
+		fasthttp.ReleaseRequest(rq)
+		fasthttp.ReleaseResponse(resp)
+		cancel()
+	}
+	if errTy := c.DoJson(context.Background(), http.MethodGet, srv.URL, nil, nil); errTy.IsNotBlank() {
+		t.Errorf("E#3EWIWQ - The calls cancelled by the caller opened the breaker: %v", errTy)
+	}
+}
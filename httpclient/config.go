@@ -0,0 +1,85 @@
+// Package httpclient provides a client for calling other services over HTTP. It builds a configured *http.Client
+// (and has the equivalent calls for fasthttp) with per-attempt timeouts, retries with exponential backoff and jitter
+// for idempotent requests, a circuit breaker per host, propagation of the request ID and the W3C trace context and
+// decoding of the failure envelope used by ground's renderers into appError.Typ.
+package httpclient
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/techrail/ground/resilience"
+)
+
+// Config of the client. Use DefaultConfig and change what is needed.
+type Config struct {
+	Timeout          time.Duration     // Timeout of a single attempt (0 means no timeout other than the context)
+	MaxRetries       int               // Number of retries after the first attempt (0 disables the retries)
+	BackoffBase      time.Duration     // Backoff before the first retry; doubled for every next one
+	BackoffMax       time.Duration     // Upper limit of the backoff (and of an honoured Retry-After value)
+	RetryStatusCodes []int             // Response codes which are retried (network errors are always retried)
+	Breaker          BreakerConfig     // Circuit breaker per host
+	PropagateIds     bool              // Forward the request ID and the trace context found in the request context
+	Base             http.RoundTripper // Transport that does the actual work (http.DefaultTransport when nil)
+}
+
+// BreakerConfig configures the circuit breaker kept per host. A breaker opens after FailureThreshold consecutive
+// failures (network errors and 5xx responses) and rejects the calls for OpenDuration. After that, up to
+// HalfOpenProbes calls are let through; the breaker closes if they succeed and opens again if one of them fails.
+// If the breaker has a name, the breakers of the client are reported under that name in the health registry.
+type BreakerConfig = resilience.BreakerConfig
+
+// DefaultConfig returns the default configuration of the client
+func DefaultConfig() Config {
+	return Config{
+		Timeout:          10 * time.Second,
+		MaxRetries:       2,
+		BackoffBase:      100 * time.Millisecond,
+		BackoffMax:       2 * time.Second,
+		RetryStatusCodes: []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+		Breaker: BreakerConfig{
+			FailureThreshold: 5,
+			OpenDuration:     30 * time.Second,
+			HalfOpenProbes:   1,
+		},
+		PropagateIds: true,
+	}
+}
+
+// retryPolicy returns the retry policy of the client
+func (c Config) retryPolicy() resilience.RetryPolicy {
+	return resilience.RetryPolicy{MaxRetries: c.MaxRetries, BackoffBase: c.BackoffBase, BackoffMax: c.BackoffMax}
+}
+
+// CallOption changes the configuration for a single call
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	timeout    time.Duration
+	maxRetries int
+}
+
+// WithTimeout sets the timeout of every attempt of the call
+func WithTimeout(d time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.timeout = d
+	}
+}
+
+// WithRetries sets the number of retries of the call (0 disables the retries)
+func WithRetries(n int) CallOption {
+	return func(o *callOptions) {
+		o.maxRetries = n
+	}
+}
+
+func (c Config) callOptions(opts []CallOption) callOptions {
+	o := callOptions{timeout: c.Timeout, maxRetries: c.MaxRetries}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.maxRetries < 0 {
+		o.maxRetries = 0
+	}
+	return o
+}
@@ -0,0 +1,61 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+
+	"github.com/techrail/ground/problem"
+	"github.com/techrail/ground/typs/appError"
+)
+
+// maxBodyInMessage is the maximum number of bytes of an unknown failure body copied into the DevMsg of the error
+const maxBodyInMessage = 512
+
+// failureEnvelope is the failure envelope sent by ground's renderers (and the members of the problem details
+// which are used when the failure was sent as application/problem+json)
+type failureEnvelope struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	DevMsg  string `json:"devMsg"`
+	Title   string `json:"title"`
+	Detail  string `json:"detail"`
+	Level   string `json:"level"`
+}
+
+// DecodeFailure converts a non-2xx response into an error. The code, message and dev message of ground's failure
+// envelope (or of the problem details) sent by the downstream service are kept; any other body ends up in the
+// DevMsg of a generic error. The HttpResponseCode of the error is the status code of the response.
+func DecodeFailure(statusCode int, contentType string, body []byte) appError.Typ {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/json" || mediaType == problem.ContentType {
+		var env failureEnvelope
+		if err := json.Unmarshal(body, &env); err == nil && env.Code != "" {
+			msg := env.Message
+			if msg == "" {
+				msg = env.Detail
+			}
+			if msg == "" {
+				msg = env.Title
+			}
+			return appError.NewNetworkError(statusCode, levelFromName(env.Level), env.Code, msg, env.DevMsg)
+		}
+	}
+
+	devMsg := string(body)
+	if len(devMsg) > maxBodyInMessage {
+		devMsg = devMsg[:maxBodyInMessage] + "..."
+	}
+	return appError.NewNetworkError(statusCode, appError.Error, "3A4AH7",
+		fmt.Sprintf("Downstream service responded with status %v", statusCode), devMsg)
+}
+
+func levelFromName(name string) appError.Level {
+	for _, l := range []appError.Level{appError.Panic, appError.Alert, appError.Error, appError.Warning,
+		appError.Notice, appError.Info, appError.Debug} {
+		if l.String() == name {
+			return l
+		}
+	}
+	return appError.Error
+}
@@ -0,0 +1,98 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/techrail/ground/constants/httpheaders"
+	"github.com/techrail/ground/logger"
+	"github.com/techrail/ground/resilience"
+	"github.com/techrail/ground/typs/appError"
+)
+
+// FastClient returns the fasthttp client used by FastDo. It can be changed (e.g. to set the connection limits)
+// before the first call.
+func (c *Client) FastClient() *fasthttp.Client {
+	c.fastOnce.Do(func() {
+		if c.fast == nil {
+			c.fast = &fasthttp.Client{}
+		}
+	})
+	return c.fast
+}
+
+// FastDo sends the request using fasthttp, with the same retries, circuit breaking and propagation as Do. The
+// context bounds the call as a whole (and is where the request ID and the trace context are taken from; a
+// *fasthttp.RequestCtx can be passed as it is). The error is returned only if no response could be obtained.
+func (c *Client) FastDo(ctx context.Context, rq *fasthttp.Request, resp *fasthttp.Response, opts ...CallOption) appError.Typ {
+	o := c.cfg.callOptions(opts)
+	if c.cfg.PropagateIds {
+		c.propagate(ctx,
+			func(k string) string { return string(rq.Header.Peek(k)) },
+			func(k, v string) { rq.Header.Set(k, v) })
+	}
+	host := string(rq.URI().Host())
+	retryable := idempotent(string(rq.Header.Method()), len(rq.Header.Peek(httpheaders.IdempotencyKey)) > 0)
+	b := c.breakers.ForKey(host)
+	client := c.FastClient()
+
+	for attempt := 0; ; attempt++ {
+		if errTy := b.Allow(); errTy.IsNotBlank() {
+			return errTy
+		}
+
+		deadline := time.Time{}
+		if d, ok := ctx.Deadline(); ok {
+			deadline = d
+		}
+		if o.timeout > 0 && (deadline.IsZero() || time.Now().Add(o.timeout).Before(deadline)) {
+			deadline = time.Now().Add(o.timeout)
+		}
+		var err error
+		if deadline.IsZero() {
+			err = client.Do(rq, resp)
+		} else {
+			err = client.DoDeadline(rq, resp, deadline)
+		}
+		if ctx.Err() != nil {
+			// The caller gave up: the call is neither a success nor a failure of the upstream
+			b.Release()
+		} else {
+			b.Record(err == nil && resp.StatusCode() < 500)
+		}
+
+		if attempt < o.maxRetries && retryable && ctx.Err() == nil &&
+			(err != nil || c.retryableStatus(resp.StatusCode())) {
+			retryAfter := ""
+			if err == nil {
+				retryAfter = string(resp.Header.Peek(fasthttp.HeaderRetryAfter))
+			}
+			wait := c.backoff(attempt, retryAfter)
+			logger.Println(fmt.Sprintf("D#3H8DA5 - Retrying %s %s in %v (attempt %v failed)",
+				rq.Header.Method(), rq.URI().FullURI(), wait, attempt+1))
+			if errTy := resilience.Sleep(ctx, wait); errTy.IsNotBlank() {
+				return errTy
+			}
+			resp.Reset()
+			continue
+		}
+
+		if err != nil {
+			return appError.NewError(appError.Error, "3B8UAM",
+				fmt.Sprintf("%s %s failed: %v", rq.Header.Method(), rq.URI().FullURI(), err))
+		}
+		return appError.BlankError
+	}
+}
+
+// FastDecodeFailure converts a non-2xx fasthttp response into an error (see DecodeFailure). It returns a blank
+// error for a 2xx response.
+func FastDecodeFailure(resp *fasthttp.Response) appError.Typ {
+	if resp.StatusCode() >= 200 && resp.StatusCode() <= 299 {
+		return appError.BlankError
+	}
+	return DecodeFailure(resp.StatusCode(), string(resp.Header.ContentType()), resp.Body())
+}
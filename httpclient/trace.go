@@ -0,0 +1,47 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
+	"github.com/techrail/ground/constants/customCtxKey"
+)
+
+// childTraceParent returns the `traceparent` header to send to the downstream service for the one found in the
+// context: the trace ID and the flags are kept and a new parent (span) ID is generated. It returns a blank string if
+// the context has no valid `traceparent`.
+func childTraceParent(ctx context.Context) string {
+	tp, _ := ctx.Value(customCtxKey.TraceParent).(string)
+	parts := strings.Split(strings.TrimSpace(tp), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || !isLowerHex(parts[0]) ||
+		len(parts[1]) != 32 || !isLowerHex(parts[1]) || strings.Trim(parts[1], "0") == "" ||
+		len(parts[2]) != 16 || !isLowerHex(parts[2]) || len(parts[3]) != 2 || !isLowerHex(parts[3]) {
+		return ""
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return ""
+	}
+
+	spanId := make([]byte, 8)
+	if _, err := rand.Read(spanId); err != nil {
+		return ""
+	}
+	return "00-" + parts[1] + "-" + hex.EncodeToString(spanId) + "-" + parts[3]
+}
+
+// traceState returns the `tracestate` header found in the context
+func traceState(ctx context.Context) string {
+	ts, _ := ctx.Value(customCtxKey.TraceState).(string)
+	return ts
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !(s[i] >= '0' && s[i] <= '9' || s[i] >= 'a' && s[i] <= 'f') {
+			return false
+		}
+	}
+	return true
+}
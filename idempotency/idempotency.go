@@ -0,0 +1,228 @@
+// Package idempotency implements the `Idempotency-Key` semantics used by the idempotency middlewares of both the
+// fasthttp (webServer) and the net/http (netserver) flavours of the servers: the response to the first request
+// carrying a key is stored and replayed for the duplicates, a duplicate arriving while the first request is still
+// being processed waits for it (or gets a 409) and a key reused with a different request gets a 422.
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/techrail/ground/constants/customHeaders"
+	"github.com/techrail/ground/constants/httpheaders"
+	"github.com/techrail/ground/typs/appError"
+	"github.com/techrail/ground/uuid"
+)
+
+// ReplayedHeader is added to the replayed responses
+const ReplayedHeader = "Idempotent-Replayed"
+
+// Record is what is stored against a key
+type Record struct {
+	InProgress  bool                `json:"inProgress,omitempty"`
+	Owner       string              `json:"owner,omitempty"` // Token of the request holding the key (while in progress)
+	Fingerprint string              `json:"fingerprint"`
+	Status      int                 `json:"status,omitempty"`
+	Header      map[string][]string `json:"header,omitempty"`
+	Body        []byte              `json:"body,omitempty"`
+}
+
+// Store keeps the records. The implementations must be safe for concurrent use.
+type Store interface {
+	// Reserve stores the record against the key only if the key does not exist yet. It tells if it did.
+	Reserve(ctx context.Context, key string, rec Record, ttl time.Duration) (bool, appError.Typ)
+	// Get returns the record stored against the key (and whether there is one)
+	Get(ctx context.Context, key string) (Record, bool, appError.Typ)
+	// Save stores the record against the key only if the key is still reserved by the owner (replacing the
+	// reservation). It tells if it did.
+	Save(ctx context.Context, key, owner string, rec Record, ttl time.Duration) (bool, appError.Typ)
+	// Delete removes the key only if it is still reserved by the owner. It tells if it did.
+	Delete(ctx context.Context, key, owner string) (bool, appError.Typ)
+}
+
+// Config of the middleware
+type Config struct {
+	Store        Store
+	HeaderName   string        // Header carrying the key (Idempotency-Key by default)
+	KeyPrefix    string        // Prefix of the keys in the store
+	Methods      []string      // Methods the middleware applies to (POST and PATCH by default)
+	Required     bool          // Reject the requests of these methods which do not carry a key (with a 400)
+	TTL          time.Duration // How long a stored response is replayed
+	LockTTL      time.Duration // How long a request can hold the key while being processed
+	Wait         time.Duration // How long a duplicate waits for the first request to finish (0 means 409 at once)
+	PollInterval time.Duration // How often a waiting duplicate checks the store
+	MaxBodySize  int           // Responses with bigger bodies are not stored
+	MaxKeyLength int           // Longer keys are rejected (with a 400)
+}
+
+// DefaultConfig returns the default configuration using the given store
+func DefaultConfig(store Store) Config {
+	return Config{
+		Store:        store,
+		HeaderName:   httpheaders.IdempotencyKey,
+		KeyPrefix:    "idempotency:",
+		Methods:      []string{http.MethodPost, http.MethodPatch},
+		TTL:          24 * time.Hour,
+		LockTTL:      time.Minute,
+		Wait:         0,
+		PollInterval: 50 * time.Millisecond,
+		MaxBodySize:  1 << 20,
+		MaxKeyLength: 255,
+	}
+}
+
+// Applies tells if the middleware applies to the method
+func (c Config) Applies(method string) bool {
+	return slices.Contains(c.Methods, method)
+}
+
+// StoreKey returns the key used in the store for the key sent by the client. The method and the path are part of it,
+// so that the same key used against different endpoints does not collide.
+func (c Config) StoreKey(method, path, key string) string {
+	return c.KeyPrefix + method + " " + path + " " + key
+}
+
+// Fingerprint identifies the request, so that a key reused with a different request can be detected
+func Fingerprint(method, uri string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + uri + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Outcome of Begin
+type Outcome int
+
+const (
+	Proceed  Outcome = iota // The request holds the key; process it and call Finish (or Abandon)
+	Replay                  // Replay the stored response
+	Conflict                // A request with the same key is still being processed (the error has a 409)
+	Mismatch                // The key was used with a different request (the error has a 422)
+	Failed                  // The store failed (the error has a 503)
+)
+
+// ValidateKey checks the key sent by the client. A blank key is valid only when keys are not required; the caller
+// is expected to skip the middleware in that case.
+func (c Config) ValidateKey(key string) appError.Typ {
+	if key == "" {
+		if c.Required {
+			return appError.NewNetworkError(http.StatusBadRequest, appError.Warning, "3E3WB2",
+				"The "+c.HeaderName+" header is required", "")
+		}
+		return appError.BlankError
+	}
+	if (c.MaxKeyLength > 0 && len(key) > c.MaxKeyLength) || strings.ContainsAny(key, "\r\n") {
+		return appError.NewNetworkError(http.StatusBadRequest, appError.Warning, "3D2FNM",
+			"The "+c.HeaderName+" header has an invalid value", "")
+	}
+	return appError.BlankError
+}
+
+// Begin claims the key for the request with the given fingerprint, waiting for a duplicate in progress if the
+// configuration asks for it. On Proceed, the returned record is the reservation: its Owner must be passed to Finish
+// (or Abandon), so that a request which held the key for longer than LockTTL does not overwrite the reservation of
+// the request which claimed the key after it.
+func (c Config) Begin(ctx context.Context, storeKey, fingerprint string) (Outcome, Record, appError.Typ) {
+	deadline := time.Now().Add(c.Wait)
+	for {
+		reservation := Record{InProgress: true, Owner: uuid.GetNewUlidAsString(), Fingerprint: fingerprint}
+		reserved, errTy := c.Store.Reserve(ctx, storeKey, reservation, c.LockTTL)
+		if errTy.IsNotBlank() {
+			return Failed, Record{}, storeError("3DTEXJ", errTy)
+		}
+		if reserved {
+			return Proceed, reservation, appError.BlankError
+		}
+
+		rec, found, errTy := c.Store.Get(ctx, storeKey)
+		if errTy.IsNotBlank() {
+			return Failed, Record{}, storeError("3FVQHU", errTy)
+		}
+		if !found {
+			// The record expired (or was abandoned) in between. Try to claim it again.
+			continue
+		}
+		if rec.Fingerprint != fingerprint {
+			return Mismatch, Record{}, appError.NewNetworkError(http.StatusUnprocessableEntity, appError.Warning,
+				"3D2SZF", "The "+c.HeaderName+" was already used with a different request", "")
+		}
+		if !rec.InProgress {
+			return Replay, rec, appError.BlankError
+		}
+
+		if c.Wait <= 0 || time.Now().After(deadline) {
+			return Conflict, Record{}, appError.NewNetworkError(http.StatusConflict, appError.Warning, "3FSCER",
+				"A request with the same "+c.HeaderName+" is being processed", "")
+		}
+		select {
+		case <-ctx.Done():
+			return Conflict, Record{}, appError.NewNetworkError(http.StatusConflict, appError.Warning, "3BKSHE",
+				"A request with the same "+c.HeaderName+" is being processed", fmt.Sprintf("%v", ctx.Err()))
+		case <-time.After(max(c.PollInterval, time.Millisecond)):
+		}
+	}
+}
+
+// Finish stores the response of the request holding the key (as the given owner). Server errors (5xx) and responses
+// which can not be stored are not kept: the key is released so that the client can retry. Nothing is stored if the
+// reservation expired and the key was claimed again in the meantime.
+func (c Config) Finish(ctx context.Context, storeKey, owner, fingerprint string, status int, header map[string][]string, body []byte) appError.Typ {
+	if status >= 500 || (c.MaxBodySize > 0 && len(body) > c.MaxBodySize) {
+		return c.Abandon(ctx, storeKey, owner)
+	}
+	rec := Record{Fingerprint: fingerprint, Status: status, Header: map[string][]string{}, Body: body}
+	for name, values := range header {
+		if !skippedHeaders[http.CanonicalHeaderKey(name)] {
+			rec.Header[name] = values
+		}
+	}
+	saved, errTy := c.Store.Save(ctx, storeKey, owner, rec, c.TTL)
+	if errTy.IsNotBlank() {
+		return storeError("3DY2EJ", errTy)
+	}
+	if !saved {
+		return lostReservation("3CFOG9", storeKey)
+	}
+	return appError.BlankError
+}
+
+// Abandon releases the key held by the owner without storing a response. A key claimed again (after the
+// reservation of the owner expired) is left alone.
+func (c Config) Abandon(ctx context.Context, storeKey, owner string) appError.Typ {
+	deleted, errTy := c.Store.Delete(ctx, storeKey, owner)
+	if errTy.IsNotBlank() {
+		return storeError("3CG9TT", errTy)
+	}
+	if !deleted {
+		return lostReservation("3HRODN", storeKey)
+	}
+	return appError.BlankError
+}
+
+// skippedHeaders are not stored (they are either set again by the server or must not be replayed)
+var skippedHeaders = map[string]bool{
+	httpheaders.Connection:       true,
+	httpheaders.ContentLength:    true,
+	httpheaders.Date:             true,
+	httpheaders.TransferEncoding: true,
+	httpheaders.SetCookie:        true,
+	customHeaders.RequestId:      true,
+}
+
+// lostReservation is returned when the reservation of a request expired (it took longer than LockTTL) before it
+// could store or release the key
+func lostReservation(code string, storeKey string) appError.Typ {
+	return appError.NewError(appError.Warning, code,
+		fmt.Sprintf("The reservation of %v expired before the request was over (it took longer than the LockTTL)", storeKey))
+}
+
+func storeError(code string, errTy appError.Typ) appError.Typ {
+	return appError.NewNetworkError(http.StatusServiceUnavailable, appError.Error, code,
+		"Idempotency store is unavailable", errTy.Message, errTy)
+}
@@ -0,0 +1,95 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestReplayConflictAndMismatch(t *testing.T) {
+	cfg := DefaultConfig(NewMemoryStore())
+	ctx := context.Background()
+	key := cfg.StoreKey(http.MethodPost, "/payments", "k1")
+	fp := Fingerprint(http.MethodPost, "/payments", []byte(`{"amount":10}`))
+
+	outcome, reservation, errTy := cfg.Begin(ctx, key, fp)
+	if outcome != Proceed {
+		t.Fatalf("E#3G8SFH - First request got outcome %v (%v); want Proceed", outcome, errTy)
+	}
+	if outcome, _, errTy := cfg.Begin(ctx, key, fp); outcome != Conflict || errTy.HttpResponseCode != http.StatusConflict {
+		t.Errorf("E#3G7Y4Q - Concurrent duplicate got outcome %v (%v); want a 409 Conflict", outcome, errTy)
+	}
+
+	header := map[string][]string{"Content-Type": {"application/json"}, "Date": {"today"}, "X-Request-Id": {"first"}}
+	if errTy := cfg.Finish(ctx, key, reservation.Owner, fp, http.StatusCreated, header, []byte(`{"id":1}`)); errTy.IsNotBlank() {
+		t.Fatalf("E#3FO75O - Finish failed: %v", errTy)
+	}
+	outcome, rec, _ := cfg.Begin(ctx, key, fp)
+	if outcome != Replay || rec.Status != http.StatusCreated || string(rec.Body) != `{"id":1}` ||
+		rec.Header["Date"] != nil || rec.Header["X-Request-Id"] != nil || rec.Header["Content-Type"][0] != "application/json" {
+		t.Errorf("E#3C54XB - Duplicate got outcome %v and record %+v; want the stored response", outcome, rec)
+	}
+
+	otherFp := Fingerprint(http.MethodPost, "/payments", []byte(`{"amount":20}`))
+	if outcome, _, errTy := cfg.Begin(ctx, key, otherFp); outcome != Mismatch || errTy.HttpResponseCode != http.StatusUnprocessableEntity {
+		t.Errorf("E#3A46ZP - Reused key got outcome %v (%v); want a 422 Mismatch", outcome, errTy)
+	}
+}
+
+func TestWaitForInProgressAndRelease(t *testing.T) {
+	cfg := DefaultConfig(NewMemoryStore())
+	cfg.Wait = time.Second
+	cfg.PollInterval = 5 * time.Millisecond
+	ctx := context.Background()
+	key := cfg.StoreKey(http.MethodPost, "/orders", "k2")
+	fp := Fingerprint(http.MethodPost, "/orders", nil)
+
+	_, reservation, _ := cfg.Begin(ctx, key, fp)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = cfg.Finish(ctx, key, reservation.Owner, fp, http.StatusOK, nil, []byte("done"))
+	}()
+	if outcome, rec, errTy := cfg.Begin(ctx, key, fp); outcome != Replay || string(rec.Body) != "done" {
+		t.Errorf("E#3D88NG - Waiting duplicate got outcome %v (%v); want the replay", outcome, errTy)
+	}
+
+	// Server errors release the key
+	key = cfg.StoreKey(http.MethodPost, "/orders", "k3")
+	_, reservation, _ = cfg.Begin(ctx, key, fp)
+	_ = cfg.Finish(ctx, key, reservation.Owner, fp, http.StatusInternalServerError, nil, nil)
+	if outcome, _, _ := cfg.Begin(ctx, key, fp); outcome != Proceed {
+		t.Errorf("E#3HM1XN - Retry after a server error got outcome %v; want Proceed", outcome)
+	}
+}
+
+func TestExpiredReservationIsNotOverwritten(t *testing.T) {
+	cfg := DefaultConfig(NewMemoryStore())
+	cfg.LockTTL = 20 * time.Millisecond
+	ctx := context.Background()
+	key := cfg.StoreKey(http.MethodPost, "/transfers", "k4")
+	fp := Fingerprint(http.MethodPost, "/transfers", nil)
+
+	_, slow, _ := cfg.Begin(ctx, key, fp)
+	// The first request takes longer than the LockTTL, so a retry claims the key
+	time.Sleep(30 * time.Millisecond)
+	outcome, retry, _ := cfg.Begin(ctx, key, fp)
+	if outcome != Proceed {
+		t.Fatalf("E#3A4XJD - Retry after the lock expired got outcome %v; want Proceed", outcome)
+	}
+
+	if errTy := cfg.Finish(ctx, key, slow.Owner, fp, http.StatusOK, nil, []byte("slow")); errTy.IsBlank() {
+		t.Errorf("E#3BD83P - Finish of the expired reservation did not fail")
+	}
+	if errTy := cfg.Abandon(ctx, key, slow.Owner); errTy.IsBlank() {
+		t.Errorf("E#3BN5W4 - Abandon of the expired reservation did not fail")
+	}
+	if outcome, _, _ := cfg.Begin(ctx, key, fp); outcome != Conflict {
+		t.Errorf("E#3G0Q9U - The reservation of the retry was lost (outcome %v; want Conflict)", outcome)
+	}
+
+	_ = cfg.Finish(ctx, key, retry.Owner, fp, http.StatusOK, nil, []byte("retry"))
+	if outcome, rec, _ := cfg.Begin(ctx, key, fp); outcome != Replay || string(rec.Body) != "retry" {
+		t.Errorf("E#3CPQPV - Duplicate got outcome %v and body %s; want the response of the retry", outcome, rec.Body)
+	}
+}
@@ -0,0 +1,241 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/valkey-io/valkey-go"
+
+	"github.com/techrail/ground/cache"
+	"github.com/techrail/ground/typs/appError"
+)
+
+// MemoryStore keeps the records in memory. It is meant for tests and single instance deployments.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]memoryRecord
+}
+
+type memoryRecord struct {
+	rec       Record
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: map[string]memoryRecord{}}
+}
+
+func (s *MemoryStore) Reserve(ctx context.Context, key string, rec Record, ttl time.Duration) (bool, appError.Typ) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, found := s.get(key); found {
+		return false, appError.BlankError
+	}
+	s.records[key] = memoryRecord{rec: rec, expiresAt: time.Now().Add(ttl)}
+	return true, appError.BlankError
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (Record, bool, appError.Typ) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, found := s.get(key)
+	return rec, found, appError.BlankError
+}
+
+func (s *MemoryStore) Save(ctx context.Context, key, owner string, rec Record, ttl time.Duration) (bool, appError.Typ) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current, found := s.get(key); !found || current.Owner != owner {
+		return false, appError.BlankError
+	}
+	s.records[key] = memoryRecord{rec: rec, expiresAt: time.Now().Add(ttl)}
+	return true, appError.BlankError
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key, owner string) (bool, appError.Typ) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current, found := s.get(key); !found || current.Owner != owner {
+		return false, appError.BlankError
+	}
+	delete(s.records, key)
+	return true, appError.BlankError
+}
+
+// get returns the record if it has not expired (removing it otherwise). The lock must be held.
+func (s *MemoryStore) get(key string) (Record, bool) {
+	mr, found := s.records[key]
+	if !found {
+		return Record{}, false
+	}
+	if time.Now().After(mr.expiresAt) {
+		delete(s.records, key)
+		return Record{}, false
+	}
+	return mr.rec, true
+}
+
+// The scripts replace (or delete) the record only if it is still the reservation of the owner (ARGV[1])
+
+const saveScript = `
+local current = redis.call('GET', KEYS[1])
+if current and cjson.decode(current).owner == ARGV[1] then
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+	return 1
+end
+return 0`
+
+const deleteScript = `
+local current = redis.call('GET', KEYS[1])
+if current and cjson.decode(current).owner == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`
+
+// ValkeyStore keeps the records in valkey
+type ValkeyStore struct {
+	cache *cache.ValkeyCache
+}
+
+// NewValkeyStore creates a store using the valkey cache
+func NewValkeyStore(c *cache.ValkeyCache) *ValkeyStore {
+	return &ValkeyStore{cache: c}
+}
+
+func (s *ValkeyStore) Reserve(ctx context.Context, key string, rec Record, ttl time.Duration) (bool, appError.Typ) {
+	b, errTy := encode(rec)
+	if errTy.IsNotBlank() {
+		return false, errTy
+	}
+	client := s.cache.Underlying()
+	err := client.Do(ctx, client.B().Set().Key(key).Value(string(b)).Nx().Px(ttl).Build()).Error()
+	if valkey.IsValkeyNil(err) {
+		// NX was not satisfied
+		return false, appError.BlankError
+	}
+	if err != nil {
+		return false, appError.NewError(appError.Error, "3DOISK", fmt.Sprintf("Could not reserve %v: %v", key, err))
+	}
+	return true, appError.BlankError
+}
+
+func (s *ValkeyStore) Get(ctx context.Context, key string) (Record, bool, appError.Typ) {
+	val, err := s.cache.Get(ctx, key)
+	if errors.Is(err, cache.ErrNotFound) {
+		return Record{}, false, appError.BlankError
+	}
+	if err != nil {
+		return Record{}, false, appError.NewError(appError.Error, "3FLD56", fmt.Sprintf("Could not get %v: %v", key, err))
+	}
+	return decode(val)
+}
+
+func (s *ValkeyStore) Save(ctx context.Context, key, owner string, rec Record, ttl time.Duration) (bool, appError.Typ) {
+	b, errTy := encode(rec)
+	if errTy.IsNotBlank() {
+		return false, errTy
+	}
+	reply, err := s.cache.Eval(ctx, valkeySaveScript, []string{key}, []string{owner, string(b), millis(ttl)})
+	if err != nil {
+		return false, appError.NewError(appError.Error, "3DCOIL", fmt.Sprintf("Could not save %v: %v", key, err))
+	}
+	return reply == int64(1), appError.BlankError
+}
+
+func (s *ValkeyStore) Delete(ctx context.Context, key, owner string) (bool, appError.Typ) {
+	reply, err := s.cache.Eval(ctx, valkeyDeleteScript, []string{key}, []string{owner})
+	if err != nil {
+		return false, appError.NewError(appError.Error, "3DBMBI", fmt.Sprintf("Could not delete %v: %v", key, err))
+	}
+	return reply == int64(1), appError.BlankError
+}
+
+var (
+	valkeySaveScript   = cache.NewScript(saveScript)
+	valkeyDeleteScript = cache.NewScript(deleteScript)
+)
+
+// RedisStore keeps the records in redis
+type RedisStore struct {
+	client *cache.Client
+}
+
+// NewRedisStore creates a store using the redis client
+func NewRedisStore(c *cache.Client) *RedisStore {
+	return &RedisStore{client: c}
+}
+
+func (s *RedisStore) Reserve(ctx context.Context, key string, rec Record, ttl time.Duration) (bool, appError.Typ) {
+	b, errTy := encode(rec)
+	if errTy.IsNotBlank() {
+		return false, errTy
+	}
+	reserved, errTy := s.client.SetNX(ctx, key, b, ttl)
+	if errTy.IsNotBlank() {
+		return false, appError.NewError(appError.Error, "3AHH8H", fmt.Sprintf("Could not reserve %v", key), errTy)
+	}
+	return reserved, appError.BlankError
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) (Record, bool, appError.Typ) {
+	val, found, errTy := s.client.Get(ctx, key)
+	if errTy.IsNotBlank() {
+		return Record{}, false, appError.NewError(appError.Error, "3FGOC4", fmt.Sprintf("Could not get %v", key), errTy)
+	}
+	if !found {
+		return Record{}, false, appError.BlankError
+	}
+	return decode(val)
+}
+
+func (s *RedisStore) Save(ctx context.Context, key, owner string, rec Record, ttl time.Duration) (bool, appError.Typ) {
+	b, errTy := encode(rec)
+	if errTy.IsNotBlank() {
+		return false, errTy
+	}
+	n, err := redisSaveScript.Run(ctx, s.client.Connection, []string{s.client.Key(key)}, owner, string(b), millis(ttl)).Int64()
+	if err != nil {
+		return false, appError.NewError(appError.Error, "3DCFWA", fmt.Sprintf("Could not save %v: %v", key, err))
+	}
+	return n == 1, appError.BlankError
+}
+
+func (s *RedisStore) Delete(ctx context.Context, key, owner string) (bool, appError.Typ) {
+	n, err := redisDeleteScript.Run(ctx, s.client.Connection, []string{s.client.Key(key)}, owner).Int64()
+	if err != nil {
+		return false, appError.NewError(appError.Error, "3C77RA", fmt.Sprintf("Could not delete %v: %v", key, err))
+	}
+	return n == 1, appError.BlankError
+}
+
+var (
+	redisSaveScript   = goredis.NewScript(saveScript)
+	redisDeleteScript = goredis.NewScript(deleteScript)
+)
+
+func millis(ttl time.Duration) string {
+	return strconv.FormatInt(max(ttl.Milliseconds(), 1), 10)
+}
+
+func encode(rec Record) ([]byte, appError.Typ) {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return nil, appError.NewError(appError.Error, "3AA5DZ", fmt.Sprintf("Could not encode the record: %v", err))
+	}
+	return b, appError.BlankError
+}
+
+func decode(val string) (Record, bool, appError.Typ) {
+	var rec Record
+	if err := json.Unmarshal([]byte(val), &rec); err != nil {
+		return Record{}, false, appError.NewError(appError.Error, "3B5UKX", fmt.Sprintf("Could not decode the record: %v", err))
+	}
+	return rec, true, appError.BlankError
+}
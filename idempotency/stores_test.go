@@ -0,0 +1,42 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/techrail/ground/cache"
+)
+
+func TestRedisStoreSavesOnlyForTheOwner(t *testing.T) {
+	srv := miniredis.RunT(t)
+	client, errTy := cache.CreateNewRedisClient(cache.RedisConfig{
+		Enabled: true, Url: "redis://" + srv.Addr(), OperationMode: cache.ModeStandalone, Requirement: cache.RequirementHardcore,
+		AppNamespace: "test",
+	})
+	if errTy.IsNotBlank() {
+		t.Fatalf("E#3EXWPJ - Could not connect to the test server: %v", errTy)
+	}
+	defer client.Close()
+	store := NewRedisStore(client)
+	ctx := context.Background()
+
+	_, _ = store.Reserve(ctx, "k", Record{InProgress: true, Owner: "first", Fingerprint: "fp"}, time.Minute)
+	if saved, errTy := store.Save(ctx, "k", "second", Record{Fingerprint: "fp", Status: 200}, time.Minute); saved || errTy.IsNotBlank() {
+		t.Errorf("E#3CJ1OV - Save by another owner returned %v (%v); want false", saved, errTy)
+	}
+	if deleted, errTy := store.Delete(ctx, "k", "second"); deleted || errTy.IsNotBlank() {
+		t.Errorf("E#3DE1ZL - Delete by another owner returned %v (%v); want false", deleted, errTy)
+	}
+	if saved, errTy := store.Save(ctx, "k", "first", Record{Fingerprint: "fp", Status: 200}, time.Minute); !saved || errTy.IsNotBlank() {
+		t.Fatalf("E#3GLJ0E - Save by the owner returned %v (%v); want true", saved, errTy)
+	}
+	if rec, found, _ := store.Get(ctx, "k"); !found || rec.InProgress || rec.Status != 200 {
+		t.Errorf("E#3D1LEL - Got %+v after the save; want the response", rec)
+	}
+	if ttl := srv.TTL("test:k"); ttl <= 0 {
+		t.Errorf("E#3BASMO - The saved record has no expiry (ttl %v)", ttl)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/techrail/ground/bgroutine"
 	"github.com/techrail/ground/cache"
 	"github.com/techrail/ground/dbcodegen"
+	"github.com/techrail/ground/httpclient"
 	"github.com/techrail/ground/logger"
 	"github.com/techrail/ground/netserver"
 	"github.com/techrail/ground/typs/appError"
@@ -24,7 +25,11 @@ func GiveMeANetHttpServer(port uint16, blockOnStart bool) *netserver.NetHttpServ
 	return netserver.NewServer(port, blockOnStart)
 }
 
-func GiveMeARoutineManager() bgroutine.Manager {
+func GiveMeAnHttpClient(cfg ...httpclient.Config) *httpclient.Client {
+	return httpclient.New(cfg...)
+}
+
+func GiveMeARoutineManager() *bgroutine.Manager {
 	return bgroutine.NewManager()
 }
 
@@ -50,6 +55,6 @@ func GiveMeABlankJsonObject() jsonObject.Typ {
 	return jsonObject.EmptyNotNullJsonObject()
 }
 
-func GiveMeACacheManager(config cache.RedisConfig) *cache.Client {
+func GiveMeACacheManager(config cache.RedisConfig) (*cache.Client, appError.Typ) {
 	return cache.CreateNewRedisClient(config)
 }
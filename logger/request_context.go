@@ -0,0 +1,26 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/techrail/ground/constants/customCtxKey"
+)
+
+// PrintlnWithContext works like Println, but tags the message with the request ID found in the context (if any).
+// It works with the context of a net/http request as well as with a *fasthttp.RequestCtx.
+func PrintlnWithContext(ctx context.Context, msg string) {
+	Println(WithRequestId(ctx, msg))
+}
+
+// WithRequestId appends the request ID found in the context (if any) to the message. The ID is appended (and not
+// prepended) so that the level and code prefix of the message (e.g. `E#1ABCDE - `) keeps working.
+func WithRequestId(ctx context.Context, msg string) string {
+	if ctx == nil {
+		return msg
+	}
+	requestId, _ := ctx.Value(customCtxKey.RequestId).(string)
+	if requestId == "" {
+		return msg
+	}
+	return msg + " [RequestId: " + requestId + "]"
+}
@@ -81,6 +81,8 @@ func Default(msg string) {
 	state.Client.Default(msg)
 }
 
+// LogWithContext logs the message (tagged with the request ID) and adds it to the operational log of the request
+// if the operational log was requested
 func LogWithContext(ctx *fasthttp.RequestCtx, msg string) {
 	var opLog []string
 	respondWithOplog := ctx.UserValue(customCtxKey.OpLogRequested)
@@ -115,5 +117,5 @@ func LogWithContext(ctx *fasthttp.RequestCtx, msg string) {
 
 	// We might have to think another, better method to call here
 
-	Println(msg)
+	Println(WithRequestId(ctx, msg))
 }
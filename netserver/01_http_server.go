@@ -28,6 +28,12 @@ func NewServer(port uint16, blockOnStart bool) *NetHttpServer {
 	}
 }
 
+// Middleware returns the middlewares of the server. Unlike the package level Middleware, they render their failures
+// with the renderer of the server (e.g. as problem details when Render.ProblemDetailsFailures is set).
+func (s *NetHttpServer) Middleware() *middleware {
+	return &middleware{server: s}
+}
+
 func (s *NetHttpServer) PortString() string {
 	return fmt.Sprintf("%d", s.port)
 }
@@ -4,24 +4,39 @@ package netserver
 
 import (
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
 
 	"github.com/techrail/ground/constants/customCtxKey"
 	"github.com/techrail/ground/constants/customHeaders"
 	"github.com/techrail/ground/constants/httpheaders"
 	"github.com/techrail/ground/logger"
+	"github.com/techrail/ground/respcache"
+	"github.com/techrail/ground/respheaders"
 	"github.com/techrail/ground/typs"
 	"github.com/techrail/ground/typs/appError"
 )
 
-type Renderer struct{}
+type Renderer struct {
+	// ProblemDetailsFailures makes the failure renderers respond with RFC 7807 problem details for every request
+	// (clients can still ask for them on their own using the `Accept` header)
+	ProblemDetailsFailures bool
+}
 
 func (r *Renderer) JsonWithFailureUsingErrorType(w http.ResponseWriter, rq *http.Request, errTy appError.Typ) {
 	errId := typs.GetRandomAlphaString(32)
 	if errTy.IsBlankNetworkError() {
 		logger.Println(fmt.Sprintf("E#2R0L3T: ErrID: %v, Error: %v @@@@@ DevMsg: %v", errId, errTy, errTy.DevMsg))
 		r.JsonWithFailure(w, rq, 500, "2R0L3T", "Internal error. Error logged with ID "+errId, errTy.DevMsg)
+		return
+	}
+	if r.problemDetailsWanted(rq) {
+		r.ProblemWithFailure(w, rq, errTy)
+		return
 	}
 	r.JsonWithFailure(w, rq, errTy.HttpResponseCode, errTy.Code, errTy.Message, errTy.DevMsg)
 }
@@ -35,6 +50,11 @@ func (r *Renderer) GetReqCtxValueAsString(rq *http.Request, key string) string {
 }
 
 func (r *Renderer) JsonWithFailure(w http.ResponseWriter, rq *http.Request, httpCode int, errorCode string, errorMessage string, devMessage string) {
+	if r.problemDetailsWanted(rq) {
+		r.ProblemWithFailure(w, rq, appError.NewNetworkError(httpCode, appError.Error, errorCode, errorMessage, devMessage))
+		return
+	}
+
 	addFixedHeaders(w)
 	w.Header().Set(httpheaders.ContentType, "application/json; charset=utf-8")
 	w.Header().Set(customHeaders.RequestId, r.GetReqCtxValueAsString(rq, customCtxKey.RequestId))
@@ -45,26 +65,10 @@ func (r *Renderer) JsonWithFailure(w http.ResponseWriter, rq *http.Request, http
 	}
 
 	// MARKER: Capturing stack trace
-	// REWRITE THIS PART AND ENABLE THE STACK TRACE
+	stackTraceStrLines := stackTraceLines(rq)
+
+	// REWRITE THIS PART AND ENABLE THE OPERATIONAL LOG AND THE DEV MESSAGE
 	// ========
-	// var stackTrace []byte
-	// stackTrace = nil
-	// captureStackTrace := r.GetReqCtxValueAsString(rq, (customCtxKey.StackTraceRequested)
-	// if captureStackTrace != nil {
-	// 	// Option was set in the context. Try to check its value
-	// 	valBool, ok := captureStackTrace.(bool)
-	// 	if ok && valBool {
-	// 		// Stacktrace was requested
-	// 		stackTrace = debug.Stack()
-	// 	}
-	// }
-	// stackTraceStr := string(stackTrace)
-	// stackTraceStr = strings.ReplaceAll(stackTraceStr, "\t", "    ")
-	// stackTraceStrLines := strings.Split(stackTraceStr, "\n")
-	// if len(stackTrace) == 0 {
-	// 	// To ensure that if a blank stack trace is sent by the runtime, it is discarded
-	// 	stackTraceStrLines = nil
-	// }
 	// // MARKER: Capturing operational log
 	// var opLog []string
 	// respondWithOplog := ctx.UserValue(customCtxKey.OpLogRequested)
@@ -115,15 +119,14 @@ func (r *Renderer) JsonWithFailure(w http.ResponseWriter, rq *http.Request, http
 	// 	fmt.Sprintf("%v - %v [::DevMsg::]-> %v", errorCode, errorMessage, devMsg))
 	//
 	devMsg := ""
-	stackTraceStrLines := []string{}
 	opLog := []string{}
 
 	resp := jsonResponseFailure{
 		Code:           errorCode,
 		Message:        errorMessage,
-		DevMsg:         devMsg,             // NOTE: Fix this
-		StackTrace:     stackTraceStrLines, // NOTE: Fix this
-		OperationalLog: opLog,              // NOTE: Fix this
+		DevMsg:         devMsg, // NOTE: Fix this
+		StackTrace:     stackTraceStrLines,
+		OperationalLog: opLog, // NOTE: Fix this
 
 	}.String()
 	//
@@ -279,15 +282,28 @@ func (r *Renderer) JsonStructWithSuccess(w http.ResponseWriter, rq *http.Request
 		return
 	}
 
+	// Successful responses get a strong ETag, and the conditional requests matching it get a 304 without a body
+	if httpCode >= 200 && httpCode <= 299 {
+		etag := respcache.StrongETag(jsonBytes)
+		w.Header().Set(httpheaders.ETag, etag)
+		if (rq.Method == http.MethodGet || rq.Method == http.MethodHead) &&
+			respcache.NotModified(rq.Header.Get(httpheaders.IfNoneMatch), "", etag, time.Time{}) {
+			w.Header().Del(httpheaders.ContentType)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
 	r.JsonBytesWithSuccess(w, rq, httpCode, jsonBytes)
 }
 
 // ==== The types that are reused in the responses ====
 
 type jsonResponseSuccess struct {
-	OperationalLog []string `json:"operationalLog,omitempty"`
-	StackTrace     []string `json:"stackTrace,omitempty"`
-	Data           any      `json:"data"`
+	XMLName        xml.Name `json:"-" xml:"response"`
+	OperationalLog []string `json:"operationalLog,omitempty" xml:"operationalLog>line,omitempty"`
+	StackTrace     []string `json:"stackTrace,omitempty" xml:"stackTrace>line,omitempty"`
+	Data           any      `json:"data" xml:"data"`
 }
 
 // String just gets the json representation or a error string
@@ -305,11 +321,12 @@ func (e jsonResponseSuccess) String() string {
 
 // ==============================================================
 type jsonResponseFailure struct {
-	Code           string   `json:"code"`
-	Message        string   `json:"message"`
-	DevMsg         string   `json:"devMsg,omitempty"`
-	StackTrace     []string `json:"stackTrace,omitempty"`
-	OperationalLog []string `json:"operationalLog,omitempty"`
+	XMLName        xml.Name `json:"-" xml:"error"`
+	Code           string   `json:"code" xml:"code"`
+	Message        string   `json:"message" xml:"message"`
+	DevMsg         string   `json:"devMsg,omitempty" xml:"devMsg,omitempty"`
+	StackTrace     []string `json:"stackTrace,omitempty" xml:"stackTrace>line,omitempty"`
+	OperationalLog []string `json:"operationalLog,omitempty" xml:"operationalLog>line,omitempty"`
 }
 
 // String just gets the json representation or a error string
@@ -331,12 +348,65 @@ func (e jsonResponseFailure) String() string {
 // Useful when just a single `200 OK` or `201 CREATED` would be ok but you still want to send a message to the client
 // about what happened. e.g. "The blog post was created" or "The upload was successful" etc.
 type SingleMessageResponse struct {
-	Message string `json:"message"`
+	Message string `json:"message" xml:"message"`
+}
+
+// stackTraceLines returns the current stack trace (one line per element) if it was requested in the request
+// context (see customCtxKey.StackTraceRequested). It returns nil otherwise.
+func stackTraceLines(rq *http.Request) []string {
+	if !GetValueFromCtxByStringKey(rq.Context(), customCtxKey.StackTraceRequested, false) {
+		return nil
+	}
+	stackTrace := debug.Stack()
+	if len(stackTrace) == 0 {
+		// To ensure that if a blank stack trace is sent by the runtime, it is discarded
+		return nil
+	}
+	stackTraceStr := strings.ReplaceAll(string(stackTrace), "\t", "    ")
+	return strings.Split(stackTraceStr, "\n")
 }
 
+// operationalLog returns the operational log of the request if it was requested in the request context (see
+// customCtxKey.OpLogRequested). It returns nil otherwise.
+func operationalLog(rq *http.Request) []string {
+	requested := rq.Context().Value(customCtxKey.OpLogRequested)
+	if requested == nil {
+		return nil
+	}
+	if valBool, ok := requested.(bool); !ok {
+		return []string{"E#1MZH45 - Value against user key was not in expected data type"}
+	} else if !valBool {
+		return nil
+	}
+	res := rq.Context().Value(customCtxKey.CtxOperationLogContent)
+	if res == nil {
+		errMsg := "E#1MZFU2 - Unexpected nil value found"
+		logger.Println(errMsg)
+		return []string{errMsg}
+	}
+	opLog, ok := res.([]string)
+	if !ok {
+		errMsg := "E#1MZFUD - Incorrect data format"
+		logger.Println(errMsg)
+		return []string{errMsg}
+	}
+	return opLog
+}
+
+// allowedDevMessage returns the devMessage if the client is allowed to see it (see
+// customCtxKey.DevMsgAllowedInFailure), blank otherwise
+func allowedDevMessage(rq *http.Request, devMessage string) string {
+	if !GetValueFromCtxByStringKey(rq.Context(), customCtxKey.DevMsgAllowedInFailure, false) {
+		return ""
+	}
+	return devMessage
+}
+
+// addFixedHeaders adds the server identity headers decided by the header policy (which can also be none at all)
 func addFixedHeaders(w http.ResponseWriter) {
-	w.Header().Add("Server", "Apache 2.4")
-	w.Header().Add("X-Powered-By", "PHP/7.2.12")
+	for _, h := range respheaders.Current().Identity {
+		w.Header().Set(h.Name, h.Value)
+	}
 }
 
 // File ends here
@@ -0,0 +1,53 @@
+package netserver
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/fasthttp/websocket"
+
+	"github.com/techrail/ground/constants/customCtxKey"
+	"github.com/techrail/ground/core"
+	"github.com/techrail/ground/logger"
+	"github.com/techrail/ground/wshub"
+)
+
+// WebSocketHandler returns a handler which upgrades the request to a websocket connection and hands it over to
+// the hub. Register it like any other handler, e.g.
+//
+//	s.Router.Handle("GET /ws", s.WebSocketHandler(hub))
+func (s *NetHttpServer) WebSocketHandler(hub *wshub.Hub) http.Handler {
+	r := s.Render
+	if r == nil {
+		r = &Renderer{}
+	}
+
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  hub.Config.ReadBufferSize,
+		WriteBufferSize: hub.Config.WriteBufferSize,
+		CheckOrigin: func(rq *http.Request) bool {
+			return hub.OriginAllowed(rq.Header.Get("Origin"), rq.Host)
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
+		if hub.IsClosed() || core.State().WebServerShutdownRequested.Load() {
+			r.JsonWithFailure(w, rq, http.StatusServiceUnavailable, "3D4H0V",
+				"Server is in process of shutting down", "")
+			return
+		}
+
+		ws, err := upgrader.Upgrade(w, rq, nil)
+		if err != nil {
+			// The upgrader has already replied to the client with an error
+			logger.Debug(fmt.Sprintf("D#3BWPOI - Websocket upgrade failed: %v", err))
+			return
+		}
+
+		if e := hub.Serve(ws, r.GetReqCtxValueAsString(rq, customCtxKey.RequestId)); e.IsNotBlank() {
+			logger.Warn(fmt.Sprintf("W#3CYPYO - Websocket connection could not be served: %v", e))
+		}
+	})
+}
+
+// File ends here
@@ -0,0 +1,66 @@
+package netserver
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/techrail/ground/constants/customCtxKey"
+	"github.com/techrail/ground/constants/customHeaders"
+	"github.com/techrail/ground/constants/httpheaders"
+	"github.com/techrail/ground/logger"
+	"github.com/techrail/ground/sse"
+	"github.com/techrail/ground/typs/appError"
+)
+
+// EventStream responds with a Server-Sent Events stream. The function fn is called once the response headers have
+// been sent and the events it sends are flushed to the client right away. The stream is closed when fn returns or
+// when the client goes away (stream.Done() is closed in that case).
+func (r *Renderer) EventStream(w http.ResponseWriter, rq *http.Request, opts sse.Options, fn func(stream *sse.Stream) appError.Typ) {
+	// Check if the writer supports flushing before we commit to the stream
+	if !canFlush(w) {
+		logger.Error("E#3A2VVE - Response writer does not support flushing")
+		r.JsonWithFailure(w, rq, http.StatusInternalServerError, "3A2VVE", "Streaming is not supported",
+			"Response writer does not support flushing")
+		return
+	}
+
+	addFixedHeaders(w)
+	w.Header().Set(httpheaders.ContentType, sse.ContentType)
+	w.Header().Set(httpheaders.CacheControl, "no-cache")
+	w.Header().Set(httpheaders.Connection, "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	reqId := r.GetReqCtxValueAsString(rq, customCtxKey.RequestId)
+	if reqId != "" {
+		w.Header().Set(customHeaders.RequestId, reqId)
+	}
+	w.WriteHeader(http.StatusOK)
+
+	stream := sse.NewStream(w, http.NewResponseController(w).Flush, rq.Header.Get(httpheaders.LastEventID))
+	go func() {
+		select {
+		case <-rq.Context().Done():
+			stream.Close()
+		case <-stream.Done():
+		}
+	}()
+
+	if e := stream.Run(opts, fn); e.IsNotBlank() {
+		logger.Debug(fmt.Sprintf("D#3EOX6Q - Event stream ended with error: %v", e))
+	}
+}
+
+// canFlush tells if the response writer (or any of the writers it wraps) can flush the data to the client
+func canFlush(w http.ResponseWriter) bool {
+	for {
+		if _, ok := w.(http.Flusher); ok {
+			return true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return false
+		}
+		w = u.Unwrap()
+	}
+}
+
+// File ends here
@@ -0,0 +1,116 @@
+package netserver
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/techrail/ground/compression"
+	"github.com/techrail/ground/constants/customCtxKey"
+	"github.com/techrail/ground/constants/customHeaders"
+	"github.com/techrail/ground/constants/httpheaders"
+	"github.com/techrail/ground/contentcodec"
+	"github.com/techrail/ground/logger"
+	"github.com/techrail/ground/typs"
+	"github.com/techrail/ground/typs/appError"
+)
+
+// StructWithSuccess works like JsonStructWithSuccess, but encodes the response in the format asked for in the
+// `Accept` header of the request (JSON, MessagePack, CBOR, XML or protobuf). The `{"data": ...}` envelope is kept
+// in every format except protobuf, where a proto.Message payload is sent as it is.
+func (r *Renderer) StructWithSuccess(w http.ResponseWriter, rq *http.Request, httpCode int, structToMarshal any) {
+	addVaryAccept(w)
+	codec := contentcodec.Negotiate(rq.Header.Get(httpheaders.Accept), structToMarshal)
+	if codec == contentcodec.Json {
+		r.JsonStructWithSuccess(w, rq, httpCode, structToMarshal)
+		return
+	}
+
+	// Log for human error
+	if httpCode < 200 || httpCode > 299 {
+		logger.Println(fmt.Sprintf("E#3E5MIK: Success Renderer Called with non-success HTTP code: %v", httpCode))
+	}
+
+	var payload any = structToMarshal
+	if codec != contentcodec.Protobuf {
+		payload = jsonResponseSuccess{
+			OperationalLog: []string{},
+			StackTrace:     []string{},
+			Data:           structToMarshal,
+		}
+	}
+
+	body, err := codec.Marshal(payload)
+	if err != nil {
+		errMsg := fmt.Sprintf("E#3BBS4N: %v marshalling failed: %v", codec.Name(), err)
+		logger.Println(errMsg)
+		r.WithFailure(w, rq, http.StatusInternalServerError, "3BBS4N", "Response encoding failed", errMsg)
+		return
+	}
+
+	r.writeEncoded(w, rq, codec, httpCode, body)
+}
+
+// WithFailure works like JsonWithFailure, but encodes the failure envelope in the format asked for in the `Accept`
+// header of the request
+func (r *Renderer) WithFailure(w http.ResponseWriter, rq *http.Request, httpCode int, errorCode string, errorMessage string, devMessage string) {
+	addVaryAccept(w)
+	codec := contentcodec.NegotiateForFailure(rq.Header.Get(httpheaders.Accept))
+	if codec == contentcodec.Json {
+		r.JsonWithFailure(w, rq, httpCode, errorCode, errorMessage, devMessage)
+		return
+	}
+
+	if httpCode > 199 && httpCode < 300 {
+		logger.Println(fmt.Sprintf("E#3HKBHY: Failure Renderer Called with non-failure HTTP code: %v", httpCode))
+	}
+
+	body, err := codec.Marshal(jsonResponseFailure{
+		Code:           errorCode,
+		Message:        errorMessage,
+		DevMsg:         "",
+		StackTrace:     stackTraceLines(rq),
+		OperationalLog: []string{},
+	})
+	if err != nil {
+		// The failure envelope is made of strings only. This should never happen, but if it does, fall back to JSON
+		logger.Println(fmt.Sprintf("E#3C0MC7: %v marshalling of the failure failed: %v", codec.Name(), err))
+		r.JsonWithFailure(w, rq, httpCode, errorCode, errorMessage, devMessage)
+		return
+	}
+
+	r.writeEncoded(w, rq, codec, httpCode, body)
+}
+
+// WithFailureUsingErrorType works like JsonWithFailureUsingErrorType, but encodes the failure envelope in the
+// format asked for in the `Accept` header of the request
+func (r *Renderer) WithFailureUsingErrorType(w http.ResponseWriter, rq *http.Request, errTy appError.Typ) {
+	if errTy.IsBlankNetworkError() {
+		errId := typs.GetRandomAlphaString(32)
+		logger.Println(fmt.Sprintf("E#3C69FB: ErrID: %v, Error: %v @@@@@ DevMsg: %v", errId, errTy, errTy.DevMsg))
+		r.WithFailure(w, rq, 500, "3C69FB", "Internal error. Error logged with ID "+errId, errTy.DevMsg)
+		return
+	}
+	if r.problemDetailsWanted(rq) {
+		r.ProblemWithFailure(w, rq, errTy)
+		return
+	}
+	r.WithFailure(w, rq, errTy.HttpResponseCode, errTy.Code, errTy.Message, errTy.DevMsg)
+}
+
+func (r *Renderer) writeEncoded(w http.ResponseWriter, rq *http.Request, codec contentcodec.Codec, httpCode int, body []byte) {
+	addFixedHeaders(w)
+	w.Header().Set(httpheaders.ContentType, codec.ContentType())
+	reqId := r.GetReqCtxValueAsString(rq, customCtxKey.RequestId)
+	if reqId != "" {
+		w.Header().Set(customHeaders.RequestId, reqId)
+	}
+	w.WriteHeader(httpCode)
+	_, _ = w.Write(body)
+}
+
+// addVaryAccept tells the caches that the response depends on the Accept header of the request
+func addVaryAccept(w http.ResponseWriter) {
+	w.Header().Set(httpheaders.Vary, compression.AddVary(w.Header().Get(httpheaders.Vary), httpheaders.Accept))
+}
+
+// File ends here
@@ -0,0 +1,48 @@
+package netserver
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/techrail/ground/constants/customCtxKey"
+	"github.com/techrail/ground/constants/customHeaders"
+	"github.com/techrail/ground/constants/httpheaders"
+	"github.com/techrail/ground/logger"
+	"github.com/techrail/ground/problem"
+	"github.com/techrail/ground/typs/appError"
+)
+
+// ProblemWithFailure responds with the error as RFC 7807 problem details (application/problem+json)
+func (r *Renderer) ProblemWithFailure(w http.ResponseWriter, rq *http.Request, errTy appError.Typ) {
+	addFixedHeaders(w)
+	w.Header().Set(httpheaders.ContentType, problem.ContentType)
+	reqId := r.GetReqCtxValueAsString(rq, customCtxKey.RequestId)
+	if reqId != "" {
+		w.Header().Set(customHeaders.RequestId, reqId)
+	}
+
+	devMsg := allowedDevMessage(rq, errTy.DevMsg)
+	details := problem.FromAppError(errTy, problem.Options{
+		Instance:       rq.URL.RequestURI(),
+		RequestId:      reqId,
+		IncludeDevMsg:  devMsg != "",
+		IncludeWrapped: devMsg != "",
+		StackTrace:     stackTraceLines(rq),
+	})
+	if ops := operationalLog(rq); len(ops) > 0 {
+		details.Extensions["operationalLog"] = ops
+	}
+
+	logger.Println(fmt.Sprintf("%v - %v [::DevMsg::]-> %v", errTy.Code, errTy.Message, devMsg))
+
+	w.WriteHeader(details.Status)
+	_, _ = w.Write(details.Bytes())
+}
+
+// problemDetailsWanted tells if the failure should be rendered as problem details. It is the case when the renderer
+// prefers problem details or the client asked for them using the `Accept` header.
+func (r *Renderer) problemDetailsWanted(rq *http.Request) bool {
+	return r.ProblemDetailsFailures || problem.Accepted(rq.Header.Get(httpheaders.Accept))
+}
+
+// File ends here
@@ -0,0 +1,160 @@
+package netserver
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/techrail/ground/compression"
+	"github.com/techrail/ground/constants/httpheaders"
+	"github.com/techrail/ground/logger"
+)
+
+// CompressResponse compresses the response body using the encoding negotiated with the client (zstd, br, gzip or
+// deflate) and the default compression configuration.
+func (m *middleware) CompressResponse(next http.Handler) http.Handler {
+	return m.CompressResponseWithConfig(compression.DefaultConfig())(next)
+}
+
+// CompressResponseWithConfig returns a middleware which compresses the responses according to the given config.
+// The response is buffered until the handler finishes. If the handler flushes the response (e.g. an event stream)
+// or hijacks the connection (e.g. a websocket), the response is sent as it is.
+func (m *middleware) CompressResponseWithConfig(cfg compression.Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
+			if rq.Method == http.MethodHead || rq.Header.Get(httpheaders.Upgrade) != "" {
+				next.ServeHTTP(w, rq)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				cfg:            cfg,
+				acceptEncoding: rq.Header.Get(httpheaders.AcceptEncoding),
+			}
+			next.ServeHTTP(cw, rq)
+			cw.finish()
+		})
+	}
+}
+
+// compressWriter buffers the response so that it can be compressed once the handler is done with it
+type compressWriter struct {
+	http.ResponseWriter
+	cfg            compression.Config
+	acceptEncoding string
+	buf            bytes.Buffer
+	status         int
+	passThrough    bool // Set when the response must go to the client as it is (streams, hijacked connections)
+	headerWritten  bool
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.headerWritten || cw.status != 0 {
+		return
+	}
+	cw.status = status
+	if compression.IsEventStream(cw.Header().Get(httpheaders.ContentType)) {
+		cw.startPassThrough()
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.passThrough {
+		return cw.ResponseWriter.Write(b)
+	}
+	return cw.buf.Write(b)
+}
+
+// Flush sends everything written so far to the client and switches to the pass through mode
+func (cw *compressWriter) Flush() {
+	if !cw.passThrough {
+		cw.startPassThrough()
+	}
+	_ = http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Hijack lets the handlers take over the connection
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	cw.passThrough = true
+	cw.headerWritten = true
+	return http.NewResponseController(cw.ResponseWriter).Hijack()
+}
+
+// Unwrap allows http.ResponseController to reach the original writer
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func (cw *compressWriter) startPassThrough() {
+	cw.passThrough = true
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	cw.writeHeaderOnce()
+	if cw.buf.Len() > 0 {
+		_, _ = cw.ResponseWriter.Write(cw.buf.Bytes())
+		cw.buf.Reset()
+	}
+}
+
+func (cw *compressWriter) writeHeaderOnce() {
+	if !cw.headerWritten {
+		cw.headerWritten = true
+		cw.ResponseWriter.WriteHeader(cw.status)
+	}
+}
+
+// finish compresses (if needed) and writes the buffered response
+func (cw *compressWriter) finish() {
+	if cw.passThrough {
+		return
+	}
+	if cw.status == 0 {
+		if cw.buf.Len() == 0 {
+			// Nothing was written by the handler
+			return
+		}
+		cw.status = http.StatusOK
+	}
+
+	body := cw.buf.Bytes()
+	h := cw.Header()
+	compressible := cw.status >= 200 && cw.status != http.StatusNoContent && cw.status != http.StatusNotModified &&
+		cw.cfg.ContentTypeAllowed(h.Get(httpheaders.ContentType))
+
+	if compressible {
+		h.Set(httpheaders.Vary, compression.AddVary(h.Get(httpheaders.Vary), httpheaders.AcceptEncoding))
+
+		if h.Get(httpheaders.ContentEncoding) == "" && len(body) >= cw.cfg.MinSize {
+			if encoding := cw.cfg.Negotiate(cw.acceptEncoding); encoding != "" {
+				compressed, err := compression.Compress(encoding, body)
+				if err.IsNotBlank() {
+					logger.Warn(fmt.Sprintf("W#3BQAVN - Sending uncompressed response: %v", err))
+				} else {
+					body = compressed
+					h.Set(httpheaders.ContentEncoding, encoding)
+					if etag := h.Get(httpheaders.ETag); etag != "" {
+						h.Set(httpheaders.ETag, compression.WeakETag(etag))
+					}
+				}
+			}
+		}
+	}
+
+	// A 204 has no content, and a 304 may only carry the Content-Length of the 200 representation (RFC 9110 §8.6),
+	// which is not known here
+	if cw.status != http.StatusNoContent && cw.status != http.StatusNotModified {
+		h.Set(httpheaders.ContentLength, strconv.Itoa(len(body)))
+	}
+	cw.writeHeaderOnce()
+	_, _ = cw.ResponseWriter.Write(body)
+}
+
+// File ends here
@@ -0,0 +1,143 @@
+package netserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/techrail/ground/idempotency"
+	"github.com/techrail/ground/logger"
+	"github.com/techrail/ground/typs/appError"
+)
+
+// Idempotency returns a middleware giving `Idempotency-Key` semantics to the requests of the configured methods:
+// the response to the first request with a key is stored and replayed (with the Idempotent-Replayed header) for the
+// duplicates. Flushed responses, hijacked connections, server errors and panics release the key instead, so that
+// the client can retry.
+func (m *middleware) Idempotency(cfg idempotency.Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
+			if !cfg.Applies(rq.Method) {
+				next.ServeHTTP(w, rq)
+				return
+			}
+			renderer := m.renderer()
+			key := rq.Header.Get(cfg.HeaderName)
+			if errTy := cfg.ValidateKey(key); errTy.IsNotBlank() {
+				renderer.JsonWithFailureUsingErrorType(w, rq, errTy)
+				return
+			}
+			if key == "" {
+				next.ServeHTTP(w, rq)
+				return
+			}
+
+			body, err := io.ReadAll(rq.Body)
+			if err != nil {
+				renderer.JsonWithFailureUsingErrorType(w, rq, appError.NewNetworkError(http.StatusBadRequest,
+					appError.Warning, "3HE71Q", "Could not read the request body", err.Error()))
+				return
+			}
+			rq.Body = io.NopCloser(bytes.NewReader(body))
+
+			storeKey := cfg.StoreKey(rq.Method, rq.URL.Path, key)
+			fingerprint := idempotency.Fingerprint(rq.Method, rq.URL.RequestURI(), body)
+			outcome, rec, errTy := cfg.Begin(rq.Context(), storeKey, fingerprint)
+			switch outcome {
+			case idempotency.Proceed:
+			case idempotency.Replay:
+				// The stored headers do not include the request ID, so the one of this request is kept
+				for name, values := range rec.Header {
+					w.Header()[name] = values
+				}
+				w.Header().Set(idempotency.ReplayedHeader, "true")
+				w.WriteHeader(rec.Status)
+				_, _ = w.Write(rec.Body)
+				return
+			default:
+				renderer.JsonWithFailureUsingErrorType(w, rq, errTy)
+				return
+			}
+
+			rw := &idempotencyRecorder{ResponseWriter: w, maxBodySize: cfg.MaxBodySize}
+			finished := false
+			defer func() {
+				if !finished {
+					// The handler panicked
+					_ = cfg.Abandon(context.Background(), storeKey, rec.Owner)
+				}
+			}()
+			next.ServeHTTP(rw, rq)
+			finished = true
+
+			if rw.streamed || rw.tooBig {
+				if errTy = cfg.Abandon(context.Background(), storeKey, rec.Owner); errTy.IsNotBlank() {
+					logger.Warn(fmt.Sprintf("W#3BGQHA - Could not release the idempotency key: %v", errTy))
+				}
+				return
+			}
+			if rw.status == 0 {
+				rw.status = http.StatusOK
+			}
+			errTy = cfg.Finish(context.Background(), storeKey, rec.Owner, fingerprint, rw.status, w.Header().Clone(), rw.body.Bytes())
+			if errTy.IsNotBlank() {
+				logger.Warn(fmt.Sprintf("W#3A0JBM - Could not store the response for the idempotency key: %v", errTy))
+			}
+		})
+	}
+}
+
+// idempotencyRecorder writes the response through while keeping a copy of it
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	maxBodySize int
+	status      int
+	body        bytes.Buffer
+	streamed    bool // The response was flushed or the connection hijacked
+	tooBig      bool
+}
+
+func (rw *idempotencyRecorder) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *idempotencyRecorder) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	if !rw.tooBig {
+		if rw.maxBodySize > 0 && rw.body.Len()+len(b) > rw.maxBodySize {
+			rw.tooBig = true
+			rw.body.Reset()
+		} else {
+			rw.body.Write(b)
+		}
+	}
+	return rw.ResponseWriter.Write(b)
+}
+
+// Flush sends the response to the client. A flushed response is a stream, which is not stored.
+func (rw *idempotencyRecorder) Flush() {
+	rw.streamed = true
+	_ = http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+// Hijack lets the handlers take over the connection
+func (rw *idempotencyRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	rw.streamed = true
+	return http.NewResponseController(rw.ResponseWriter).Hijack()
+}
+
+// Unwrap allows http.ResponseController to reach the original writer
+func (rw *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// File ends here
@@ -0,0 +1,161 @@
+package netserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/techrail/ground/constants/customHeaders"
+	"github.com/techrail/ground/constants/httpheaders"
+	"github.com/techrail/ground/logger"
+	"github.com/techrail/ground/respcache"
+)
+
+// CacheResponse returns a middleware caching the responses of the GET and HEAD requests in the configured store.
+// The entries are keyed on the path, the (sorted) query and the configured request headers and kept for the TTL of
+// the route. The cached responses carry an ETag and a Last-Modified header, and the conditional requests
+// (If-None-Match, If-Modified-Since) matching them get a 304. Flushed responses and hijacked connections are never
+// cached. The requests with credentials (Authorization or Cookie) are only served, and only store, the responses
+// marked as shared (Cache-Control public or s-maxage).
+func (m *middleware) CacheResponse(cfg respcache.Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
+			ttl := cfg.TTLFor(rq.URL.Path)
+			if ttl <= 0 || !respcache.Applies(rq.Method, rq.Header.Get(httpheaders.CacheControl)) {
+				next.ServeHTTP(w, rq)
+				return
+			}
+
+			key := cfg.Key(rq.URL.Path, rq.URL.RawQuery, rq.Header.Get)
+			authenticated := respcache.Authenticated(rq.Header.Get)
+			entry, found, errTy := cfg.Store.Get(rq.Context(), key)
+			if errTy.IsNotBlank() {
+				logger.Warn(fmt.Sprintf("W#3BJ3IW - Could not read the response cache: %v", errTy))
+			}
+			if found && entry.Servable(authenticated) {
+				// The entries do not hold a request ID, so the one of this request is kept
+				h := w.Header()
+				for name, values := range entry.Header {
+					h[name] = values
+				}
+				h.Set(httpheaders.Age, entry.Age())
+				h.Set(respcache.CacheStatusHeader, "HIT")
+				writeEntry(w, rq, entry.Status, entry, entry.Body)
+				return
+			}
+
+			cw := &cacheWriter{ResponseWriter: w}
+			next.ServeHTTP(cw, rq)
+			if cw.passThrough {
+				return
+			}
+			if cw.status == 0 {
+				cw.status = http.StatusOK
+			}
+			body := cw.buf.Bytes()
+			if rq.Method != http.MethodGet || !cfg.Cacheable(cw.status, w.Header(), len(body), authenticated) {
+				// HEAD responses have no body, so only the GET responses are stored
+				w.WriteHeader(cw.status)
+				_, _ = w.Write(body)
+				return
+			}
+
+			entry = respcache.NewEntry(cw.status, w.Header(), bytes.Clone(body))
+			if errTy = cfg.Store.Set(context.Background(), key, entry, ttl); errTy.IsNotBlank() {
+				logger.Warn(fmt.Sprintf("W#3D2R2T - Could not store the response in the cache: %v", errTy))
+			}
+			h := w.Header()
+			h.Set(httpheaders.ETag, entry.ETag)
+			h.Set(httpheaders.LastModified, entry.LastModified.Format(http.TimeFormat))
+			h.Set(respcache.CacheStatusHeader, "MISS")
+			writeEntry(w, rq, cw.status, entry, body)
+		})
+	}
+}
+
+// writeEntry writes the response (or a 304 if the request is a matching conditional request)
+func writeEntry(w http.ResponseWriter, rq *http.Request, status int, entry respcache.Entry, body []byte) {
+	h := w.Header()
+	if respcache.NotModified(rq.Header.Get(httpheaders.IfNoneMatch), rq.Header.Get(httpheaders.IfModifiedSince),
+		entry.ETag, entry.LastModified) {
+		for name := range h {
+			if !respcache.KeepInNotModified(name) && name != respcache.CacheStatusHeader && name != httpheaders.Age &&
+				name != customHeaders.RequestId {
+				h.Del(name)
+			}
+		}
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	h.Set(httpheaders.ContentLength, strconv.Itoa(len(body)))
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// cacheWriter buffers the response so that it can be stored once the handler is done with it
+type cacheWriter struct {
+	http.ResponseWriter
+	buf         bytes.Buffer
+	status      int
+	passThrough bool // Set when the response must go to the client as it is (streams, hijacked connections)
+}
+
+func (cw *cacheWriter) WriteHeader(status int) {
+	if cw.status != 0 {
+		return
+	}
+	cw.status = status
+	if cw.Header().Get(httpheaders.ContentType) == "text/event-stream" {
+		cw.startPassThrough()
+	}
+}
+
+func (cw *cacheWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.passThrough {
+		return cw.ResponseWriter.Write(b)
+	}
+	return cw.buf.Write(b)
+}
+
+// Flush sends everything written so far to the client and switches to the pass through mode
+func (cw *cacheWriter) Flush() {
+	if !cw.passThrough {
+		cw.startPassThrough()
+	}
+	_ = http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Hijack lets the handlers take over the connection
+func (cw *cacheWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	cw.passThrough = true
+	return http.NewResponseController(cw.ResponseWriter).Hijack()
+}
+
+// Unwrap allows http.ResponseController to reach the original writer
+func (cw *cacheWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func (cw *cacheWriter) startPassThrough() {
+	if cw.passThrough {
+		return
+	}
+	cw.passThrough = true
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if cw.buf.Len() > 0 {
+		_, _ = cw.ResponseWriter.Write(cw.buf.Bytes())
+		cw.buf.Reset()
+	}
+}
+
+// File ends here
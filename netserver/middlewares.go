@@ -2,52 +2,113 @@ package netserver
 
 import (
 	"context"
-	"log"
+	"fmt"
 	"net/http"
+	"runtime/debug"
 
-	"github.com/google/uuid"
 	"github.com/techrail/ground/constants/customCtxKey"
+	"github.com/techrail/ground/constants/customHeaders"
+	"github.com/techrail/ground/constants/httpheaders"
+	"github.com/techrail/ground/logger"
+	"github.com/techrail/ground/requestid"
+	"github.com/techrail/ground/respheaders"
+	"github.com/techrail/ground/typs/appError"
 )
 
-type middleware struct{}
+type middleware struct {
+	server *NetHttpServer // Server whose renderer renders the failures (nil for the package level Middleware)
+}
 
+// Middleware renders its failures with a blank Renderer. Use NetHttpServer.Middleware to render them with the
+// renderer of the server.
 var Middleware *middleware
 
 func init() {
 	Middleware = new(middleware)
 }
 
-// RequestIDMiddleware is a Middleware to check and set requestID
+// RequestIDMiddleware puts the request ID in the request context (see customCtxKey.RequestId) and echoes it in the
+// response. The ID sent by the client in the X-Request-Id header is used if it is valid; otherwise a new one
+// (a ULID) is generated.
 func (m *middleware) RequestIDMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		requestID := r.Header.Get(customCtxKey.RequestId)
-		if requestID == "" {
-			// Generate a new UUID as the requestID
-			requestID = uuid.New().String()
+		incoming := r.Header.Get(customHeaders.RequestId)
+		requestID := requestid.FromIncoming(incoming)
+		if incoming != "" && incoming != requestID {
+			logger.Println("D#3DJMA4 - Discarded invalid incoming request ID; using " + requestID)
 		}
-		// Create a new context with the userID value
-		ctx := context.WithValue(r.Context(), customCtxKey.RequestId, requestID)
 
-		// Create a new request with the new context
-		r = r.WithContext(ctx)
+		w.Header().Set(customHeaders.RequestId, requestID)
+		ctx := requestid.NewContext(r.Context(), requestID)
+		// The trace context is kept as it is; the outbound HTTP client (see httpclient) forwards it
+		if tp := r.Header.Get(httpheaders.Traceparent); tp != "" {
+			ctx = context.WithValue(ctx, customCtxKey.TraceParent, tp)
+			ctx = context.WithValue(ctx, customCtxKey.TraceState, r.Header.Get(httpheaders.Tracestate))
+		}
 
-		// Call the next handler in the chain with the updated request
-		next.ServeHTTP(w, r)
+		// Call the next handler in the chain with the request ID in the context
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// RecoverPanic recovers from a panic raised by the handlers down the chain. The panic is converted into an error
+// at the Panic level, logged (with the request ID and the stack trace) and the standard failure response is sent.
+// The stack trace is included in the response only if it was requested (see customCtxKey.StackTraceRequested).
 func (m *middleware) RecoverPanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
-			if err := recover(); err != nil {
-				// Log the panic
-				log.Printf("Panic recovered: %v", err)
-				// Respond with 500 Internal Server Error
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			rec := recover()
+			if rec == nil {
+				return
 			}
+			if rec == http.ErrAbortHandler {
+				// This one is meant to abort the response. Let net/http deal with it.
+				panic(rec)
+			}
+
+			requestId := GetValueFromCtxByStringKey(r.Context(), customCtxKey.RequestId, "")
+			if requestId == "" {
+				// RecoverPanic usually wraps RequestIDMiddleware, whose context does not come back up the chain, but
+				// the ID it echoed is in the response
+				requestId = w.Header().Get(customHeaders.RequestId)
+				r = r.WithContext(requestid.NewContext(r.Context(), requestId))
+			}
+			logger.Panic(fmt.Sprintf("P#3GDCZ0 - Panic recovered while serving request %v (%v %v): %v\n%s",
+				requestId, r.Method, r.URL.RequestURI(), rec, debug.Stack()))
+
+			errTy := appError.NewNetworkError(http.StatusInternalServerError, appError.Panic, "3GDCZ0",
+				"Internal error. Error logged against request ID "+requestId, fmt.Sprintf("Panic: %v", rec))
+
+			// The renderer is called from within this deferred function, so the stack trace it captures still
+			// contains the panicking frames.
+			m.renderer().JsonWithFailureUsingErrorType(w, r, errTy)
 		}()
 		next.ServeHTTP(w, r)
 	})
 }
 
+// renderer returns the renderer of the server the middlewares belong to (a blank one if there is none)
+func (m *middleware) renderer() *Renderer {
+	if m.server == nil || m.server.Render == nil {
+		return &Renderer{}
+	}
+	return m.server.Render
+}
+
+// SecurityHeaders adds the security headers (CSP, X-Frame-Options, X-Content-Type-Options, Referrer-Policy,
+// Permissions-Policy and HSTS) configured in the header policy to the response. The Strict-Transport-Security
+// header is only sent when the request came over a secure connection (directly or through a TLS terminating proxy).
+func (m *middleware) SecurityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		policy := respheaders.Current()
+		for _, h := range policy.Security {
+			w.Header().Set(h.Name, h.Value)
+		}
+		if policy.Hsts != "" && (r.TLS != nil || r.Header.Get(httpheaders.XForwardedProto) == "https") {
+			w.Header().Set(httpheaders.StrictTransportSecurity, policy.Hsts)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // File ends here
@@ -0,0 +1,145 @@
+package netserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/techrail/ground/constants/customCtxKey"
+	"github.com/techrail/ground/constants/customHeaders"
+	"github.com/techrail/ground/problem"
+	"github.com/techrail/ground/respcache"
+	"github.com/techrail/ground/typs/appError"
+)
+
+func TestRecoverPanic_UsesTheRendererOfTheServer(t *testing.T) {
+	s := NewServer(0, false)
+	s.Render.ProblemDetailsFailures = true
+	handler := s.Middleware().RecoverPanic(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panics", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("E#3HYCJ0 - Expected a 500, got %v", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != problem.ContentType {
+		t.Errorf("E#3E3KM5 - Expected problem details, got the content type %v", ct)
+	}
+}
+
+func TestCacheResponse_AuthenticatedRequests(t *testing.T) {
+	s := NewServer(0, false)
+	calls := 0
+	cacheResponse := s.Middleware().CacheResponse(respcache.DefaultConfig(respcache.NewMemoryStore(0)))
+	handler := s.Middleware().RequestIDMiddleware(cacheResponse(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/public" {
+			w.Header().Set("Cache-Control", "public, max-age=60")
+		}
+		_, _ = w.Write([]byte("hello"))
+	})))
+	get := func(path string, header http.Header) *httptest.ResponseRecorder {
+		rq := httptest.NewRequest(http.MethodGet, path, nil)
+		for name, values := range header {
+			rq.Header[name] = values
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, rq)
+		return w
+	}
+
+	first := get("/profile", nil)
+	second := get("/profile", nil)
+	if calls != 1 || second.Header().Get(respcache.CacheStatusHeader) != "HIT" {
+		t.Fatalf("E#3HKNGH - The anonymous response was not served from the cache (%v calls)", calls)
+	}
+	if id := second.Header().Get("X-Request-Id"); id == "" || id == first.Header().Get("X-Request-Id") {
+		t.Errorf("E#3CCETH - The cached response carried the request ID %q; want the one of the request", id)
+	}
+
+	get("/profile", http.Header{"Authorization": {"Bearer alice"}})
+	get("/profile", http.Header{"Cookie": {"session=bob"}})
+	if calls != 3 {
+		t.Errorf("E#3AEO4C - The authenticated requests were served from the cache (%v calls; want 3)", calls)
+	}
+	get("/account", http.Header{"Cookie": {"session=bob"}})
+	if w := get("/account", nil); w.Header().Get(respcache.CacheStatusHeader) != "MISS" {
+		t.Errorf("E#3A20MY - The response to an authenticated request was stored and served to another client")
+	}
+
+	get("/public", http.Header{"Authorization": {"Bearer alice"}})
+	if w := get("/public", http.Header{"Authorization": {"Bearer bob"}}); calls != 6 ||
+		w.Header().Get(respcache.CacheStatusHeader) != "HIT" {
+		t.Errorf("E#3C50W3 - The shared response was not served from the cache (%v calls; want 6)", calls)
+	}
+}
+
+func TestProblemWithFailure_DevMsgAndOperationalLog(t *testing.T) {
+	s := NewServer(0, false)
+	errTy := appError.NewNetworkError(http.StatusBadRequest, appError.Error, "CODE1", "Bad input", "the dev message",
+		appError.NewError(appError.Error, "CODE2", "The cause"))
+
+	rq := httptest.NewRequest(http.MethodGet, "/fails", nil)
+	w := httptest.NewRecorder()
+	s.Render.ProblemWithFailure(w, rq, errTy)
+	if body := w.Body.String(); strings.Contains(body, "the dev message") || strings.Contains(body, "CODE2") {
+		t.Errorf("E#3GLVLI - The dev message was rendered without being allowed: %v", body)
+	}
+
+	ctx := context.WithValue(rq.Context(), customCtxKey.DevMsgAllowedInFailure, true)
+	ctx = context.WithValue(ctx, customCtxKey.OpLogRequested, true)
+	ctx = context.WithValue(ctx, customCtxKey.CtxOperationLogContent, []string{"step 1"})
+	w = httptest.NewRecorder()
+	s.Render.ProblemWithFailure(w, rq.WithContext(ctx), errTy)
+	var got map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("E#3GL55V - Could not decode the problem details: %v", err)
+	}
+	if got["devMsg"] != "the dev message" || got["wrappedErrors"] == nil || got["operationalLog"] == nil {
+		t.Errorf("E#3ACSR5 - Expected the dev message, the wrapped errors and the operational log, got %v", got)
+	}
+}
+
+func TestRecoverPanic_WrappingTheRequestIDMiddleware(t *testing.T) {
+	s := NewServer(0, false)
+	handler := s.Middleware().RecoverPanic(s.Middleware().RequestIDMiddleware(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		})))
+
+	rq := httptest.NewRequest(http.MethodGet, "/panics", nil)
+	rq.Header.Set(customHeaders.RequestId, "req-1")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, rq)
+	if id := w.Header().Get(customHeaders.RequestId); id != "req-1" {
+		t.Errorf("E#3GNRGU - Expected the request ID in the response, got %q", id)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "req-1") {
+		t.Errorf("E#3D8GSP - Expected the request ID in the failure, got %v", body)
+	}
+}
+
+func TestCompressResponse_ContentLength(t *testing.T) {
+	for status, expected := range map[int]string{
+		http.StatusOK:          "5",
+		http.StatusNoContent:   "",
+		http.StatusNotModified: "",
+	} {
+		handler := Middleware.CompressResponse(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			if status == http.StatusOK {
+				_, _ = w.Write([]byte("hello"))
+			}
+		}))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if got := w.Header().Get("Content-Length"); w.Code != status || got != expected {
+			t.Errorf("E#3EZBVS - For a %v expected the Content-Length %q, got a %v with %q", status, expected, w.Code, got)
+		}
+	}
+}
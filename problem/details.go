@@ -0,0 +1,149 @@
+// Package problem maps appError.Typ values to RFC 7807 problem details (application/problem+json) which are used by
+// the failure renderers of both the fasthttp (render) and the net/http (netserver) flavours of the servers.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/techrail/ground/typs/appError"
+)
+
+const ContentType = "application/problem+json"
+
+// TypeBaseUri is prepended to the error code to build the `type` member. When it is blank, `about:blank` is used
+// as the type (in which case the title is the text of the HTTP status, as the RFC asks).
+var TypeBaseUri = ""
+
+// Details is an RFC 7807 problem details object. Extensions are serialized as top level members.
+type Details struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]any
+}
+
+// WrappedError describes one element of the chain of wrapped errors
+type WrappedError struct {
+	Code    string `json:"code"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// Options control which of the optional members are included
+type Options struct {
+	Instance       string   // Value of the `instance` member (usually the request URI)
+	RequestId      string   // Added as the `requestId` extension when not blank
+	IncludeDevMsg  bool     // Add the DevMsg of the error as the `devMsg` extension
+	IncludeWrapped bool     // Add the chain of wrapped errors as the `wrappedErrors` extension
+	StackTrace     []string // Added as the `stackTrace` extension when not empty
+}
+
+// FromAppError builds the problem details for the error. The Code, Level and ExtraData of the error end up in the
+// `code`, `level` and `extraData` extension members. ExtraData is embedded as JSON if it is valid JSON.
+func FromAppError(errTy appError.Typ, opts Options) Details {
+	status := errTy.HttpResponseCode
+	if status < 400 || status > 599 {
+		status = http.StatusInternalServerError
+	}
+
+	d := Details{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   errTy.Message,
+		Instance: opts.Instance,
+		Extensions: map[string]any{
+			"code":  errTy.Code,
+			"level": errTy.Level.String(),
+		},
+	}
+	if TypeBaseUri != "" {
+		d.Type = TypeBaseUri + errTy.Code
+	}
+
+	if opts.RequestId != "" {
+		d.Extensions["requestId"] = opts.RequestId
+	}
+	if opts.IncludeDevMsg && errTy.DevMsg != "" {
+		d.Extensions["devMsg"] = errTy.DevMsg
+	}
+	if errTy.ExtraData != "" {
+		if json.Valid([]byte(errTy.ExtraData)) {
+			d.Extensions["extraData"] = json.RawMessage(errTy.ExtraData)
+		} else {
+			d.Extensions["extraData"] = errTy.ExtraData
+		}
+	}
+	if opts.IncludeWrapped {
+		var wrapped []WrappedError
+		for w := errTy.WrappedError; w != nil; w = w.WrappedError {
+			wrapped = append(wrapped, WrappedError{Code: w.Code, Level: w.Level.String(), Message: w.Message})
+		}
+		if len(wrapped) > 0 {
+			d.Extensions["wrappedErrors"] = wrapped
+		}
+	}
+	if len(opts.StackTrace) > 0 {
+		d.Extensions["stackTrace"] = opts.StackTrace
+	}
+	return d
+}
+
+// MarshalJSON writes the standard members followed by the extension members. Extensions can't override the
+// standard members.
+func (d Details) MarshalJSON() ([]byte, error) {
+	m := make(map[string]any, len(d.Extensions)+5)
+	for k, v := range d.Extensions {
+		m[k] = v
+	}
+	m["type"] = d.Type
+	m["title"] = d.Title
+	m["status"] = d.Status
+	if d.Detail != "" {
+		m["detail"] = d.Detail
+	} else {
+		delete(m, "detail")
+	}
+	if d.Instance != "" {
+		m["instance"] = d.Instance
+	} else {
+		delete(m, "instance")
+	}
+	return json.Marshal(m)
+}
+
+// Bytes returns the JSON representation of the problem details (or a minimal hand-built one if marshalling fails)
+func (d Details) Bytes() []byte {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return []byte(`{"type":"about:blank","title":"Internal Server Error","status":500}`)
+	}
+	return b
+}
+
+// Accepted tells if the client listed application/problem+json in the value of its `Accept` header
+func Accepted(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		if strings.ToLower(strings.TrimSpace(fields[0])) != ContentType {
+			continue
+		}
+		for _, param := range fields[1:] {
+			param = strings.ReplaceAll(strings.TrimSpace(param), " ", "")
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			// A q-value of 0 (0.0, 0.000...) means not acceptable
+			if q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil && q <= 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
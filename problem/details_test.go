@@ -0,0 +1,71 @@
+package problem
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/techrail/ground/typs/appError"
+)
+
+func TestFromAppError(t *testing.T) {
+	inner := appError.NewError(appError.Error, "1AAAAA", "row not found")
+	errTy := appError.NewNetworkError(404, appError.Warning, "1BBBBB", "User does not exist", "no row for id 7", inner)
+	errTy.ExtraData = `{"userId":7}`
+
+	d := FromAppError(errTy, Options{Instance: "/users/7", RequestId: "01HX", IncludeDevMsg: true, IncludeWrapped: true})
+
+	var m map[string]any
+	if err := json.Unmarshal(d.Bytes(), &m); err != nil {
+		t.Fatalf("E#3BP047 - Could not parse the problem details: %v", err)
+	}
+
+	expected := map[string]any{
+		"type":      "about:blank",
+		"title":     "Not Found",
+		"status":    float64(404),
+		"detail":    "User does not exist",
+		"instance":  "/users/7",
+		"code":      "1BBBBB",
+		"level":     "Warning",
+		"requestId": "01HX",
+		"devMsg":    "no row for id 7",
+	}
+	for k, v := range expected {
+		if m[k] != v {
+			t.Errorf("E#3DWJBK - Expected `%v` to be `%v`, got `%v`", k, v, m[k])
+		}
+	}
+	if extra, ok := m["extraData"].(map[string]any); !ok || extra["userId"] != float64(7) {
+		t.Errorf("E#3A274S - Expected extraData to be embedded as JSON, got %v", m["extraData"])
+	}
+	if wrapped, ok := m["wrappedErrors"].([]any); !ok || len(wrapped) != 1 {
+		t.Errorf("E#3EUP2I - Expected one wrapped error, got %v", m["wrappedErrors"])
+	}
+}
+
+func TestFromAppError_NonNetworkErrorBecomes500(t *testing.T) {
+	d := FromAppError(appError.NewError(appError.Error, "1CCCCC", "boom"), Options{})
+	if d.Status != 500 || d.Title != "Internal Server Error" {
+		t.Errorf("E#3BAFON - Expected a 500 problem, got %v %v", d.Status, d.Title)
+	}
+	if _, ok := d.Extensions["devMsg"]; ok {
+		t.Errorf("E#3F52E1 - Did not expect devMsg when it was not asked for")
+	}
+}
+
+func TestAccepted(t *testing.T) {
+	if !Accepted("application/json, application/problem+json") {
+		t.Errorf("E#3H7WLW - Expected problem+json to be accepted")
+	}
+	if Accepted("application/problem+json;q=0") || Accepted("application/json") {
+		t.Errorf("E#3ASGFH - Did not expect problem+json to be accepted")
+	}
+	for _, accept := range []string{"application/problem+json;q=0.0", "application/problem+json; q=0.000"} {
+		if Accepted(accept) {
+			t.Errorf("E#3D1EZF - Did not expect problem+json to be accepted with `%v`", accept)
+		}
+	}
+	if !Accepted("application/problem+json;q=0.5") || !Accepted("application/problem+json;level=1") {
+		t.Errorf("E#3GKZTD - Expected problem+json to be accepted with a positive q-value")
+	}
+}
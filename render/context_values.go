@@ -0,0 +1,97 @@
+package render
+
+import (
+	"runtime/debug"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/techrail/ground/constants/customCtxKey"
+	"github.com/techrail/ground/constants/customHeaders"
+	"github.com/techrail/ground/logger"
+	"github.com/techrail/ground/requestid"
+)
+
+// stackTraceLines returns the current stack trace (one line per element) if it was requested in the context.
+// It returns nil otherwise.
+func stackTraceLines(ctx *fasthttp.RequestCtx) []string {
+	var stackTrace []byte
+	stackTrace = nil
+	captureStackTrace := ctx.UserValue(customCtxKey.StackTraceRequested)
+	if captureStackTrace != nil {
+		// Option was set in the context. Try to check its value
+		valBool, ok := captureStackTrace.(bool)
+		if ok && valBool {
+			// Stacktrace was requested
+			stackTrace = debug.Stack()
+		}
+	}
+	if len(stackTrace) == 0 {
+		// To ensure that if a blank stack trace is sent by the runtime, it is discarded
+		return nil
+	}
+	stackTraceStr := string(stackTrace)
+	stackTraceStr = strings.ReplaceAll(stackTraceStr, "\t", "    ")
+	return strings.Split(stackTraceStr, "\n")
+}
+
+// operationalLog returns the operational log collected in the context if it was requested. It returns nil otherwise.
+func operationalLog(ctx *fasthttp.RequestCtx) []string {
+	var opLog []string
+	respondWithOplog := ctx.UserValue(customCtxKey.OpLogRequested)
+	if respondWithOplog != nil {
+		// Option was set in the context. Try to check its value
+		valBool, ok := respondWithOplog.(bool)
+
+		if ok {
+			if valBool == true {
+				res := ctx.UserValue(customCtxKey.CtxOperationLogContent)
+				if res == nil {
+					errMsg := "E#1MZFU2 - Unexpected nil value found"
+					logger.Println(errMsg)
+					opLog = []string{
+						errMsg,
+					}
+				} else {
+					// Try to assert
+					if oprLog, typeAsserted := res.([]string); !typeAsserted {
+						errMsg := "E#1MZFUD - Incorrect data format"
+						logger.Println(errMsg)
+						opLog = []string{
+							errMsg,
+						}
+					} else {
+						opLog = oprLog
+					}
+				}
+			}
+		} else {
+			opLog = []string{
+				"E#1MZH45 - Value against user key was not in expected data type",
+			}
+		}
+	}
+	return opLog
+}
+
+// allowedDevMessage returns the devMessage if the client is allowed to see it (blank otherwise)
+func allowedDevMessage(ctx *fasthttp.RequestCtx, devMessage string) string {
+	devMsg := ""
+	if val, ok := ctx.UserValue(customCtxKey.DevMsgAllowedInFailure).(bool); ok != false {
+		// Key contains a valid boolean value. Check if the value is true
+		if val {
+			// devMessage can be sent
+			devMsg = devMessage
+		}
+	}
+	return devMsg
+}
+
+// setRequestIdHeader echoes the request ID stored in the context (if any) in the response and returns it
+func setRequestIdHeader(ctx *fasthttp.RequestCtx) string {
+	requestId := requestid.FromContext(ctx)
+	if requestId != "" {
+		ctx.Response.Header.Set(customHeaders.RequestId, requestId)
+	}
+	return requestId
+}
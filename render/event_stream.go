@@ -0,0 +1,45 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/techrail/ground/constants/httpheaders"
+	"github.com/techrail/ground/logger"
+	"github.com/techrail/ground/sse"
+	"github.com/techrail/ground/typs/appError"
+)
+
+// EventStream responds with a Server-Sent Events stream. The function fn is called once the response headers have
+// been sent and the events it sends are flushed to the client right away. The stream is closed when fn returns.
+// A disconnected client is detected on the next write (the heartbeats make sure that there is one), after which
+// stream.Done() is closed and every send fails.
+func EventStream(ctx *fasthttp.RequestCtx, opts sse.Options, fn func(stream *sse.Stream) appError.Typ) {
+	addFixedHeaders(ctx)
+	ctx.Response.Header.Set(fasthttp.HeaderContentType, sse.ContentType)
+	ctx.Response.Header.Set(fasthttp.HeaderCacheControl, "no-cache")
+	ctx.Response.Header.Set(fasthttp.HeaderConnection, "keep-alive")
+	ctx.Response.Header.Set("X-Accel-Buffering", "no")
+	setRequestIdHeader(ctx)
+
+	lastEventId := string(ctx.Request.Header.Peek(httpheaders.LastEventID))
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	// The stream writer must not touch ctx (it may have been recycled by then)
+	serverDone := ctx.Done()
+	ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		stream := sse.NewStream(w, w.Flush, lastEventId)
+		go func() {
+			// Close the stream when the server shuts down so that fn gets a chance to return
+			select {
+			case <-serverDone:
+				stream.Close()
+			case <-stream.Done():
+			}
+		}()
+		if e := stream.Run(opts, fn); e.IsNotBlank() {
+			logger.Debug(fmt.Sprintf("D#3B4JSK - Event stream ended with error: %v", e))
+		}
+	})
+}
@@ -1,8 +1,14 @@
 package render
 
-import "github.com/valyala/fasthttp"
+import (
+	"github.com/valyala/fasthttp"
 
+	"github.com/techrail/ground/respheaders"
+)
+
+// addFixedHeaders adds the server identity headers decided by the header policy (which can also be none at all)
 func addFixedHeaders(ctx *fasthttp.RequestCtx) {
-	ctx.Response.Header.Set(fasthttp.HeaderServer, "Apache 2.4")
-	ctx.Response.Header.Set(fasthttp.HeaderXPoweredBy, "PHP/7.2.12")
+	for _, h := range respheaders.Current().Identity {
+		ctx.Response.Header.Set(h.Name, h.Value)
+	}
 }
@@ -3,12 +3,12 @@ package render
 import (
 	"encoding/json"
 	"fmt"
-	"github.com/techrail/ground/constants/customCtxKey"
-	"github.com/techrail/ground/constants/customHeaders"
+	"time"
+
+	"github.com/techrail/ground/constants/httpheaders"
 	"github.com/techrail/ground/logger"
+	"github.com/techrail/ground/respcache"
 	"github.com/valyala/fasthttp"
-	"runtime/debug"
-	"strings"
 )
 
 // JsonStringWithSuccess is supposed to set the response code and string body value in the context response
@@ -16,9 +16,7 @@ import (
 func JsonStringWithSuccess(ctx *fasthttp.RequestCtx, httpCode int, jsonBody string) {
 	addFixedHeaders(ctx)
 	ctx.Response.Header.Set(fasthttp.HeaderContentType, "application/json; charset=utf-8")
-	if ctx.UserValue(customCtxKey.RequestId) != nil {
-		ctx.Response.Header.Set(customHeaders.RequestId, fmt.Sprintf("%v", ctx.UserValue(customCtxKey.RequestId)))
-	}
+	setRequestIdHeader(ctx)
 
 	// Log for human error
 	if httpCode < 200 || httpCode > 299 {
@@ -48,8 +46,8 @@ func JsonStringWithSuccess(ctx *fasthttp.RequestCtx, httpCode int, jsonBody stri
 // JsonBytesWithSuccess is supposed to set the response code and []byte body value in the context response
 func JsonBytesWithSuccess(ctx *fasthttp.RequestCtx, httpCode int, jsonBody []byte) {
 	addFixedHeaders(ctx)
-	ctx.Response.Header.Set(fasthttp.HeaderXContentTypeOptions, "application/json; charset=utf-8")
-	ctx.Response.Header.Set(customHeaders.RequestId, fmt.Sprintf("%v", ctx.UserValue(customCtxKey.RequestId)))
+	ctx.Response.Header.Set(fasthttp.HeaderContentType, "application/json; charset=utf-8")
+	setRequestIdHeader(ctx)
 
 	// Log for human error
 	if httpCode < 200 || httpCode > 299 {
@@ -64,7 +62,7 @@ func JsonBytesWithSuccess(ctx *fasthttp.RequestCtx, httpCode int, jsonBody []byt
 func JsonStructWithSuccess(ctx *fasthttp.RequestCtx, httpCode int, structToMarshal interface{}) {
 	addFixedHeaders(ctx)
 	ctx.Response.Header.Set(fasthttp.HeaderContentType, "application/json; charset=utf-8")
-	ctx.Response.Header.Set(customHeaders.RequestId, fmt.Sprintf("%v", ctx.UserValue(customCtxKey.RequestId)))
+	setRequestIdHeader(ctx)
 
 	// Log for human error
 	if httpCode < 200 || httpCode > 299 {
@@ -72,61 +70,8 @@ func JsonStructWithSuccess(ctx *fasthttp.RequestCtx, httpCode int, structToMarsh
 		logger.Println(errMsg)
 	}
 
-	// MARKER: Capturing stack trace
-	var stackTrace []byte
-	stackTrace = nil
-	captureStackTrace := ctx.UserValue(customCtxKey.StackTraceRequested)
-	if captureStackTrace != nil {
-		// Option was set in the context. Try to check its value
-		valBool, ok := captureStackTrace.(bool)
-		if ok && valBool {
-			// Stacktrace was requested
-			stackTrace = debug.Stack()
-		}
-	}
-	stackTraceStr := string(stackTrace)
-	stackTraceStr = strings.ReplaceAll(stackTraceStr, "\t", "    ")
-	stackTraceStrLines := strings.Split(stackTraceStr, "\n")
-	if len(stackTrace) == 0 {
-		// To ensure that if a blank stack trace is sent by the runtime, it is discarded
-		stackTraceStrLines = nil
-	}
-
-	// MARKER: Capturing operational log
-	var opLog []string
-	respondWithOplog := ctx.UserValue(customCtxKey.OpLogRequested)
-	if respondWithOplog != nil {
-		// Option was set in the context. Try to check its value
-		valBool, ok := respondWithOplog.(bool)
-
-		if ok {
-			if valBool == true {
-				res := ctx.UserValue(customCtxKey.CtxOperationLogContent)
-				if res == nil {
-					errMsg := "E#1MZFU2 - Unexpected nil value found"
-					logger.Println(errMsg)
-					opLog = []string{
-						errMsg,
-					}
-				} else {
-					// Try to assert
-					if oprLog, typeAsserted := res.([]string); !typeAsserted {
-						errMsg := "E#1MZFUD - Incorrect data format"
-						logger.Println(errMsg)
-						opLog = []string{
-							errMsg,
-						}
-					} else {
-						opLog = oprLog
-					}
-				}
-			}
-		} else {
-			opLog = []string{
-				"E#1MZH45 - Value against user key was not in expected data type",
-			}
-		}
-	}
+	stackTraceStrLines := stackTraceLines(ctx)
+	opLog := operationalLog(ctx)
 
 	successResponse := jsonResponseSuccess{
 		OperationalLog: opLog,
@@ -147,5 +92,17 @@ func JsonStructWithSuccess(ctx *fasthttp.RequestCtx, httpCode int, structToMarsh
 		return
 	}
 
+	// Successful responses get a strong ETag, and the conditional requests matching it get a 304 without a body
+	if httpCode >= 200 && httpCode <= 299 {
+		etag := respcache.StrongETag(jsonBytes)
+		ctx.Response.Header.Set(httpheaders.ETag, etag)
+		if (ctx.IsGet() || ctx.IsHead()) &&
+			respcache.NotModified(string(ctx.Request.Header.Peek(httpheaders.IfNoneMatch)), "", etag, time.Time{}) {
+			ctx.SetStatusCode(fasthttp.StatusNotModified)
+			ctx.Response.ResetBody()
+			return
+		}
+	}
+
 	JsonBytesWithSuccess(ctx, httpCode, jsonBytes)
 }
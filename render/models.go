@@ -1,11 +1,15 @@
 package render
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"encoding/xml"
+)
 
 type jsonResponseSuccess struct {
-	OperationalLog []string    `json:"operationalLog,omitempty"`
-	StackTrace     []string    `json:"stackTrace,omitempty"`
-	Data           interface{} `json:"data"`
+	XMLName        xml.Name    `json:"-" xml:"response"`
+	OperationalLog []string    `json:"operationalLog,omitempty" xml:"operationalLog>line,omitempty"`
+	StackTrace     []string    `json:"stackTrace,omitempty" xml:"stackTrace>line,omitempty"`
+	Data           interface{} `json:"data" xml:"data"`
 }
 
 // String just gets the json representation or a error string
@@ -23,11 +27,12 @@ func (e jsonResponseSuccess) String() string {
 
 // ==============================================================
 type jsonResponseFailure struct {
-	Code           string   `json:"code"`
-	Message        string   `json:"message"`
-	DevMsg         string   `json:"devMsg,omitempty"`
-	StackTrace     []string `json:"stackTrace,omitempty"`
-	OperationalLog []string `json:"operationalLog,omitempty"`
+	XMLName        xml.Name `json:"-" xml:"error"`
+	Code           string   `json:"code" xml:"code"`
+	Message        string   `json:"message" xml:"message"`
+	DevMsg         string   `json:"devMsg,omitempty" xml:"devMsg,omitempty"`
+	StackTrace     []string `json:"stackTrace,omitempty" xml:"stackTrace>line,omitempty"`
+	OperationalLog []string `json:"operationalLog,omitempty" xml:"operationalLog>line,omitempty"`
 }
 
 // String just gets the json representation or a error string
@@ -49,5 +54,5 @@ func (e jsonResponseFailure) String() string {
 // Useful when just a single `200 OK` or `201 CREATED` would be ok but you still want to send a message to the client
 // about what happened. e.g. "The blog post was created" or "The upload was successful" etc.
 type SingleMessageResponse struct {
-	Message string `json:"message"`
+	Message string `json:"message" xml:"message"`
 }
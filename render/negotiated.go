@@ -0,0 +1,114 @@
+package render
+
+import (
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/techrail/ground/compression"
+	"github.com/techrail/ground/contentcodec"
+	"github.com/techrail/ground/logger"
+	types "github.com/techrail/ground/typs"
+	"github.com/techrail/ground/typs/appError"
+)
+
+// StructWithSuccess works like JsonStructWithSuccess, but encodes the response in the format asked for in the
+// `Accept` header of the request (JSON, MessagePack, CBOR, XML or protobuf). The `{"data": ...}` envelope is kept
+// in every format except protobuf, where a proto.Message payload is sent as it is.
+func StructWithSuccess(ctx *fasthttp.RequestCtx, httpCode int, structToMarshal any) {
+	codec := contentcodec.Negotiate(string(ctx.Request.Header.Peek(fasthttp.HeaderAccept)), structToMarshal)
+	if codec == contentcodec.Json {
+		JsonStructWithSuccess(ctx, httpCode, structToMarshal)
+		addVaryAccept(ctx)
+		return
+	}
+
+	// Log for human error
+	if httpCode < 200 || httpCode > 299 {
+		logger.Println(fmt.Sprintf("E#3CU3HJ - Success Renderer Called with non-success HTTP code: %v", httpCode))
+	}
+
+	var payload any = structToMarshal
+	if codec != contentcodec.Protobuf {
+		payload = jsonResponseSuccess{
+			OperationalLog: operationalLog(ctx),
+			StackTrace:     stackTraceLines(ctx),
+			Data:           structToMarshal,
+		}
+	}
+
+	body, err := codec.Marshal(payload)
+	if err != nil {
+		errMsg := fmt.Sprintf("E#3AOMC9 - %v marshalling failed: %v", codec.Name(), err)
+		logger.Println(errMsg)
+		WithFailure(ctx, fasthttp.StatusInternalServerError, "3AOMC9", "Response encoding failed", errMsg)
+		return
+	}
+
+	writeEncoded(ctx, codec, httpCode, body)
+}
+
+// WithFailure works like JsonWithFailure, but encodes the failure envelope in the format asked for in the `Accept`
+// header of the request
+func WithFailure(ctx *fasthttp.RequestCtx, httpCode int, errorCode string, errorMessage string, devMessage string) {
+	codec := contentcodec.NegotiateForFailure(string(ctx.Request.Header.Peek(fasthttp.HeaderAccept)))
+	if codec == contentcodec.Json {
+		JsonWithFailure(ctx, httpCode, errorCode, errorMessage, devMessage)
+		addVaryAccept(ctx)
+		return
+	}
+
+	if httpCode > 199 && httpCode < 300 {
+		logger.Println(fmt.Sprintf("E#3FJLFM - Failure Renderer Called with non-failure HTTP code: %v", httpCode))
+	}
+
+	devMsg := allowedDevMessage(ctx, devMessage)
+	logger.Println(fmt.Sprintf("%v - %v [::DevMsg::]-> %v", errorCode, errorMessage, devMsg))
+
+	body, err := codec.Marshal(jsonResponseFailure{
+		Code:           errorCode,
+		Message:        errorMessage,
+		DevMsg:         devMsg,
+		StackTrace:     stackTraceLines(ctx),
+		OperationalLog: operationalLog(ctx),
+	})
+	if err != nil {
+		// The failure envelope is made of strings only. This should never happen, but if it does, fall back to JSON
+		logger.Println(fmt.Sprintf("E#3GV4SA - %v marshalling of the failure failed: %v", codec.Name(), err))
+		JsonWithFailure(ctx, httpCode, errorCode, errorMessage, devMessage)
+		return
+	}
+
+	writeEncoded(ctx, codec, httpCode, body)
+}
+
+// WithFailureUsingErrorType works like JsonWithFailureUsingErrorType, but encodes the failure envelope in the
+// format asked for in the `Accept` header of the request
+func WithFailureUsingErrorType(ctx *fasthttp.RequestCtx, errTy appError.Typ) {
+	if errTy.IsBlankNetworkError() {
+		errId := types.GetRandomAlphaString(32)
+		logger.Println(fmt.Sprintf("E#3AK9GW - ErrID: %v, Error: %v @@@@@ DevMsg: %v", errId, errTy, errTy.DevMsg))
+		WithFailure(ctx, 500, "3AK9GW", "Internal error. Error logged with ID "+errId, errTy.DevMsg)
+		return
+	}
+	if problemDetailsWanted(ctx) {
+		ProblemWithFailure(ctx, errTy)
+		return
+	}
+	WithFailure(ctx, errTy.HttpResponseCode, errTy.Code, errTy.Message, errTy.DevMsg)
+}
+
+func writeEncoded(ctx *fasthttp.RequestCtx, codec contentcodec.Codec, httpCode int, body []byte) {
+	addFixedHeaders(ctx)
+	addVaryAccept(ctx)
+	ctx.Response.Header.Set(fasthttp.HeaderContentType, codec.ContentType())
+	setRequestIdHeader(ctx)
+	ctx.SetStatusCode(httpCode)
+	ctx.Response.SetBody(body)
+}
+
+// addVaryAccept tells the caches that the response depends on the Accept header of the request
+func addVaryAccept(ctx *fasthttp.RequestCtx) {
+	ctx.Response.Header.Set(fasthttp.HeaderVary,
+		compression.AddVary(string(ctx.Response.Header.Peek(fasthttp.HeaderVary)), fasthttp.HeaderAccept))
+}
@@ -0,0 +1,47 @@
+package render
+
+import (
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/techrail/ground/constants/customCtxKey"
+	"github.com/techrail/ground/logger"
+	"github.com/techrail/ground/problem"
+	"github.com/techrail/ground/typs/appError"
+)
+
+// ProblemWithFailure responds with the error as RFC 7807 problem details (application/problem+json)
+func ProblemWithFailure(ctx *fasthttp.RequestCtx, errTy appError.Typ) {
+	addFixedHeaders(ctx)
+	ctx.Response.Header.Set(fasthttp.HeaderContentType, problem.ContentType)
+
+	requestId := setRequestIdHeader(ctx)
+
+	devMsg := allowedDevMessage(ctx, errTy.DevMsg)
+	details := problem.FromAppError(errTy, problem.Options{
+		Instance:       string(ctx.RequestURI()),
+		RequestId:      requestId,
+		IncludeDevMsg:  devMsg != "",
+		IncludeWrapped: devMsg != "",
+		StackTrace:     stackTraceLines(ctx),
+	})
+	if ops := operationalLog(ctx); len(ops) > 0 {
+		details.Extensions["operationalLog"] = ops
+	}
+
+	logger.Println(fmt.Sprintf("%v - %v [::DevMsg::]-> %v", errTy.Code, errTy.Message, devMsg))
+
+	ctx.SetStatusCode(details.Status)
+	ctx.Response.SetBody(details.Bytes())
+}
+
+// problemDetailsWanted tells if the failure should be rendered as problem details. It is the case when the server
+// prefers problem details (see webServer.FastHttpServer.ProblemDetailsFailures) or the client asked for them using
+// the `Accept` header.
+func problemDetailsWanted(ctx *fasthttp.RequestCtx) bool {
+	if preferred, ok := ctx.UserValue(customCtxKey.ProblemDetailsPreferred).(bool); ok && preferred {
+		return true
+	}
+	return problem.Accepted(string(ctx.Request.Header.Peek(fasthttp.HeaderAccept)))
+}
@@ -0,0 +1,155 @@
+package render
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/techrail/ground/constants/httpheaders"
+	"github.com/techrail/ground/contentcodec"
+)
+
+// newRequestCtx creates the context of a request with the given Accept header
+func newRequestCtx(accept string) *fasthttp.RequestCtx {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.SetRequestURI("/items")
+	if accept != "" {
+		ctx.Request.Header.Set(fasthttp.HeaderAccept, accept)
+	}
+	return ctx
+}
+
+// varies tells if the Vary header lists the header
+func varies(vary, header string) bool {
+	for _, h := range strings.Split(vary, ",") {
+		if strings.EqualFold(strings.TrimSpace(h), header) {
+			return true
+		}
+	}
+	return false
+}
+
+type item struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func TestStructWithSuccess_Negotiation(t *testing.T) {
+	cases := []struct {
+		accept  string
+		payload any
+		codec   contentcodec.Codec
+	}{
+		{"", item{Name: "a"}, contentcodec.Json},
+		{"text/html", item{Name: "a"}, contentcodec.Json},
+		{"application/msgpack", item{Name: "a"}, contentcodec.MsgPack},
+		{"application/cbor", item{Name: "a"}, contentcodec.Cbor},
+		{"application/xml", item{Name: "a"}, contentcodec.Xml},
+		// A map can not be encoded as XML, and a struct which is not a proto.Message as protobuf
+		{"application/xml", map[string]string{"name": "a"}, contentcodec.Json},
+		{"application/x-protobuf", item{Name: "a"}, contentcodec.Json},
+	}
+	for _, c := range cases {
+		ctx := newRequestCtx(c.accept)
+		ctx.Response.Header.Set(fasthttp.HeaderVary, fasthttp.HeaderAcceptEncoding)
+		StructWithSuccess(ctx, http.StatusOK, c.payload)
+
+		if ct := string(ctx.Response.Header.ContentType()); ct != c.codec.ContentType() {
+			t.Errorf("E#3HA3IA - For `%v` expected %v, got the content type %v", c.accept, c.codec.Name(), ct)
+			continue
+		}
+		if vary := string(ctx.Response.Header.Peek(fasthttp.HeaderVary)); !varies(vary, fasthttp.HeaderAcceptEncoding) ||
+			!varies(vary, fasthttp.HeaderAccept) {
+			t.Errorf("E#3D019Y - For `%v` expected Vary to list Accept-Encoding and Accept, got %v", c.accept, vary)
+		}
+		if c.codec == contentcodec.Xml {
+			if body := string(ctx.Response.Body()); !strings.Contains(body, "<name>a</name>") {
+				t.Errorf("E#3FGKGU - Unexpected XML body %v", body)
+			}
+			continue
+		}
+		var got struct {
+			Data map[string]any `json:"data"`
+		}
+		if err := c.codec.Unmarshal(ctx.Response.Body(), &got); err != nil || got.Data["name"] != "a" {
+			t.Errorf("E#3AHKFA - For `%v` the body decoded to %v, %v", c.accept, got, err)
+		}
+	}
+}
+
+func TestStructWithSuccess_Protobuf(t *testing.T) {
+	ctx := newRequestCtx("application/x-protobuf")
+	StructWithSuccess(ctx, http.StatusCreated, wrapperspb.String("ground"))
+
+	var got wrapperspb.StringValue
+	if err := proto.Unmarshal(ctx.Response.Body(), &got); err != nil || got.GetValue() != "ground" {
+		t.Errorf("E#3D05V6 - Expected the proto.Message without the envelope, got %v, %v", got.GetValue(), err)
+	}
+	if ctx.Response.StatusCode() != http.StatusCreated {
+		t.Errorf("E#3BLU46 - Expected a 201, got %v", ctx.Response.StatusCode())
+	}
+}
+
+func TestWithFailure_Negotiation(t *testing.T) {
+	ctx := newRequestCtx("application/msgpack")
+	WithFailure(ctx, http.StatusBadRequest, "CODE1", "Bad input", "the dev message")
+	var got map[string]any
+	if err := contentcodec.MsgPack.Unmarshal(ctx.Response.Body(), &got); err != nil || got["code"] != "CODE1" ||
+		got["devMsg"] != nil {
+		t.Errorf("E#3FDTO0 - The MessagePack failure decoded to %v, %v", got, err)
+	}
+	if ctx.Response.StatusCode() != http.StatusBadRequest ||
+		string(ctx.Response.Header.Peek(fasthttp.HeaderVary)) != fasthttp.HeaderAccept {
+		t.Errorf("E#3BYXRR - Unexpected status %v or Vary %q", ctx.Response.StatusCode(),
+			ctx.Response.Header.Peek(fasthttp.HeaderVary))
+	}
+
+	// The failure envelope is sent as a google.protobuf.Struct
+	ctx = newRequestCtx("application/x-protobuf")
+	WithFailure(ctx, http.StatusConflict, "CODE2", "Conflict", "")
+	var s structpb.Struct
+	if err := proto.Unmarshal(ctx.Response.Body(), &s); err != nil || s.GetFields()["code"].GetStringValue() != "CODE2" {
+		t.Errorf("E#3FBA30 - The protobuf failure decoded to %v, %v", s.String(), err)
+	}
+
+	ctx = newRequestCtx("image/png")
+	WithFailure(ctx, http.StatusNotFound, "CODE3", "Not found", "")
+	if ct := string(ctx.Response.Header.ContentType()); ct != contentcodec.Json.ContentType() {
+		t.Errorf("E#3D71ZL - Expected the JSON fallback, got the content type %v", ct)
+	}
+}
+
+func TestJsonStructWithSuccess_ETag(t *testing.T) {
+	ctx := newRequestCtx("")
+	JsonStructWithSuccess(ctx, http.StatusOK, item{Name: "a"})
+	etag := string(ctx.Response.Header.Peek(httpheaders.ETag))
+	if etag == "" || ctx.Response.StatusCode() != http.StatusOK {
+		t.Fatalf("E#3HWAIO - Expected a 200 with an ETag, got %v %q", ctx.Response.StatusCode(), etag)
+	}
+
+	ctx = newRequestCtx("")
+	ctx.Request.Header.Set(httpheaders.IfNoneMatch, etag)
+	JsonStructWithSuccess(ctx, http.StatusOK, item{Name: "a"})
+	if ctx.Response.StatusCode() != http.StatusNotModified || len(ctx.Response.Body()) > 0 {
+		t.Errorf("E#3F7VBJ - Expected a 304 without a body, got %v %q", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+
+	ctx = newRequestCtx("")
+	ctx.Request.Header.Set(httpheaders.IfNoneMatch, etag)
+	JsonStructWithSuccess(ctx, http.StatusOK, item{Name: "b"})
+	if ctx.Response.StatusCode() != http.StatusOK {
+		t.Errorf("E#3CA26F - Expected a 200 once the body changed, got %v", ctx.Response.StatusCode())
+	}
+
+	ctx = newRequestCtx("")
+	ctx.Request.Header.SetMethod(http.MethodPost)
+	ctx.Request.Header.Set(httpheaders.IfNoneMatch, etag)
+	JsonStructWithSuccess(ctx, http.StatusOK, item{Name: "a"})
+	if ctx.Response.StatusCode() != http.StatusOK {
+		t.Errorf("E#3BH6NM - Expected a 200 for a POST, got %v", ctx.Response.StatusCode())
+	}
+}
@@ -0,0 +1,97 @@
+// Package requestid holds the request correlation logic shared by the fasthttp (webServer) and the net/http
+// (netserver) flavours of the servers: validating the ID that came in the X-Request-Id header, generating a new one
+// (a ULID) when it is missing or unusable, keeping it in the request context and forwarding it to the downstream
+// services called while serving the request.
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/techrail/ground/constants/customCtxKey"
+	"github.com/techrail/ground/constants/customHeaders"
+	"github.com/techrail/ground/uuid"
+)
+
+// MaxLength is the maximum length of an incoming request ID that is accepted as it is
+const MaxLength = 128
+
+// Valid tells if the incoming request ID can be used as it is. It must be 1 to MaxLength characters long and can
+// contain only letters, digits and `-`, `_`, `.`, `:` (which covers ULIDs, UUIDs and most of the tracing IDs).
+// Anything else (spaces, control characters, quotes...) could be used to forge log lines and is rejected.
+func Valid(id string) bool {
+	if len(id) == 0 || len(id) > MaxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// New returns a new request ID (the string form of a ULID)
+func New() string {
+	return uuid.GetNewUlidAsString()
+}
+
+// FromIncoming returns the incoming request ID if it is valid, or a new one otherwise
+func FromIncoming(incoming string) string {
+	if Valid(incoming) {
+		return incoming
+	}
+	return New()
+}
+
+// NewContext returns a copy of the context carrying the request ID
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, customCtxKey.RequestId, id)
+}
+
+// FromContext returns the request ID stored in the context (blank if there is none). Since *fasthttp.RequestCtx
+// implements context.Context (and returns the user values from Value), it works for both the servers.
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(customCtxKey.RequestId).(string)
+	return id
+}
+
+// Transport is an http.RoundTripper which forwards the request ID found in the context of the outgoing request to
+// the downstream service in the X-Request-Id header. A header already set on the outgoing request is not touched.
+type Transport struct {
+	Base http.RoundTripper // The transport that actually does the work. http.DefaultTransport is used when nil.
+}
+
+// NewTransport returns a Transport wrapping the given one
+func NewTransport(base http.RoundTripper) *Transport {
+	return &Transport{Base: base}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(rq *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	id := FromContext(rq.Context())
+	if id == "" || rq.Header.Get(customHeaders.RequestId) != "" {
+		return base.RoundTrip(rq)
+	}
+	// A RoundTripper must not modify the request it was given
+	rq = rq.Clone(rq.Context())
+	rq.Header.Set(customHeaders.RequestId, id)
+	return base.RoundTrip(rq)
+}
+
+// NewClient returns an http.Client (with the given timeout; 0 means none) whose requests carry the request ID
+func NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: NewTransport(nil), Timeout: timeout}
+}
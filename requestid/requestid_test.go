@@ -0,0 +1,53 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/techrail/ground/constants/customHeaders"
+)
+
+func TestValid(t *testing.T) {
+	cases := map[string]bool{
+		"":                                     false,
+		"01HZX3K5Q8V7ZB6M3N2P1R0STU":           true,
+		"9f1c6d2e-8a47-4b8e-9c1d-2f3a4b5c6d7e": true,
+		"trace:abc.def_1":                      true,
+		"has space":                            false,
+		"line\nbreak":                          false,
+		strings.Repeat("a", MaxLength+1):       false,
+	}
+	for id, want := range cases {
+		if got := Valid(id); got != want {
+			t.Errorf("E#3HQTHF - Valid(%q) = %v; want %v", id, got, want)
+		}
+	}
+	if !Valid(New()) {
+		t.Errorf("E#3C18RI - A generated request ID is not valid")
+	}
+}
+
+func TestTransportForwardsRequestId(t *testing.T) {
+	received := make(chan string, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
+		received <- rq.Header.Get(customHeaders.RequestId)
+	}))
+	defer srv.Close()
+
+	client := NewClient(0)
+	rq, _ := http.NewRequestWithContext(NewContext(context.Background(), "abc-123"), http.MethodGet, srv.URL, nil)
+	resp, err := client.Do(rq)
+	if err != nil {
+		t.Fatalf("E#3CQMDH - Request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if got := <-received; got != "abc-123" {
+		t.Errorf("E#3GSR3F - Downstream got request ID %q; want %q", got, "abc-123")
+	}
+	if rq.Header.Get(customHeaders.RequestId) != "" {
+		t.Errorf("E#3AVCXC - The transport modified the original request")
+	}
+}
@@ -0,0 +1,208 @@
+package resilience
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/techrail/ground/health"
+	"github.com/techrail/ground/typs/appError"
+)
+
+// State of a circuit breaker
+type State int
+
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// BreakerConfig configures a circuit breaker. A breaker opens after FailureThreshold consecutive failures and rejects
+// the calls for OpenDuration. After that, up to HalfOpenProbes calls are let through; the breaker closes if one of
+// them succeeds and opens again if one of them fails.
+type BreakerConfig struct {
+	Name             string // Used in the error messages and as the name of the health check (none if blank)
+	FailureThreshold int    // 0 disables the breaker
+	OpenDuration     time.Duration
+	HalfOpenProbes   int
+}
+
+// Breaker is a circuit breaker. It is safe for concurrent use.
+type Breaker struct {
+	cfg      BreakerConfig
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probes   int
+	now      func() time.Time
+}
+
+// NewBreaker creates a breaker. If the configuration has a name, the breaker is registered in the health registry.
+func NewBreaker(cfg BreakerConfig) *Breaker {
+	b := &Breaker{cfg: cfg, now: time.Now}
+	if cfg.Name != "" && cfg.FailureThreshold > 0 {
+		health.Register(cfg.Name, func(ctx context.Context) health.Result {
+			return health.Result{Status: b.State().healthStatus(), Details: b.details()}
+		})
+	}
+	return b
+}
+
+// Allow tells if a call can be made now. Every allowed call must be followed by a call to Record.
+func (b *Breaker) Allow() appError.Typ {
+	if b == nil || b.cfg.FailureThreshold <= 0 {
+		return appError.BlankError
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case Open:
+		if b.now().Sub(b.openedAt) < b.cfg.OpenDuration {
+			return b.openError()
+		}
+		b.state = HalfOpen
+		b.probes = 0
+		fallthrough
+	case HalfOpen:
+		if b.probes >= max(b.cfg.HalfOpenProbes, 1) {
+			return b.openError()
+		}
+		b.probes++
+	}
+	return appError.BlankError
+}
+
+// Record records the result of an allowed call
+func (b *Breaker) Record(success bool) {
+	if b == nil || b.cfg.FailureThreshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if success {
+		b.state = Closed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == HalfOpen || b.failures >= b.cfg.FailureThreshold {
+		b.state = Open
+		b.openedAt = b.now()
+	}
+}
+
+// Release gives back an allowed call without recording its result, e.g. when the caller cancelled it (which tells
+// nothing about the health of the protected system)
+func (b *Breaker) Release() {
+	if b == nil || b.cfg.FailureThreshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == HalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// State returns the current state of the breaker. An open breaker whose open duration is over is reported as
+// half-open.
+func (b *Breaker) State() State {
+	if b == nil {
+		return Closed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == Open && b.now().Sub(b.openedAt) >= b.cfg.OpenDuration {
+		return HalfOpen
+	}
+	return b.state
+}
+
+func (b *Breaker) openError() appError.Typ {
+	return appError.NewError(appError.Error, CodeBreakerOpen, fmt.Sprintf("Circuit breaker %v is open", b.cfg.Name))
+}
+
+func (b *Breaker) details() map[string]any {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return map[string]any{"consecutiveFailures": b.failures}
+}
+
+func (s State) healthStatus() health.Status {
+	switch s {
+	case Open:
+		return health.StatusDown
+	case HalfOpen:
+		return health.StatusDegraded
+	default:
+		return health.StatusUp
+	}
+}
+
+// BreakerSet keeps one breaker per key (e.g. per host). If the configuration has a name, the set is registered in
+// the health registry as a single check which is degraded while any of its breakers is not closed.
+type BreakerSet struct {
+	cfg      BreakerConfig
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+// NewBreakerSet creates a set of breakers sharing the configuration
+func NewBreakerSet(cfg BreakerConfig) *BreakerSet {
+	s := &BreakerSet{cfg: cfg, breakers: map[string]*Breaker{}}
+	if cfg.Name != "" && cfg.FailureThreshold > 0 {
+		health.Register(cfg.Name, s.healthCheck)
+	}
+	return s
+}
+
+// ForKey returns the breaker of the key (creating it if needed)
+func (s *BreakerSet) ForKey(key string) *Breaker {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.breakers[key]
+	if !ok {
+		cfg := s.cfg
+		cfg.Name = s.cfg.Name + "[" + key + "]"
+		// The breakers of the set are not registered on their own
+		b = &Breaker{cfg: cfg, now: time.Now}
+		s.breakers[key] = b
+	}
+	return b
+}
+
+func (s *BreakerSet) healthCheck(ctx context.Context) health.Result {
+	s.mu.Lock()
+	keys := make([]string, 0, len(s.breakers))
+	for k := range s.breakers {
+		keys = append(keys, k)
+	}
+	s.mu.Unlock()
+	sort.Strings(keys)
+
+	status := health.StatusUp
+	details := map[string]any{}
+	for _, k := range keys {
+		state := s.ForKey(k).State()
+		if state != Closed {
+			status = health.StatusDegraded
+			details[k] = state.String()
+		}
+	}
+	return health.Result{Status: status, Details: details}
+}
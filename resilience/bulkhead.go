@@ -0,0 +1,73 @@
+package resilience
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/techrail/ground/typs/appError"
+)
+
+// Bulkhead limits the number of concurrent calls. It is safe for concurrent use.
+type Bulkhead struct {
+	name    string
+	slots   chan struct{}
+	maxWait time.Duration
+}
+
+// NewBulkhead creates a bulkhead letting maxConcurrent calls in at the same time. A call waits up to maxWait for a
+// free slot. A maxConcurrent of 0 (or less) means no limit.
+func NewBulkhead(name string, maxConcurrent int, maxWait time.Duration) *Bulkhead {
+	b := &Bulkhead{name: name, maxWait: maxWait}
+	if maxConcurrent > 0 {
+		b.slots = make(chan struct{}, maxConcurrent)
+	}
+	return b
+}
+
+// Acquire takes a slot. Every successful Acquire must be followed by a call to Release.
+func (b *Bulkhead) Acquire(ctx context.Context) appError.Typ {
+	if b == nil || b.slots == nil {
+		return appError.BlankError
+	}
+	select {
+	case b.slots <- struct{}{}:
+		return appError.BlankError
+	default:
+	}
+	if b.maxWait <= 0 {
+		return b.fullError()
+	}
+
+	timer := time.NewTimer(b.maxWait)
+	defer timer.Stop()
+	select {
+	case b.slots <- struct{}{}:
+		return appError.BlankError
+	case <-timer.C:
+		return b.fullError()
+	case <-ctx.Done():
+		return appError.NewError(appError.Error, CodeCancelled,
+			fmt.Sprintf("Cancelled while waiting for bulkhead %v: %v", b.name, ctx.Err()))
+	}
+}
+
+// Release gives the slot back
+func (b *Bulkhead) Release() {
+	if b == nil || b.slots == nil {
+		return
+	}
+	<-b.slots
+}
+
+// InFlight returns the number of slots taken
+func (b *Bulkhead) InFlight() int {
+	if b == nil || b.slots == nil {
+		return 0
+	}
+	return len(b.slots)
+}
+
+func (b *Bulkhead) fullError() appError.Typ {
+	return appError.NewError(appError.Error, CodeBulkheadFull, fmt.Sprintf("Bulkhead %v is full", b.name))
+}
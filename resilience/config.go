@@ -0,0 +1,30 @@
+// Package resilience has the primitives used to protect the calls made to other systems (HTTP services, caches,
+// databases): a circuit breaker, a concurrency limiting bulkhead, a timeout wrapper and a retry policy. They can be
+// used on their own or combined in a Policy. Every rejection is an appError.Typ with one of the Code* codes, so that
+// the callers can tell them apart from the errors of the protected call.
+package resilience
+
+import "time"
+
+const (
+	CodeBreakerOpen  = "3FHEJE" // The circuit breaker is open
+	CodeBulkheadFull = "3BL2KL" // No bulkhead slot became free in time
+	CodeTimeout      = "3B4I4K" // The call did not finish in time
+	CodeCancelled    = "3EVY4T" // The context was cancelled while waiting (for a slot or before a retry)
+	CodeCallFailed   = "3FSV7Y" // The protected function returned a plain error (see Policy.Run)
+)
+
+// Config of a Policy. A zero value part is disabled, so the zero Config protects nothing.
+type Config struct {
+	Name          string        // Used in the error messages and as the name of the health check (none if blank)
+	Breaker       BreakerConfig // Circuit breaker
+	MaxConcurrent int           // Bulkhead: maximum number of calls in flight (0 disables the bulkhead)
+	MaxWait       time.Duration // Bulkhead: how long a call waits for a free slot before being rejected
+	Timeout       time.Duration // Timeout of every attempt (0 disables the timeout)
+	Retry         RetryPolicy   // Retries of the failed attempts
+}
+
+// Enabled tells if any part of the configuration is enabled
+func (c Config) Enabled() bool {
+	return c.Breaker.FailureThreshold > 0 || c.MaxConcurrent > 0 || c.Timeout > 0 || c.Retry.MaxRetries > 0
+}
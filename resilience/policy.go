@@ -0,0 +1,107 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/techrail/ground/health"
+	"github.com/techrail/ground/typs/appError"
+)
+
+// Policy combines the primitives: every attempt goes through the bulkhead, the circuit breaker and the timeout and
+// the failed attempts are retried as per the retry policy. A nil *Policy runs the functions as they are, so that an
+// optional policy can be used without checks. It is safe for concurrent use.
+type Policy struct {
+	cfg      Config
+	breaker  *Breaker
+	bulkhead *Bulkhead
+}
+
+// NewPolicy creates a policy. If the configuration has a name, the policy is registered in the health registry.
+func NewPolicy(cfg Config) *Policy {
+	if cfg.Breaker.Name == "" {
+		cfg.Breaker.Name = cfg.Name
+	}
+	p := &Policy{
+		cfg: cfg,
+		// The policy registers itself (covering the breaker and the bulkhead), so the breaker is not registered
+		breaker:  &Breaker{cfg: cfg.Breaker, now: time.Now},
+		bulkhead: NewBulkhead(cfg.Name, cfg.MaxConcurrent, cfg.MaxWait),
+	}
+	if cfg.Name != "" {
+		health.Register(cfg.Name, p.healthCheck)
+	}
+	return p
+}
+
+// Execute runs the function through the policy. A call cancelled by the caller (its context is cancelled) is not
+// counted as a failure by the circuit breaker.
+func (p *Policy) Execute(ctx context.Context, fn func(ctx context.Context) appError.Typ) appError.Typ {
+	if p == nil {
+		return fn(ctx)
+	}
+	return p.cfg.Retry.Do(ctx, func(ctx context.Context) appError.Typ {
+		if errTy := p.bulkhead.Acquire(ctx); errTy.IsNotBlank() {
+			return errTy
+		}
+		defer p.bulkhead.Release()
+
+		if errTy := p.breaker.Allow(); errTy.IsNotBlank() {
+			return errTy
+		}
+		errTy := WithTimeout(ctx, p.cfg.Timeout, fn)
+		if errTy.IsNotBlank() && errors.Is(ctx.Err(), context.Canceled) {
+			// The caller gave up: the call is neither a success nor a failure of the protected system
+			p.breaker.Release()
+			return errTy
+		}
+		p.breaker.Record(errTy.IsBlank())
+		return errTy
+	})
+}
+
+// Run works like Execute for a function returning a plain error. The errors for which `expected` returns true (e.g.
+// "not found" errors) are neither counted as failures nor retried. The error of the function is returned as it is;
+// an appError.Typ is returned only when the policy rejected the call (or the call timed out).
+func (p *Policy) Run(ctx context.Context, fn func(ctx context.Context) error, expected func(err error) bool) error {
+	if p == nil {
+		return fn(ctx)
+	}
+	var fnErr error
+	errTy := p.Execute(ctx, func(ctx context.Context) appError.Typ {
+		fnErr = fn(ctx)
+		if fnErr == nil || (expected != nil && expected(fnErr)) {
+			return appError.BlankError
+		}
+		return appError.NewError(appError.Error, CodeCallFailed, fmt.Sprintf("%v call failed: %v", p.cfg.Name, fnErr))
+	})
+	if errTy.IsBlank() || errTy.Code == CodeCallFailed {
+		return fnErr
+	}
+	return errTy
+}
+
+// State returns the state of the circuit breaker of the policy
+func (p *Policy) State() State {
+	if p == nil {
+		return Closed
+	}
+	return p.breaker.State()
+}
+
+func (p *Policy) healthCheck(ctx context.Context) health.Result {
+	state := p.breaker.State()
+	status := state.healthStatus()
+	if status == health.StatusUp && p.cfg.MaxConcurrent > 0 && p.bulkhead.InFlight() >= p.cfg.MaxConcurrent {
+		status = health.StatusDegraded
+	}
+	return health.Result{
+		Status: status,
+		Details: map[string]any{
+			"breaker":  state.String(),
+			"inFlight": p.bulkhead.InFlight(),
+		},
+	}
+}
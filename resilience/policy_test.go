@@ -0,0 +1,127 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/techrail/ground/health"
+	"github.com/techrail/ground/typs/appError"
+)
+
+var errTest = appError.NewError(appError.Error, "3HJ6T9", "Test failure")
+
+func TestBreakerStates(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker(BreakerConfig{FailureThreshold: 2, OpenDuration: time.Minute, HalfOpenProbes: 1})
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if errTy := b.Allow(); errTy.IsNotBlank() {
+			t.Fatalf("E#3GVAZV - Closed breaker rejected the call: %v", errTy)
+		}
+		b.Record(false)
+	}
+	if b.State() != Open || b.Allow().Code != CodeBreakerOpen {
+		t.Fatalf("E#3A72BH - Breaker did not open after the failures (state: %v)", b.State())
+	}
+
+	now = now.Add(time.Minute)
+	if b.State() != HalfOpen || b.Allow().IsNotBlank() {
+		t.Fatalf("E#3C84AK - Breaker did not let the probe through (state: %v)", b.State())
+	}
+	if b.Allow().Code != CodeBreakerOpen {
+		t.Errorf("E#3APE0N - Breaker let more than one probe through")
+	}
+	b.Record(true)
+	if b.State() != Closed {
+		t.Errorf("E#3G204R - Breaker did not close after a successful probe (state: %v)", b.State())
+	}
+}
+
+func TestBulkheadLimitsConcurrency(t *testing.T) {
+	p := NewPolicy(Config{MaxConcurrent: 1})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = p.Execute(context.Background(), func(ctx context.Context) appError.Typ {
+			close(started)
+			<-release
+			return appError.BlankError
+		})
+	}()
+	<-started
+
+	errTy := p.Execute(context.Background(), func(ctx context.Context) appError.Typ {
+		return appError.BlankError
+	})
+	close(release)
+	wg.Wait()
+	if errTy.Code != CodeBulkheadFull {
+		t.Errorf("E#3D0VYM - Got %v; want a full bulkhead", errTy)
+	}
+}
+
+func TestTimeoutAndRetries(t *testing.T) {
+	attempts := 0
+	p := NewPolicy(Config{
+		Timeout: 10 * time.Millisecond,
+		Retry:   RetryPolicy{MaxRetries: 2, BackoffBase: time.Millisecond, BackoffMax: time.Millisecond},
+	})
+	errTy := p.Execute(context.Background(), func(ctx context.Context) appError.Typ {
+		attempts++
+		<-ctx.Done()
+		return errTest
+	})
+	if errTy.Code != CodeTimeout || attempts != 3 {
+		t.Errorf("E#3EAEVF - Got %v after %v attempts; want a timeout after 3 attempts", errTy, attempts)
+	}
+}
+
+func TestRunKeepsExpectedErrors(t *testing.T) {
+	errNotFound := errors.New("not found")
+	p := NewPolicy(Config{Name: "resilience-test", Breaker: BreakerConfig{FailureThreshold: 1, OpenDuration: time.Minute}})
+	defer health.Unregister("resilience-test")
+
+	err := p.Run(context.Background(), func(ctx context.Context) error { return errNotFound },
+		func(err error) bool { return errors.Is(err, errNotFound) })
+	if !errors.Is(err, errNotFound) || p.State() != Closed {
+		t.Fatalf("E#3ASR7R - Got %v (state %v); want the expected error and a closed breaker", err, p.State())
+	}
+
+	errBroken := errors.New("broken")
+	if err = p.Run(context.Background(), func(ctx context.Context) error { return errBroken }, nil); !errors.Is(err, errBroken) {
+		t.Errorf("E#3FOAOL - Got %v; want the error of the function", err)
+	}
+	var errTy appError.Typ
+	err = p.Run(context.Background(), func(ctx context.Context) error { return nil }, nil)
+	if !errors.As(err, &errTy) || errTy.Code != CodeBreakerOpen {
+		t.Errorf("E#3H2YQ1 - Got %v; want an open breaker", err)
+	}
+
+	results := health.Check(context.Background())
+	if health.Overall(results) != health.StatusDown {
+		t.Errorf("E#3BIVTX - Health did not report the open breaker: %+v", results)
+	}
+}
+
+func TestCancelledCallsAreNotFailures(t *testing.T) {
+	p := NewPolicy(Config{Breaker: BreakerConfig{FailureThreshold: 1, OpenDuration: time.Minute}})
+	ctx, cancel := context.WithCancel(context.Background())
+	err := p.Run(ctx, func(ctx context.Context) error {
+		cancel()
+		<-ctx.Done()
+		return ctx.Err()
+	}, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("E#3FM0VJ - Expected the cancellation error, got %v", err)
+	}
+	if p.State() != Closed {
+		t.Errorf("E#3BPUNX - A call cancelled by the caller opened the breaker")
+	}
+}
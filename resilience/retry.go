@@ -0,0 +1,78 @@
+package resilience
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/techrail/ground/typs/appError"
+)
+
+// RetryPolicy describes the retries of a failed call: up to MaxRetries retries, with exponential backoff (starting
+// at BackoffBase, capped to BackoffMax) and full jitter.
+type RetryPolicy struct {
+	MaxRetries  int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// Retryable tells if the error can be retried. When nil, every error except the rejections of this package
+	// (open breaker, full bulkhead, cancelled context) is retried.
+	Retryable func(errTy appError.Typ) bool
+}
+
+// Backoff returns the time to wait before the retry following the given attempt (counted from 0). A positive
+// override (e.g. the Retry-After value sent by an HTTP server) is used instead of the computed backoff; it is capped
+// to BackoffMax as well.
+func (r RetryPolicy) Backoff(attempt int, override time.Duration) time.Duration {
+	if override > 0 {
+		return min(override, r.BackoffMax)
+	}
+	ceiling := r.BackoffBase << min(attempt, 30)
+	if ceiling <= 0 || ceiling > r.BackoffMax {
+		ceiling = r.BackoffMax
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// Do runs the function and retries it while it fails with a retryable error. The error of the last attempt is
+// returned.
+func (r RetryPolicy) Do(ctx context.Context, fn func(ctx context.Context) appError.Typ) appError.Typ {
+	for attempt := 0; ; attempt++ {
+		errTy := fn(ctx)
+		if errTy.IsBlank() || attempt >= r.MaxRetries || !r.retryable(errTy) || ctx.Err() != nil {
+			return errTy
+		}
+		if waitErr := Sleep(ctx, r.Backoff(attempt, 0)); waitErr.IsNotBlank() {
+			return waitErr.Wrap(errTy)
+		}
+	}
+}
+
+func (r RetryPolicy) retryable(errTy appError.Typ) bool {
+	if r.Retryable != nil {
+		return r.Retryable(errTy)
+	}
+	switch errTy.Code {
+	case CodeBreakerOpen, CodeBulkheadFull, CodeCancelled:
+		return false
+	}
+	return true
+}
+
+// Sleep waits for the duration or until the context is done (in which case an error with CodeCancelled is returned)
+func Sleep(ctx context.Context, d time.Duration) appError.Typ {
+	if d <= 0 {
+		return appError.BlankError
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return appError.BlankError
+	case <-ctx.Done():
+		return appError.NewError(appError.Error, CodeCancelled, fmt.Sprintf("Cancelled while waiting: %v", ctx.Err()))
+	}
+}
@@ -0,0 +1,26 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/techrail/ground/typs/appError"
+)
+
+// WithTimeout runs the function with a context which is cancelled after the timeout (no timeout if it is 0 or
+// less). The function must respect the context. If the function fails after the timeout expired, the returned error
+// has the CodeTimeout code and wraps the error of the function.
+func WithTimeout(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) appError.Typ) appError.Typ {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	errTy := fn(ctx)
+	if errTy.IsNotBlank() && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return appError.NewError(appError.Error, CodeTimeout, fmt.Sprintf("Call did not finish in %v", timeout), errTy)
+	}
+	return errTy
+}
@@ -0,0 +1,232 @@
+// Package respcache implements the server side caching of responses used by the response caching middlewares of
+// both the fasthttp (webServer) and the net/http (netserver) flavours of the servers, and the ETag and conditional
+// request (If-None-Match, If-Modified-Since) handling which the renderers use as well.
+package respcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/techrail/ground/constants/customHeaders"
+	"github.com/techrail/ground/constants/httpheaders"
+)
+
+// CacheStatusHeader tells if the response was served from the cache (HIT) or not (MISS)
+const CacheStatusHeader = "X-Cache"
+
+// Entry is a cached response
+type Entry struct {
+	Status       int                 `json:"status"`
+	Header       map[string][]string `json:"header,omitempty"`
+	Body         []byte              `json:"body,omitempty"`
+	ETag         string              `json:"etag"`
+	LastModified time.Time           `json:"lastModified"`
+	StoredAt     time.Time           `json:"storedAt"`
+	// Shared tells if the response allowed to be shared (Cache-Control public or s-maxage), in which case it is also
+	// served to the authenticated requests
+	Shared bool `json:"shared,omitempty"`
+}
+
+// Config of the middleware
+type Config struct {
+	Store       Store
+	KeyPrefix   string
+	TTL         time.Duration            // Default time to live of the entries
+	RouteTTL    map[string]time.Duration // TTL per path; a key ending in `*` matches the paths starting with the rest
+	VaryHeaders []string                 // Request headers which are part of the cache key (e.g. Accept, Accept-Language)
+	MaxBodySize int                      // Responses with bigger bodies are not cached
+}
+
+// DefaultConfig returns the default configuration using the given store
+func DefaultConfig(store Store) Config {
+	return Config{
+		Store:       store,
+		KeyPrefix:   "respcache:",
+		TTL:         time.Minute,
+		VaryHeaders: []string{httpheaders.Accept, httpheaders.AcceptEncoding},
+		MaxBodySize: 1 << 20,
+	}
+}
+
+// Applies tells if the request can be served from (and stored in) the cache. Only GET and HEAD requests which did
+// not ask to bypass the caches are.
+func Applies(method, cacheControl string) bool {
+	if method != http.MethodGet && method != http.MethodHead {
+		return false
+	}
+	return !hasDirective(cacheControl, "no-cache") && !hasDirective(cacheControl, "no-store")
+}
+
+// TTLFor returns the time to live of the responses of the path (0 means the path is not cached)
+func (c Config) TTLFor(path string) time.Duration {
+	if ttl, ok := c.RouteTTL[path]; ok {
+		return ttl
+	}
+	longest := -1
+	ttl := c.TTL
+	for route, routeTtl := range c.RouteTTL {
+		prefix, isPrefix := strings.CutSuffix(route, "*")
+		if isPrefix && strings.HasPrefix(path, prefix) && len(prefix) > longest {
+			longest = len(prefix)
+			ttl = routeTtl
+		}
+	}
+	return ttl
+}
+
+// Authenticated tells if the request carries credentials (an Authorization or a Cookie header). The responses to
+// these requests are personal unless they say otherwise (see Cacheable) and they are only served the shared entries.
+func Authenticated(header func(name string) string) bool {
+	return header(httpheaders.Authorization) != "" || header(httpheaders.Cookie) != ""
+}
+
+// Servable tells if the entry can be served to a request (see Authenticated)
+func (e Entry) Servable(authenticated bool) bool {
+	return !authenticated || e.Shared
+}
+
+// Key returns the cache key of the request. HEAD requests share the entries of the GET requests. The query
+// parameters are sorted, so that their order does not matter.
+func (c Config) Key(path, rawQuery string, header func(name string) string) string {
+	h := sha256.New()
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	if values, err := url.ParseQuery(rawQuery); err == nil {
+		h.Write([]byte(values.Encode()))
+	} else {
+		h.Write([]byte(rawQuery))
+	}
+	for _, name := range c.VaryHeaders {
+		h.Write([]byte{0})
+		h.Write([]byte(header(name)))
+	}
+	return c.KeyPrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+// Cacheable tells if a response can be stored: only 200 responses which did not opt out (Cache-Control no-store or
+// private), do not set cookies and are not too big are. As per RFC 9111 (section 3.5), the responses to the
+// authenticated requests are stored only if they are explicitly shared (Cache-Control public or s-maxage).
+func (c Config) Cacheable(status int, header http.Header, bodySize int, authenticated bool) bool {
+	if status != http.StatusOK || (c.MaxBodySize > 0 && bodySize > c.MaxBodySize) {
+		return false
+	}
+	if header.Get(httpheaders.SetCookie) != "" {
+		return false
+	}
+	cc := header.Get(httpheaders.CacheControl)
+	if hasDirective(cc, "no-store") || hasDirective(cc, "private") {
+		return false
+	}
+	return !authenticated || shared(cc)
+}
+
+// shared tells if the Cache-Control header of a response allows the shared caches to serve it to any request
+func shared(cacheControl string) bool {
+	return hasDirective(cacheControl, "public") || hasDirective(cacheControl, "s-maxage")
+}
+
+// StrongETag returns a strong entity tag for the body
+func StrongETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// NotModified tells if a GET or HEAD request with the given If-None-Match and If-Modified-Since headers can be
+// answered with a 304 for a response with the given ETag and last modification time. As per RFC 9110, the
+// If-Modified-Since header is ignored when If-None-Match is present.
+func NotModified(ifNoneMatch, ifModifiedSince, etag string, lastModified time.Time) bool {
+	if ifNoneMatch != "" {
+		return etagMatches(ifNoneMatch, etag)
+	}
+	if ifModifiedSince == "" || lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// etagMatches does the weak comparison of the tags listed in If-None-Match with the ETag
+func etagMatches(ifNoneMatch, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// NotModifiedHeaders are the headers of the full response that are kept in a 304 response
+var NotModifiedHeaders = []string{
+	httpheaders.CacheControl, httpheaders.ContentLocation, httpheaders.Date, httpheaders.ETag, httpheaders.Expires,
+	httpheaders.Vary, httpheaders.LastModified,
+}
+
+// KeepInNotModified tells if the header is kept in a 304 response
+func KeepInNotModified(name string) bool {
+	return slices.ContainsFunc(NotModifiedHeaders, func(h string) bool { return strings.EqualFold(h, name) })
+}
+
+// skippedHeaders are not stored in the entries (they are set again when the entry is served, the request ID being
+// the one of the request served)
+var skippedHeaders = []string{
+	httpheaders.Connection, httpheaders.ContentLength, httpheaders.Date, httpheaders.TransferEncoding,
+	CacheStatusHeader, customHeaders.RequestId,
+}
+
+// NewEntry builds the entry for a response. The ETag and the Last-Modified headers of the response are used when
+// present; otherwise a strong ETag is computed and the current time is used.
+func NewEntry(status int, header http.Header, body []byte) Entry {
+	e := Entry{
+		Status:   status,
+		Header:   map[string][]string{},
+		Body:     body,
+		ETag:     header.Get(httpheaders.ETag),
+		StoredAt: time.Now().UTC(),
+		Shared:   shared(header.Get(httpheaders.CacheControl)),
+	}
+	for name, values := range header {
+		if !slices.ContainsFunc(skippedHeaders, func(h string) bool { return strings.EqualFold(h, name) }) {
+			e.Header[name] = values
+		}
+	}
+	if e.ETag == "" {
+		e.ETag = StrongETag(body)
+		e.Header[httpheaders.ETag] = []string{e.ETag}
+	}
+	if lm, err := http.ParseTime(header.Get(httpheaders.LastModified)); err == nil {
+		e.LastModified = lm
+	} else {
+		e.LastModified = e.StoredAt
+		e.Header[httpheaders.LastModified] = []string{e.StoredAt.Format(http.TimeFormat)}
+	}
+	return e
+}
+
+// Age returns the value of the Age header for the entry
+func (e Entry) Age() string {
+	return strconv.FormatInt(max(int64(time.Since(e.StoredAt)/time.Second), 0), 10)
+}
+
+func hasDirective(cacheControl, directive string) bool {
+	for _, part := range strings.Split(cacheControl, ",") {
+		name, _, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if strings.EqualFold(name, directive) {
+			return true
+		}
+	}
+	return false
+}
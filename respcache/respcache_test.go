@@ -0,0 +1,87 @@
+package respcache
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestKeyAndTTL(t *testing.T) {
+	cfg := DefaultConfig(NewMemoryStore(0))
+	cfg.RouteTTL = map[string]time.Duration{"/users/*": time.Hour, "/users/me": 0, "/users/admin/*": time.Second}
+	header := func(name string) string { return "" }
+
+	if cfg.Key("/users", "a=1&b=2", header) != cfg.Key("/users", "b=2&a=1", header) {
+		t.Errorf("E#3DK7SS - The order of the query parameters changed the key")
+	}
+	if cfg.Key("/users", "a=1", header) == cfg.Key("/users", "a=2", header) {
+		t.Errorf("E#3C4DLR - Different queries got the same key")
+	}
+	json := cfg.Key("/users", "", func(string) string { return "application/json" })
+	if json == cfg.Key("/users", "", header) {
+		t.Errorf("E#3CCLPI - Different Accept headers got the same key")
+	}
+
+	cases := map[string]time.Duration{
+		"/other": time.Minute, "/users/1": time.Hour, "/users/me": 0, "/users/admin/x": time.Second,
+	}
+	for path, want := range cases {
+		if got := cfg.TTLFor(path); got != want {
+			t.Errorf("E#3CLOG1 - TTL of %v is %v; want %v", path, got, want)
+		}
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	etag := StrongETag([]byte(`{"data":1}`))
+	lastModified := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	if !NotModified(etag, "", etag, lastModified) || !NotModified(`"x", W/`+etag, "", etag, lastModified) ||
+		!NotModified("*", "", etag, lastModified) {
+		t.Errorf("E#3FM28I - A matching If-None-Match did not give a 304")
+	}
+	if NotModified(`"x"`, lastModified.Format(http.TimeFormat), etag, lastModified) {
+		t.Errorf("E#3G4H1A - If-Modified-Since was used although If-None-Match was present")
+	}
+	if !NotModified("", lastModified.Format(http.TimeFormat), etag, lastModified) {
+		t.Errorf("E#3BY8MH - An unmodified response did not give a 304")
+	}
+	if NotModified("", lastModified.Add(-time.Hour).Format(http.TimeFormat), etag, lastModified) {
+		t.Errorf("E#3EK5ZX - A modified response gave a 304")
+	}
+}
+
+func TestStoreAndCacheability(t *testing.T) {
+	cfg := DefaultConfig(NewMemoryStore(2))
+	ctx := context.Background()
+
+	if cfg.Cacheable(http.StatusOK, http.Header{"Cache-Control": {"private"}}, 1, false) ||
+		cfg.Cacheable(http.StatusOK, http.Header{"Set-Cookie": {"a=b"}}, 1, false) ||
+		cfg.Cacheable(http.StatusNotFound, http.Header{}, 1, false) || !cfg.Cacheable(http.StatusOK, http.Header{}, 1, false) {
+		t.Errorf("E#3GX24I - Cacheable gave an unexpected answer")
+	}
+	if cfg.Cacheable(http.StatusOK, http.Header{}, 1, true) ||
+		!cfg.Cacheable(http.StatusOK, http.Header{"Cache-Control": {"public, max-age=60"}}, 1, true) ||
+		!cfg.Cacheable(http.StatusOK, http.Header{"Cache-Control": {"s-maxage=60"}}, 1, true) {
+		t.Errorf("E#3DA8HU - Cacheable gave an unexpected answer for an authenticated request")
+	}
+	if Applies(http.MethodPost, "") || Applies(http.MethodGet, "no-cache") || !Applies(http.MethodHead, "") {
+		t.Errorf("E#3A3MDD - Applies gave an unexpected answer")
+	}
+
+	e := NewEntry(http.StatusOK, http.Header{"Content-Type": {"text/plain"}, "Date": {"today"}}, []byte("hi"))
+	if e.ETag != StrongETag([]byte("hi")) || e.Header["Date"] != nil || e.LastModified.IsZero() {
+		t.Errorf("E#3H1V92 - Unexpected entry %+v", e)
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		_ = cfg.Store.Set(ctx, key, e, time.Minute)
+	}
+	if _, found, _ := cfg.Store.Get(ctx, "a"); found {
+		t.Errorf("E#3GF3R8 - The least recently used entry was not evicted")
+	}
+	_ = cfg.Store.Set(ctx, "d", e, -time.Second)
+	if got, found, _ := cfg.Store.Get(ctx, "d"); found {
+		t.Errorf("E#3AHSIK - Expired entry %+v was returned", got)
+	}
+}
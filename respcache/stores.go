@@ -0,0 +1,192 @@
+package respcache
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/techrail/ground/cache"
+	"github.com/techrail/ground/typs/appError"
+)
+
+// Store keeps the cached responses. The implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the entry stored against the key (and whether there is one)
+	Get(ctx context.Context, key string) (Entry, bool, appError.Typ)
+	// Set stores the entry against the key for the given time
+	Set(ctx context.Context, key string, e Entry, ttl time.Duration) appError.Typ
+	// Delete removes the key
+	Delete(ctx context.Context, key string) appError.Typ
+}
+
+// MemoryStore keeps the entries in memory. When MaxEntries is reached, the least recently used entry is evicted.
+type MemoryStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	lru        *list.List
+}
+
+type memoryEntry struct {
+	key       string
+	entry     Entry
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store holding at most maxEntries entries (0 means no limit)
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	return &MemoryStore{maxEntries: maxEntries, entries: map[string]*list.Element{}, lru: list.New()}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (Entry, bool, appError.Typ) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, found := s.entries[key]
+	if !found {
+		return Entry{}, false, appError.BlankError
+	}
+	me := el.Value.(*memoryEntry)
+	if time.Now().After(me.expiresAt) {
+		s.remove(el)
+		return Entry{}, false, appError.BlankError
+	}
+	s.lru.MoveToFront(el)
+	return me.entry, true, appError.BlankError
+}
+
+func (s *MemoryStore) Set(ctx context.Context, key string, e Entry, ttl time.Duration) appError.Typ {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, found := s.entries[key]; found {
+		el.Value = &memoryEntry{key: key, entry: e, expiresAt: time.Now().Add(ttl)}
+		s.lru.MoveToFront(el)
+		return appError.BlankError
+	}
+	s.entries[key] = s.lru.PushFront(&memoryEntry{key: key, entry: e, expiresAt: time.Now().Add(ttl)})
+	for s.maxEntries > 0 && s.lru.Len() > s.maxEntries {
+		s.remove(s.lru.Back())
+	}
+	return appError.BlankError
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) appError.Typ {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, found := s.entries[key]; found {
+		s.remove(el)
+	}
+	return appError.BlankError
+}
+
+// Len returns the number of entries (including the expired ones not removed yet)
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lru.Len()
+}
+
+// remove drops the element. The lock must be held.
+func (s *MemoryStore) remove(el *list.Element) {
+	s.lru.Remove(el)
+	delete(s.entries, el.Value.(*memoryEntry).key)
+}
+
+// ValkeyStore keeps the entries in valkey
+type ValkeyStore struct {
+	cache *cache.ValkeyCache
+}
+
+// NewValkeyStore creates a store using the valkey cache
+func NewValkeyStore(c *cache.ValkeyCache) *ValkeyStore {
+	return &ValkeyStore{cache: c}
+}
+
+func (s *ValkeyStore) Get(ctx context.Context, key string) (Entry, bool, appError.Typ) {
+	val, err := s.cache.Get(ctx, key)
+	if errors.Is(err, cache.ErrNotFound) {
+		return Entry{}, false, appError.BlankError
+	}
+	if err != nil {
+		return Entry{}, false, appError.NewError(appError.Error, "3F6NEZ", fmt.Sprintf("Could not get %v: %v", key, err))
+	}
+	return decode(val)
+}
+
+func (s *ValkeyStore) Set(ctx context.Context, key string, e Entry, ttl time.Duration) appError.Typ {
+	b, errTy := encode(e)
+	if errTy.IsNotBlank() {
+		return errTy
+	}
+	client := s.cache.Underlying()
+	if err := client.Do(ctx, client.B().Set().Key(key).Value(string(b)).Px(ttl).Build()).Error(); err != nil {
+		return appError.NewError(appError.Error, "3G6YR1", fmt.Sprintf("Could not set %v: %v", key, err))
+	}
+	return appError.BlankError
+}
+
+func (s *ValkeyStore) Delete(ctx context.Context, key string) appError.Typ {
+	client := s.cache.Underlying()
+	if err := client.Do(ctx, client.B().Del().Key(key).Build()).Error(); err != nil {
+		return appError.NewError(appError.Error, "3ALVQN", fmt.Sprintf("Could not delete %v: %v", key, err))
+	}
+	return appError.BlankError
+}
+
+// RedisStore keeps the entries in redis
+type RedisStore struct {
+	client *cache.Client
+}
+
+// NewRedisStore creates a store using the redis client
+func NewRedisStore(c *cache.Client) *RedisStore {
+	return &RedisStore{client: c}
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) (Entry, bool, appError.Typ) {
+	val, found, errTy := s.client.Get(ctx, key)
+	if errTy.IsNotBlank() {
+		return Entry{}, false, appError.NewError(appError.Error, "3CTG9G", fmt.Sprintf("Could not get %v", key), errTy)
+	}
+	if !found {
+		return Entry{}, false, appError.BlankError
+	}
+	return decode(val)
+}
+
+func (s *RedisStore) Set(ctx context.Context, key string, e Entry, ttl time.Duration) appError.Typ {
+	b, errTy := encode(e)
+	if errTy.IsNotBlank() {
+		return errTy
+	}
+	if errTy = s.client.Set(ctx, key, b, ttl); errTy.IsNotBlank() {
+		return appError.NewError(appError.Error, "3AP2DZ", fmt.Sprintf("Could not set %v", key), errTy)
+	}
+	return appError.BlankError
+}
+
+func (s *RedisStore) Delete(ctx context.Context, key string) appError.Typ {
+	if _, errTy := s.client.Delete(ctx, key); errTy.IsNotBlank() {
+		return appError.NewError(appError.Error, "3FBMRG", fmt.Sprintf("Could not delete %v", key), errTy)
+	}
+	return appError.BlankError
+}
+
+func encode(e Entry) ([]byte, appError.Typ) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, appError.NewError(appError.Error, "3FX4T9", fmt.Sprintf("Could not encode the entry: %v", err))
+	}
+	return b, appError.BlankError
+}
+
+func decode(val string) (Entry, bool, appError.Typ) {
+	var e Entry
+	if err := json.Unmarshal([]byte(val), &e); err != nil {
+		return Entry{}, false, appError.NewError(appError.Error, "3BJCZK", fmt.Sprintf("Could not decode the entry: %v", err))
+	}
+	return e, true, appError.BlankError
+}
@@ -0,0 +1,102 @@
+// Package respheaders decides which fixed headers (server identity and security headers) are stamped on the
+// responses sent by both the fasthttp (render, webServer) and the net/http (netserver) flavours of the servers.
+// The policy is driven by the `httpHeaders` section of the config, but it can also be overridden in code.
+package respheaders
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/techrail/ground/config"
+	"github.com/techrail/ground/constants/httpheaders"
+)
+
+// Header is a single header name and value pair
+type Header struct {
+	Name  string
+	Value string
+}
+
+// Policy is the set of headers which are added to the responses
+type Policy struct {
+	Identity []Header // Server identity headers, added by the renderers to every response
+	Security []Header // Security headers, added by the security headers middlewares
+	Hsts     string   // Value of the Strict-Transport-Security header. Only sent over secure connections
+}
+
+var override atomic.Pointer[Policy]
+
+// Override replaces the policy built from the config with the given one. Pass a blank Policy to emit nothing.
+func Override(p Policy) {
+	override.Store(&p)
+}
+
+// ResetOverride goes back to the policy built from the config
+func ResetOverride() {
+	override.Store(nil)
+}
+
+// configPolicy is the policy built from the config, along with the generation of the config it was built from
+type configPolicy struct {
+	generation uint64
+	policy     Policy
+}
+
+var fromConfig atomic.Pointer[configPolicy]
+
+// Current returns the policy in force: the overridden one if Override was called, otherwise the one built from
+// the config. The latter is built once per load of the config.
+func Current() Policy {
+	if p := override.Load(); p != nil {
+		return *p
+	}
+	gen := config.Generation()
+	if c := fromConfig.Load(); c != nil && c.generation == gen {
+		return c.policy
+	}
+	p := FromConfig()
+	fromConfig.Store(&configPolicy{generation: gen, policy: p})
+	return p
+}
+
+// FromConfig builds the policy from the `httpHeaders` section of the config
+func FromConfig() Policy {
+	cfg := config.Store().HttpHeaders
+	p := Policy{}
+
+	if cfg.EmitServerIdentity {
+		p.Identity = appendIfOn(p.Identity, httpheaders.Server, cfg.Server)
+		p.Identity = appendIfOn(p.Identity, httpheaders.XPoweredBy, cfg.PoweredBy)
+	}
+
+	sec := cfg.Security
+	p.Security = appendIfOn(p.Security, httpheaders.ContentSecurityPolicy, sec.ContentSecurityPolicy)
+	p.Security = appendIfOn(p.Security, httpheaders.XFrameOptions, sec.FrameOptions)
+	if sec.ContentTypeNoSniff {
+		p.Security = append(p.Security, Header{Name: httpheaders.XContentTypeOptions, Value: "nosniff"})
+	}
+	p.Security = appendIfOn(p.Security, httpheaders.ReferrerPolicy, sec.ReferrerPolicy)
+	p.Security = appendIfOn(p.Security, "Permissions-Policy", sec.PermissionsPolicy)
+
+	if sec.HstsMaxAgeInSeconds > 0 {
+		hsts := "max-age=" + strconv.FormatInt(sec.HstsMaxAgeInSeconds, 10)
+		if sec.HstsIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if sec.HstsPreload {
+			hsts += "; preload"
+		}
+		p.Hsts = hsts
+	}
+
+	return p
+}
+
+func appendIfOn(headers []Header, name string, value string) []Header {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.EqualFold(value, config.HeaderValueOff) {
+		return headers
+	}
+	return append(headers, Header{Name: name, Value: value})
+}
@@ -0,0 +1,46 @@
+package respheaders
+
+import (
+	"testing"
+
+	"github.com/techrail/ground/constants/httpheaders"
+)
+
+func TestOverride_EmitNothing(t *testing.T) {
+	Override(Policy{})
+	defer ResetOverride()
+
+	p := Current()
+	if len(p.Identity) != 0 || len(p.Security) != 0 || p.Hsts != "" {
+		t.Errorf("E#3F0UF0 - Expected a blank policy, got %+v", p)
+	}
+}
+
+func TestFromConfig_Defaults(t *testing.T) {
+	p := FromConfig()
+	found := false
+	for _, h := range p.Security {
+		if h.Name == httpheaders.XContentTypeOptions && h.Value == "nosniff" {
+			found = true
+		}
+		if h.Name == "Permissions-Policy" {
+			t.Errorf("E#3CYEIA - Permissions-Policy is off by default but was found: %v", h.Value)
+		}
+	}
+	if !found {
+		t.Errorf("E#3AUP2H - Expected X-Content-Type-Options to be set by default")
+	}
+	if p.Hsts != "" {
+		t.Errorf("E#3FAN48 - Did not expect HSTS by default, got %v", p.Hsts)
+	}
+}
+
+func TestCurrent_BuiltOncePerConfigLoad(t *testing.T) {
+	first := Current()
+	if len(first.Security) == 0 {
+		t.Fatalf("E#3BL9ML - Expected the default security headers")
+	}
+	if c := fromConfig.Load(); c == nil || &c.policy.Security[0] != &Current().Security[0] {
+		t.Errorf("E#3AD2BP - Expected the policy built from the config to be reused")
+	}
+}
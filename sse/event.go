@@ -0,0 +1,59 @@
+// Package sse contains the building blocks for Server-Sent Events (text/event-stream) responses. The renderers of
+// both the fasthttp (render) and the net/http (netserver) flavours use it to stream events to the browsers.
+package sse
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const ContentType = "text/event-stream"
+
+// Event is a single Server-Sent Event. Only the Data field is mandatory.
+type Event struct {
+	Id    string        // Sent as the `id` field. The browser sends it back as `Last-Event-ID` when it reconnects
+	Event string        // Sent as the `event` field. Browsers dispatch the event using this name ("message" if blank)
+	Data  string        // Sent as one `data` field per line
+	Retry time.Duration // Sent as the `retry` field (reconnection time) when greater than zero
+}
+
+// Bytes returns the wire representation of the event (including the blank line which terminates the event)
+func (e Event) Bytes() []byte {
+	var b bytes.Buffer
+	if e.Id != "" {
+		b.WriteString("id: ")
+		b.WriteString(singleLine(e.Id))
+		b.WriteByte('\n')
+	}
+	if e.Event != "" {
+		b.WriteString("event: ")
+		b.WriteString(singleLine(e.Event))
+		b.WriteByte('\n')
+	}
+	if e.Retry > 0 {
+		b.WriteString("retry: ")
+		b.WriteString(strconv.FormatInt(e.Retry.Milliseconds(), 10))
+		b.WriteByte('\n')
+	}
+	data := strings.ReplaceAll(e.Data, "\r\n", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: ")
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	b.WriteByte('\n')
+	return b.Bytes()
+}
+
+// commentBytes returns the wire representation of a comment line. Browsers ignore the comments, which makes them
+// useful as heartbeats which keep the proxies from closing an idle connection.
+func commentBytes(text string) []byte {
+	return []byte(": " + singleLine(text) + "\n\n")
+}
+
+// singleLine strips the line breaks, which are not allowed in the id, event and comment fields
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
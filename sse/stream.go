@@ -0,0 +1,188 @@
+package sse
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/techrail/ground/typs/appError"
+)
+
+// Options control the behaviour of a stream
+type Options struct {
+	HeartbeatInterval time.Duration // Interval at which the heartbeat comments are sent. Zero disables heartbeats
+	Retry             time.Duration // Reconnection time advertised to the browser at the start of the stream
+	// OnResume is called before the stream function when the client reconnected with a `Last-Event-ID` header.
+	// Use it to replay the events the client missed.
+	OnResume func(lastEventId string, stream *Stream) appError.Typ
+}
+
+// DefaultOptions returns the options used by the renderers when none are supplied
+func DefaultOptions() Options {
+	return Options{
+		HeartbeatInterval: 15 * time.Second,
+	}
+}
+
+// Stream writes events to a single client. It is safe to use a stream from multiple goroutines.
+type Stream struct {
+	mu          sync.Mutex
+	w           io.Writer
+	flush       func() error
+	lastEventId string
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+// NewStream creates a new stream which writes to w and calls flush after every event. The renderers create the
+// streams, so it is usually not needed to call this function directly.
+func NewStream(w io.Writer, flush func() error, lastEventId string) *Stream {
+	return &Stream{
+		w:           w,
+		flush:       flush,
+		lastEventId: lastEventId,
+		done:        make(chan struct{}),
+	}
+}
+
+// LastEventId returns the value of the `Last-Event-ID` header sent by the client (blank on the first connection)
+func (s *Stream) LastEventId() string {
+	return s.lastEventId
+}
+
+// Done returns a channel which is closed when the client goes away (or the stream is closed)
+func (s *Stream) Done() <-chan struct{} {
+	return s.done
+}
+
+// IsClosed tells if the client went away (or the stream was closed)
+func (s *Stream) IsClosed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close marks the stream as closed. Nothing is written to the stream after it is closed: Close waits for a write
+// in progress to finish, so the writer of the stream can be released once it returns.
+func (s *Stream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.markClosed()
+}
+
+// markClosed closes the done channel. It must be called with the lock held.
+func (s *Stream) markClosed() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+// Send writes the event and flushes it to the client
+func (s *Stream) Send(e Event) appError.Typ {
+	return s.write(e.Bytes())
+}
+
+// SendData sends an event with the given name and data
+func (s *Stream) SendData(event string, data string) appError.Typ {
+	return s.Send(Event{Event: event, Data: data})
+}
+
+// SendJson marshals the value to JSON and sends it as the data of an event with the given name
+func (s *Stream) SendJson(id string, event string, v any) appError.Typ {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return appError.NewError(appError.Error, "3GLH72", fmt.Sprintf("Could not marshal the event data: %v", err))
+	}
+	return s.Send(Event{Id: id, Event: event, Data: string(b)})
+}
+
+// Comment writes a comment line to the stream. Browsers ignore comments.
+func (s *Stream) Comment(text string) appError.Typ {
+	return s.write(commentBytes(text))
+}
+
+// SendFrom sends every event received on the channel until the channel is closed or the client goes away
+func (s *Stream) SendFrom(events <-chan Event) appError.Typ {
+	for {
+		select {
+		case <-s.done:
+			return appError.BlankError
+		case e, ok := <-events:
+			if !ok {
+				return appError.BlankError
+			}
+			if err := s.Send(e); err.IsNotBlank() {
+				return err
+			}
+		}
+	}
+}
+
+func (s *Stream) write(b []byte) appError.Typ {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.IsClosed() {
+		return appError.NewError(appError.Notice, "3DW4LW", "Event stream is closed")
+	}
+
+	if _, err := s.w.Write(b); err != nil {
+		s.markClosed()
+		return appError.NewError(appError.Notice, "3E54W3", fmt.Sprintf("Client went away: %v", err))
+	}
+	if s.flush != nil {
+		if err := s.flush(); err != nil {
+			s.markClosed()
+			return appError.NewError(appError.Notice, "3D63RG", fmt.Sprintf("Client went away: %v", err))
+		}
+	}
+	return appError.BlankError
+}
+
+// Run prepares the stream according to the options (retry advertisement, heartbeats and resume hook) and then calls
+// fn. The stream is closed when fn returns.
+func (s *Stream) Run(opts Options, fn func(stream *Stream) appError.Typ) appError.Typ {
+	defer s.Close()
+
+	if opts.Retry > 0 {
+		if e := s.write([]byte(fmt.Sprintf("retry: %d\n\n", opts.Retry.Milliseconds()))); e.IsNotBlank() {
+			return e
+		}
+	} else if e := s.Comment("stream opened"); e.IsNotBlank() {
+		// Send something right away so that the headers reach the client
+		return e
+	}
+
+	if opts.HeartbeatInterval > 0 {
+		go s.heartbeat(opts.HeartbeatInterval)
+	}
+
+	if s.lastEventId != "" && opts.OnResume != nil {
+		if e := opts.OnResume(s.lastEventId, s); e.IsNotBlank() {
+			return e
+		}
+	}
+
+	return fn(s)
+}
+
+// heartbeat sends a comment at each interval which keeps the connection alive and detects the clients that went away
+func (s *Stream) heartbeat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			if e := s.Comment("heartbeat"); e.IsNotBlank() {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package sse
+
+import (
+	"bytes"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/techrail/ground/typs/appError"
+)
+
+func TestEvent_Bytes(t *testing.T) {
+	e := Event{Id: "42", Event: "progress", Data: "line one\nline two", Retry: 3 * time.Second}
+	expected := "id: 42\nevent: progress\nretry: 3000\ndata: line one\ndata: line two\n\n"
+	if string(e.Bytes()) != expected {
+		t.Errorf("E#3C9Z6E - Expected %q, got %q", expected, string(e.Bytes()))
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestStream_DetectsDisconnect(t *testing.T) {
+	s := NewStream(failingWriter{}, nil, "")
+	if e := s.SendData("progress", "10%"); e.IsBlank() {
+		t.Errorf("E#3CVZ0H - Expected an error when writing to a disconnected client")
+	}
+	select {
+	case <-s.Done():
+	default:
+		t.Errorf("E#3EXK86 - Expected the stream to be closed after the write failure")
+	}
+}
+
+func TestStream_RunCallsResumeHook(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewStream(&buf, nil, "7")
+	resumedFrom := ""
+	opts := Options{
+		OnResume: func(lastEventId string, stream *Stream) appError.Typ {
+			resumedFrom = lastEventId
+			return stream.Send(Event{Id: "8", Data: "missed"})
+		},
+	}
+	s.Run(opts, func(stream *Stream) appError.Typ {
+		return stream.Send(Event{Id: "9", Data: "new"})
+	})
+
+	if resumedFrom != "7" {
+		t.Errorf("E#3C2EAQ - Expected resume from `7`, got `%v`", resumedFrom)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("id: 8\ndata: missed\n\nid: 9\ndata: new\n\n")) {
+		t.Errorf("E#3BW0CH - Unexpected stream content: %q", buf.String())
+	}
+	if !s.IsClosed() {
+		t.Errorf("E#3BHHT2 - Expected the stream to be closed after Run returned")
+	}
+}
+
+// releasedWriter records the writes which ended after the handler released it
+type releasedWriter struct {
+	released   atomic.Bool
+	lateWrites atomic.Int32
+}
+
+func (w *releasedWriter) Write(b []byte) (int, error) {
+	time.Sleep(time.Millisecond)
+	if w.released.Load() {
+		w.lateWrites.Add(1)
+	}
+	return len(b), nil
+}
+
+func TestStream_NoWriteAfterRun(t *testing.T) {
+	w := &releasedWriter{}
+	s := NewStream(w, nil, "")
+	s.Run(Options{HeartbeatInterval: time.Millisecond}, func(stream *Stream) appError.Typ {
+		time.Sleep(20 * time.Millisecond)
+		return appError.BlankError
+	})
+	w.released.Store(true)
+	time.Sleep(10 * time.Millisecond)
+	if n := w.lateWrites.Load(); n > 0 {
+		t.Errorf("E#3BXS15 - %v heartbeat(s) were written after Run returned", n)
+	}
+}
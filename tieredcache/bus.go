@@ -0,0 +1,116 @@
+package tieredcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/valkey-io/valkey-go"
+
+	"github.com/techrail/ground/cache"
+)
+
+// Bus carries the invalidation messages between the replicas. The implementations must be safe for concurrent use.
+type Bus interface {
+	// Publish sends the message to the subscribers of the channel
+	Publish(ctx context.Context, channel, message string) error
+	// Subscribe calls fn for every message of the channel. It blocks while subscribed and returns when the context
+	// is done or when the subscription breaks (the caller subscribes again).
+	Subscribe(ctx context.Context, channel string, fn func(message string)) error
+}
+
+// ValkeyBus uses the pub/sub of valkey
+type ValkeyBus struct {
+	client valkey.Client
+}
+
+// NewValkeyBus creates a bus using the valkey cache. The in-memory cache has no pub/sub; use NewMemoryBus with it.
+func NewValkeyBus(c cache.ValkeyCacheAPI) *ValkeyBus {
+	return &ValkeyBus{client: c.Underlying()}
+}
+
+func (b *ValkeyBus) Publish(ctx context.Context, channel, message string) error {
+	if b.client == nil {
+		return errors.New("tieredcache: the valkey cache has no pub/sub")
+	}
+	return b.client.Do(ctx, b.client.B().Publish().Channel(channel).Message(message).Build()).Error()
+}
+
+func (b *ValkeyBus) Subscribe(ctx context.Context, channel string, fn func(message string)) error {
+	if b.client == nil {
+		return errors.New("tieredcache: the valkey cache has no pub/sub")
+	}
+	return b.client.Receive(ctx, b.client.B().Subscribe().Channel(channel).Build(), func(msg valkey.PubSubMessage) {
+		fn(msg.Message)
+	})
+}
+
+// RedisBus uses the pub/sub of redis. The channel is namespaced like the keys of the client.
+type RedisBus struct {
+	client *cache.Client
+}
+
+// NewRedisBus creates a bus using the redis client
+func NewRedisBus(c *cache.Client) *RedisBus {
+	return &RedisBus{client: c}
+}
+
+func (b *RedisBus) Publish(ctx context.Context, channel, message string) error {
+	return b.client.Connection.Publish(ctx, b.client.Key(channel), message).Err()
+}
+
+func (b *RedisBus) Subscribe(ctx context.Context, channel string, fn func(message string)) error {
+	ps := b.client.Connection.Subscribe(ctx, b.client.Key(channel))
+	defer ps.Close()
+	// Wait for the confirmation, so that a failure to subscribe is reported
+	if _, err := ps.Receive(ctx); err != nil {
+		return err
+	}
+	for {
+		msg, err := ps.ReceiveMessage(ctx)
+		if err != nil {
+			return err
+		}
+		fn(msg.Payload)
+	}
+}
+
+// MemoryBus delivers the messages within the process (for tests, or the caches of a single node). The messages are
+// delivered synchronously by Publish.
+type MemoryBus struct {
+	mu          sync.Mutex
+	subscribers map[string]map[*func(string)]struct{}
+}
+
+// NewMemoryBus creates an in-process bus
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{subscribers: map[string]map[*func(string)]struct{}{}}
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, channel, message string) error {
+	b.mu.Lock()
+	fns := make([]func(string), 0, len(b.subscribers[channel]))
+	for fn := range b.subscribers[channel] {
+		fns = append(fns, *fn)
+	}
+	b.mu.Unlock()
+	for _, fn := range fns {
+		fn(message)
+	}
+	return nil
+}
+
+func (b *MemoryBus) Subscribe(ctx context.Context, channel string, fn func(message string)) error {
+	b.mu.Lock()
+	if b.subscribers[channel] == nil {
+		b.subscribers[channel] = map[*func(string)]struct{}{}
+	}
+	b.subscribers[channel][&fn] = struct{}{}
+	b.mu.Unlock()
+
+	<-ctx.Done()
+	b.mu.Lock()
+	delete(b.subscribers[channel], &fn)
+	b.mu.Unlock()
+	return ctx.Err()
+}
@@ -0,0 +1,226 @@
+package tieredcache
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+const (
+	PolicyLRU = "lru" // Evicts the least recently used entry
+	PolicyLFU = "lfu" // Evicts the least frequently used entry (the least recently used among equals)
+)
+
+// Stats are the counters of the local tier
+type Stats struct {
+	Hits          int64 // Reads served by the local tier
+	Misses        int64 // Reads which went to the shared backend
+	Evictions     int64 // Entries removed to respect the limits
+	Invalidations int64 // Entries removed on the request of another replica
+	Entries       int   // Entries currently held
+	Bytes         int64 // Size of the entries currently held (keys and values)
+}
+
+// lfuAgingReads is how many reads per entry held the LFU policy counts before halving the counts of uses, so that the
+// entries which were popular once do not stay forever
+const lfuAgingReads = 10
+
+// local is the bounded in-process tier. The entries are kept in a heap ordered by the eviction policy, so that the
+// next victim is always at the top.
+type local struct {
+	mu         sync.Mutex
+	lfu        bool
+	maxEntries int
+	maxBytes   int64
+	entries    map[string]*localEntry
+	order      localHeap
+	tick       uint64 // Logical clock of the reads and writes (LRU)
+	epoch      uint64 // Incremented by every write and invalidation
+	reads      int    // Reads since the counts of uses were last halved (LFU)
+	stats      Stats
+}
+
+type localEntry struct {
+	key       string
+	data      []byte
+	expiresAt time.Time
+	uses      uint64 // Number of uses, starting at 1 when stored and halved from time to time (LFU)
+	lastUsed  uint64 // Tick of the last read or write
+	index     int    // Position in the heap
+}
+
+func newLocal(policy string, maxEntries int, maxBytes int64) *local {
+	l := &local{
+		lfu:        policy == PolicyLFU,
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		entries:    map[string]*localEntry{},
+	}
+	l.order.lfu = l.lfu
+	return l
+}
+
+func (l *local) get(key string) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e, found := l.entries[key]
+	if found && time.Now().After(e.expiresAt) {
+		l.remove(e)
+		found = false
+	}
+	if !found {
+		l.stats.Misses++
+		return nil, false
+	}
+	l.stats.Hits++
+	l.tick++
+	e.uses++
+	e.lastUsed = l.tick
+	heap.Fix(&l.order, e.index)
+	if l.lfu {
+		l.reads++
+		if l.reads >= lfuAgingReads*len(l.entries) {
+			l.age()
+		}
+	}
+	return e.data, true
+}
+
+// age halves the counts of uses (LFU). The lock must be held.
+func (l *local) age() {
+	l.reads = 0
+	for _, e := range l.entries {
+		e.uses = max(e.uses/2, 1)
+	}
+	heap.Init(&l.order)
+}
+
+// set stores the value written by this replica. It moves to the next epoch too, so that a read which started
+// before the write does not replace the value with the previous one.
+func (l *local) set(key string, data []byte, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.epoch++
+	l.store(key, data, ttl)
+}
+
+// store adds the entry, evicting others to make room for it. The victims are chosen before the entry is added, so
+// that a new entry (which was not read yet) is not evicted right away by the LFU policy. The lock must be held.
+func (l *local) store(key string, data []byte, ttl time.Duration) {
+	if e, found := l.entries[key]; found {
+		l.remove(e)
+	}
+	e := &localEntry{key: key, data: data, expiresAt: time.Now().Add(ttl), uses: 1}
+	if l.maxBytes > 0 && e.size() > l.maxBytes {
+		// It would evict everything else and still not fit
+		return
+	}
+	for len(l.entries) > 0 && ((l.maxEntries > 0 && len(l.entries) >= l.maxEntries) ||
+		(l.maxBytes > 0 && l.stats.Bytes+e.size() > l.maxBytes)) {
+		l.remove(l.order.entries[0])
+		l.stats.Evictions++
+	}
+	l.tick++
+	e.lastUsed = l.tick
+	l.entries[key] = e
+	heap.Push(&l.order, e)
+	l.stats.Bytes += e.size()
+}
+
+// invalidate removes the keys; remote tells if another replica asked for it. It also moves to the next epoch, so
+// that the reads which started before it do not fill the removed keys back (see fill).
+func (l *local) invalidate(remote bool, keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.epoch++
+	for _, key := range keys {
+		if e, found := l.entries[key]; found {
+			l.remove(e)
+			if remote {
+				l.stats.Invalidations++
+			}
+		}
+	}
+}
+
+// currentEpoch returns the epoch to pass to fill
+func (l *local) currentEpoch() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.epoch
+}
+
+// fill stores the value read from the shared backend, unless an invalidation happened since the read started (the
+// value could be the invalidated one)
+func (l *local) fill(key string, data []byte, ttl time.Duration, epoch uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.epoch == epoch {
+		l.store(key, data, ttl)
+	}
+}
+
+func (l *local) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.epoch++
+	l.entries = map[string]*localEntry{}
+	l.order.entries = nil
+	l.stats.Bytes = 0
+	l.reads = 0
+}
+
+func (l *local) snapshot() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := l.stats
+	s.Entries = len(l.entries)
+	return s
+}
+
+// remove drops the entry. The lock must be held.
+func (l *local) remove(e *localEntry) {
+	heap.Remove(&l.order, e.index)
+	delete(l.entries, e.key)
+	l.stats.Bytes -= e.size()
+}
+
+func (e *localEntry) size() int64 {
+	return int64(len(e.key) + len(e.data))
+}
+
+// localHeap implements heap.Interface with the next entry to evict at the top
+type localHeap struct {
+	lfu     bool
+	entries []*localEntry
+}
+
+func (h *localHeap) Len() int { return len(h.entries) }
+
+func (h *localHeap) Less(i, j int) bool {
+	a, b := h.entries[i], h.entries[j]
+	if h.lfu && a.uses != b.uses {
+		return a.uses < b.uses
+	}
+	return a.lastUsed < b.lastUsed
+}
+
+func (h *localHeap) Swap(i, j int) {
+	h.entries[i], h.entries[j] = h.entries[j], h.entries[i]
+	h.entries[i].index = i
+	h.entries[j].index = j
+}
+
+func (h *localHeap) Push(x any) {
+	e := x.(*localEntry)
+	e.index = len(h.entries)
+	h.entries = append(h.entries, e)
+}
+
+func (h *localHeap) Pop() any {
+	last := len(h.entries) - 1
+	e := h.entries[last]
+	h.entries[last] = nil
+	h.entries = h.entries[:last]
+	return e
+}
@@ -0,0 +1,189 @@
+// Package tieredcache keeps a bounded local cache (LRU or LFU) in each replica in front of the shared cache (redis
+// or valkey). The writes go to both tiers and publish an invalidation message, on which the other replicas drop
+// their local copy of the keys. If messages get lost, the local TTL bounds how long a replica can serve a stale
+// value. The Cache is a typedcache.Backend, so a typed read-through cache can be put on top of it.
+package tieredcache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/techrail/ground/logger"
+	"github.com/techrail/ground/typedcache"
+	"github.com/techrail/ground/typs/appError"
+	"github.com/techrail/ground/uuid"
+)
+
+// Config configures a Cache; see DefaultConfig
+type Config struct {
+	Backend typedcache.Backend // The shared tier
+	Bus     Bus                // Carries the invalidations (without it, only LocalTTL limits the staleness)
+	Channel string             // Channel of the invalidation messages
+	Policy  string             // PolicyLRU or PolicyLFU
+	// MaxEntries and MaxBytes (keys and values) limit the size of the local tier. 0 means no limit, but at least one
+	// of them should be set.
+	MaxEntries int
+	MaxBytes   int64
+	// LocalTTL is the longest time an entry stays in the local tier (the TTL of the write applies if shorter)
+	LocalTTL time.Duration
+	// ResubscribeInterval is the wait before subscribing again after the subscription breaks
+	ResubscribeInterval time.Duration
+}
+
+// DefaultConfig returns the configuration of an LRU local tier of 10000 entries, kept for 1 minute at most
+func DefaultConfig(backend typedcache.Backend, bus Bus) Config {
+	return Config{
+		Backend:             backend,
+		Bus:                 bus,
+		Channel:             "ground:tieredcache:invalidations",
+		Policy:              PolicyLRU,
+		MaxEntries:          10000,
+		LocalTTL:            time.Minute,
+		ResubscribeInterval: 5 * time.Second,
+	}
+}
+
+// Cache is a two-tier cache. Create it using New and stop it using Close.
+type Cache struct {
+	cfg   Config
+	id    string // Identifies the replica in the invalidation messages
+	local *local
+	stop  context.CancelFunc
+	done  chan struct{}
+}
+
+var _ typedcache.Backend = (*Cache)(nil)
+
+// invalidation is the message published on the writes
+type invalidation struct {
+	Origin string   `json:"origin"`
+	Keys   []string `json:"keys"`
+}
+
+// New creates the cache and, if there is a bus, subscribes to the invalidations in the background
+func New(cfg Config) *Cache {
+	if cfg.Policy != PolicyLFU {
+		cfg.Policy = PolicyLRU
+	}
+	if cfg.LocalTTL <= 0 {
+		cfg.LocalTTL = time.Minute
+	}
+	if cfg.ResubscribeInterval <= 0 {
+		cfg.ResubscribeInterval = 5 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Cache{
+		cfg:   cfg,
+		id:    uuid.GetNewUlidAsString(),
+		local: newLocal(cfg.Policy, cfg.MaxEntries, cfg.MaxBytes),
+		stop:  cancel,
+		done:  make(chan struct{}),
+	}
+	if cfg.Bus == nil {
+		close(c.done)
+		return c
+	}
+	go c.subscribe(ctx)
+	return c
+}
+
+// Get returns the data of the key from the local tier, or from the shared one (keeping it locally)
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, bool, appError.Typ) {
+	if data, found := c.local.get(key); found {
+		return data, true, appError.BlankError
+	}
+	epoch := c.local.currentEpoch()
+	data, found, errTy := c.cfg.Backend.Get(ctx, key)
+	if errTy.IsNotBlank() || !found {
+		return nil, false, errTy
+	}
+	c.local.fill(key, data, c.cfg.LocalTTL, epoch)
+	return data, true, appError.BlankError
+}
+
+// Set writes the data to both tiers and tells the other replicas to drop their copy
+func (c *Cache) Set(ctx context.Context, key string, data []byte, ttl time.Duration) appError.Typ {
+	if errTy := c.cfg.Backend.Set(ctx, key, data, ttl); errTy.IsNotBlank() {
+		// The shared value is unknown now
+		c.local.invalidate(false, key)
+		c.publish(ctx, key)
+		return errTy
+	}
+	localTTL := c.cfg.LocalTTL
+	if ttl > 0 && ttl < localTTL {
+		localTTL = ttl
+	}
+	c.local.set(key, data, localTTL)
+	c.publish(ctx, key)
+	return appError.BlankError
+}
+
+// Delete removes the keys from both tiers and tells the other replicas to drop them
+func (c *Cache) Delete(ctx context.Context, keys ...string) appError.Typ {
+	if len(keys) == 0 {
+		return appError.BlankError
+	}
+	errTy := c.cfg.Backend.Delete(ctx, keys...)
+	c.local.invalidate(false, keys...)
+	c.publish(ctx, keys...)
+	return errTy
+}
+
+// Stats returns the counters of the local tier
+func (c *Cache) Stats() Stats {
+	return c.local.snapshot()
+}
+
+// Close stops listening to the invalidations
+func (c *Cache) Close() {
+	c.stop()
+	<-c.done
+}
+
+// publish sends the invalidation of the keys. A failure is only logged: the write itself succeeded and the other
+// replicas drop their copy when the local TTL ends.
+func (c *Cache) publish(ctx context.Context, keys ...string) {
+	if c.cfg.Bus == nil {
+		return
+	}
+	msg, _ := json.Marshal(invalidation{Origin: c.id, Keys: keys})
+	if err := c.cfg.Bus.Publish(ctx, c.cfg.Channel, string(msg)); err != nil {
+		logger.Warn(fmt.Sprintf("W#3HIMYK - Could not publish the invalidation of %v: %v", keys, err))
+	}
+}
+
+// subscribe listens to the invalidations until the cache is closed. The local tier is cleared after the
+// subscription breaks, since the messages sent meanwhile are lost.
+func (c *Cache) subscribe(ctx context.Context) {
+	defer close(c.done)
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			c.local.clear()
+		}
+		err := c.cfg.Bus.Subscribe(ctx, c.cfg.Channel, c.onMessage)
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Warn(fmt.Sprintf("W#3HD4RL - The subscription to the cache invalidations broke, retrying in %v: %v",
+			c.cfg.ResubscribeInterval, err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(c.cfg.ResubscribeInterval):
+		}
+	}
+}
+
+func (c *Cache) onMessage(message string) {
+	var inv invalidation
+	if err := json.Unmarshal([]byte(message), &inv); err != nil {
+		logger.Warn(fmt.Sprintf("W#3CH9D6 - Ignoring the malformed cache invalidation %q: %v", message, err))
+		return
+	}
+	if inv.Origin == c.id {
+		return
+	}
+	c.local.invalidate(true, inv.Keys...)
+}
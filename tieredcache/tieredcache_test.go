@@ -0,0 +1,86 @@
+package tieredcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/techrail/ground/cache"
+	"github.com/techrail/ground/typedcache"
+)
+
+func TestInvalidationAcrossReplicas(t *testing.T) {
+	bus := NewMemoryBus()
+	shared := typedcache.NewValkeyBackend(cache.NewMemoryValkeyCache())
+	a, b := New(DefaultConfig(shared, bus)), New(DefaultConfig(shared, bus))
+	defer a.Close()
+	defer b.Close()
+	for deadline := time.Now().Add(time.Second); ; {
+		bus.mu.Lock()
+		n := len(bus.subscribers[a.cfg.Channel])
+		bus.mu.Unlock()
+		if n == 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	ctx := context.Background()
+
+	_ = a.Set(ctx, "k", []byte("v1"), time.Minute)
+	_, _, _ = b.Get(ctx, "k")
+	if data, _, _ := b.Get(ctx, "k"); string(data) != "v1" || b.Stats().Hits != 1 {
+		t.Errorf("E#3AQ9LF - The second read was not local: %q, %+v", data, b.Stats())
+	}
+	_ = a.Set(ctx, "k", []byte("v2"), time.Minute)
+	if data, _, _ := b.Get(ctx, "k"); string(data) != "v2" {
+		t.Errorf("E#3FBG5Y - The replica served %q after the invalidation", data)
+	}
+	if s := b.Stats(); s.Invalidations != 1 {
+		t.Errorf("E#3DFHRO - Unexpected stats %+v", s)
+	}
+}
+
+func TestLocalLimits(t *testing.T) {
+	l := newLocal(PolicyLFU, 2, 0)
+	l.set("a", []byte("1"), time.Minute)
+	l.set("b", []byte("1"), time.Minute)
+	l.get("a")
+	l.set("c", []byte("1"), time.Minute)
+	if _, found := l.get("b"); found {
+		t.Errorf("E#3B5FDX - LFU did not evict the least used entry")
+	}
+
+	l = newLocal(PolicyLRU, 0, 9)
+	l.set("a", []byte("1234"), time.Minute)
+	l.set("b", []byte("1234"), time.Minute)
+	if s := l.snapshot(); s.Entries != 1 || s.Bytes != 5 || s.Evictions != 1 {
+		t.Errorf("E#3BNRX2 - The byte limit was not respected: %+v", s)
+	}
+}
+
+func TestLFUAdmitsNewEntries(t *testing.T) {
+	l := newLocal(PolicyLFU, 3, 0)
+	for _, key := range []string{"a", "b", "c"} {
+		l.set(key, []byte("1"), time.Minute)
+		for range 5 {
+			l.get(key)
+		}
+	}
+	l.set("d", []byte("1"), time.Minute)
+	if _, found := l.get("d"); !found {
+		t.Errorf("E#3EC731 - The new entry evicted itself from a tier full of read entries")
+	}
+	if s := l.snapshot(); s.Entries != 3 || s.Evictions != 1 {
+		t.Errorf("E#3AVQ7M - Expected one of the old entries to be evicted: %+v", s)
+	}
+
+	// Enough reads halve the counts, so that the entries which were popular once can be evicted in the end
+	for range lfuAgingReads * 3 {
+		l.get("d")
+	}
+	for _, e := range l.entries {
+		if e.key != "d" && e.uses > 3 {
+			t.Errorf("E#3BCYXF - The count of uses of %v was not halved (%v)", e.key, e.uses)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package typedcache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/techrail/ground/cache"
+	"github.com/techrail/ground/contentcodec"
+	"github.com/techrail/ground/typs/appError"
+)
+
+// Backend stores the encoded entries. The implementations must be safe for concurrent use.
+type Backend interface {
+	// Get returns the data stored against the key (and whether there is any)
+	Get(ctx context.Context, key string) ([]byte, bool, appError.Typ)
+	// Set stores the data against the key for the given time
+	Set(ctx context.Context, key string, data []byte, ttl time.Duration) appError.Typ
+	// Delete removes the keys
+	Delete(ctx context.Context, keys ...string) appError.Typ
+}
+
+// Codec encodes the values into the backend and decodes them back. Any contentcodec.Codec is a Codec.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	// Json encodes the values as JSON. The values of type jsonObject.Typ keep their shape, as they marshal themselves.
+	Json Codec = contentcodec.Json
+	// MsgPack encodes the values as MessagePack (using the `json` struct tags)
+	MsgPack Codec = contentcodec.MsgPack
+	// Gob encodes the values with encoding/gob; the unexported fields are not kept
+	Gob Codec = gobCodec{}
+)
+
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// ValkeyBackend stores the entries using the valkey cache (or any other ValkeyCacheAPI, like the in-memory one)
+type ValkeyBackend struct {
+	cache cache.ValkeyCacheAPI
+}
+
+// NewValkeyBackend creates a backend using the valkey cache
+func NewValkeyBackend(c cache.ValkeyCacheAPI) *ValkeyBackend {
+	return &ValkeyBackend{cache: c}
+}
+
+func (b *ValkeyBackend) Get(ctx context.Context, key string) ([]byte, bool, appError.Typ) {
+	val, err := b.cache.Get(ctx, key)
+	if errors.Is(err, cache.ErrNotFound) {
+		return nil, false, appError.BlankError
+	}
+	if err != nil {
+		return nil, false, appError.NewError(appError.Error, "3EKNV2", fmt.Sprintf("Could not get %v: %v", key, err))
+	}
+	return []byte(val), true, appError.BlankError
+}
+
+func (b *ValkeyBackend) Set(ctx context.Context, key string, data []byte, ttl time.Duration) appError.Typ {
+	if err := b.cache.Set(ctx, key, string(data), cache.WithExpiration(ttl)); err != nil {
+		return appError.NewError(appError.Error, "3BTK2Q", fmt.Sprintf("Could not set %v: %v", key, err))
+	}
+	return appError.BlankError
+}
+
+func (b *ValkeyBackend) Delete(ctx context.Context, keys ...string) appError.Typ {
+	if _, err := b.cache.Del(ctx, keys...); err != nil {
+		return appError.NewError(appError.Error, "3HMIYT", fmt.Sprintf("Could not delete %v: %v", keys, err))
+	}
+	return appError.BlankError
+}
+
+// RedisBackend stores the entries using the redis client (within the namespace of the application)
+type RedisBackend struct {
+	client *cache.Client
+}
+
+// NewRedisBackend creates a backend using the redis client
+func NewRedisBackend(c *cache.Client) *RedisBackend {
+	return &RedisBackend{client: c}
+}
+
+func (b *RedisBackend) Get(ctx context.Context, key string) ([]byte, bool, appError.Typ) {
+	val, found, errTy := b.client.Get(ctx, key)
+	if errTy.IsNotBlank() {
+		return nil, false, appError.NewError(appError.Error, "3D6GZZ", fmt.Sprintf("Could not get %v", key), errTy)
+	}
+	return []byte(val), found, appError.BlankError
+}
+
+func (b *RedisBackend) Set(ctx context.Context, key string, data []byte, ttl time.Duration) appError.Typ {
+	if errTy := b.client.Set(ctx, key, data, ttl); errTy.IsNotBlank() {
+		return appError.NewError(appError.Error, "3DG22V", fmt.Sprintf("Could not set %v", key), errTy)
+	}
+	return appError.BlankError
+}
+
+func (b *RedisBackend) Delete(ctx context.Context, keys ...string) appError.Typ {
+	if _, errTy := b.client.Delete(ctx, keys...); errTy.IsNotBlank() {
+		return appError.NewError(appError.Error, "3ADQE8", fmt.Sprintf("Could not delete %v", keys), errTy)
+	}
+	return appError.BlankError
+}
@@ -0,0 +1,268 @@
+// Package typedcache is a typed read-through cache: Get returns the cached value of the key or loads it (e.g. from
+// the database) with the loader of the cache, stores it and returns it. The concurrent misses of a key are loaded
+// once (singleflight), the missing values can be cached too (negative caching) and the expired values can be served
+// while they are refreshed in the background (stale-while-revalidate). The values are kept in any Backend; see
+// NewValkeyBackend and NewRedisBackend.
+package typedcache
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/techrail/ground/cache"
+	"github.com/techrail/ground/logger"
+	"github.com/techrail/ground/typs/appError"
+)
+
+// ErrNotFound is returned by Get when the value does not exist. The loaders can return it (or sql.ErrNoRows, or
+// cache.ErrNotFound) to tell that there is no value for the key, which is then cached if negative caching is on.
+var ErrNotFound = errors.New("typedcache: value not found")
+
+// Loader loads the value of a key on a miss
+type Loader[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+// Config configures a Cache; see DefaultConfig
+type Config[K comparable, V any] struct {
+	Backend Backend
+	Codec   Codec        // Json by default
+	Loader  Loader[K, V] // Without a loader, a miss gives ErrNotFound
+	// KeyPrefix is prepended to the keys in the backend, so that the caches of different types do not collide
+	KeyPrefix string
+	KeyFunc   func(K) string // Converts the key to a string (fmt.Sprint by default)
+	TTL       time.Duration  // How long the values stay fresh
+	// TTLJitter adds a random part, up to the given fraction of TTL, to every TTL so that the keys written together
+	// do not expire together (0.1 means up to 10% more)
+	TTLJitter float64
+	// NegativeTTL is how long a missing value is remembered (0 disables negative caching)
+	NegativeTTL time.Duration
+	// StaleTTL is how long the values are kept after they expire; within it the stale value is returned and
+	// refreshed in the background (0 disables stale-while-revalidate)
+	StaleTTL time.Duration
+	// LoadTimeout bounds the loads. They are not cancelled with the context of the caller, since other callers (or
+	// the background refresh) may be waiting for the same load. 10 seconds by default.
+	LoadTimeout time.Duration
+}
+
+// DefaultConfig returns the configuration with a TTL of 5 minutes (+10% jitter) and a negative TTL of 30 seconds
+func DefaultConfig[K comparable, V any](backend Backend, keyPrefix string, loader Loader[K, V]) Config[K, V] {
+	return Config[K, V]{
+		Backend:     backend,
+		Codec:       Json,
+		Loader:      loader,
+		KeyPrefix:   keyPrefix,
+		TTL:         5 * time.Minute,
+		TTLJitter:   0.1,
+		NegativeTTL: 30 * time.Second,
+		LoadTimeout: 10 * time.Second,
+	}
+}
+
+// Cache is a typed read-through cache. The values returned by the concurrent Get calls of a key can be the same
+// value, so they must not be modified. Create it using New.
+type Cache[K comparable, V any] struct {
+	cfg        Config[K, V]
+	group      singleflight.Group
+	refreshing sync.Map // Keys being refreshed in the background
+}
+
+// New creates a cache as per the configuration. The Backend is required.
+func New[K comparable, V any](cfg Config[K, V]) *Cache[K, V] {
+	if cfg.Codec == nil {
+		cfg.Codec = Json
+	}
+	if cfg.KeyFunc == nil {
+		cfg.KeyFunc = func(key K) string { return fmt.Sprint(key) }
+	}
+	if cfg.LoadTimeout <= 0 {
+		cfg.LoadTimeout = 10 * time.Second
+	}
+	return &Cache[K, V]{cfg: cfg}
+}
+
+// Get returns the value of the key, loading it on a miss. It returns ErrNotFound if there is no value for the key,
+// and the error of the loader if the load fails. The failures of the backend are logged and treated as misses.
+func (c *Cache[K, V]) Get(ctx context.Context, key K) (V, error) {
+	var zero V
+	bk := c.backendKey(key)
+	if e, found := c.read(ctx, bk); found {
+		if time.Now().After(e.freshUntil) && c.cfg.Loader != nil {
+			c.refreshInBackground(ctx, key, bk)
+		}
+		if e.negative {
+			return zero, ErrNotFound
+		}
+		var value V
+		err := c.cfg.Codec.Unmarshal(e.payload, &value)
+		if err == nil {
+			return value, nil
+		}
+		// The type changed since the value was stored, most likely; load it again
+		logger.Warn(fmt.Sprintf("W#3HX1X8 - Could not decode the cached value of %v: %v", bk, err))
+	}
+
+	ch := c.group.DoChan(bk, func() (any, error) {
+		return c.load(ctx, key, bk, false)
+	})
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		// A nil interface value (V being an interface) does not assert to V
+		value, _ := res.Val.(V)
+		return value, nil
+	}
+}
+
+// Set stores the value of the key (e.g. after updating it in the database)
+func (c *Cache[K, V]) Set(ctx context.Context, key K, value V) appError.Typ {
+	payload, err := c.cfg.Codec.Marshal(value)
+	if err != nil {
+		return appError.NewError(appError.Error, "3CJLXZ", fmt.Sprintf("Could not encode the value of %v: %v", key, err))
+	}
+	ttl := c.jittered(c.cfg.TTL)
+	return c.cfg.Backend.Set(ctx, c.backendKey(key), encodeEntry(false, time.Now().Add(ttl), payload), ttl+c.cfg.StaleTTL)
+}
+
+// Delete removes the keys, so that their next Get loads them again
+func (c *Cache[K, V]) Delete(ctx context.Context, keys ...K) appError.Typ {
+	if len(keys) == 0 {
+		return appError.BlankError
+	}
+	backendKeys := make([]string, len(keys))
+	for i, key := range keys {
+		backendKeys[i] = c.backendKey(key)
+	}
+	return c.cfg.Backend.Delete(ctx, backendKeys...)
+}
+
+// load runs the loader and stores its result (replacing the stale value, if any). It runs once for the concurrent
+// misses of a key.
+func (c *Cache[K, V]) load(ctx context.Context, key K, bk string, stale bool) (V, error) {
+	var zero V
+	if c.cfg.Loader == nil {
+		return zero, ErrNotFound
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.cfg.LoadTimeout)
+	defer cancel()
+
+	value, err := c.cfg.Loader(ctx, key)
+	if isNotFound(err) {
+		if c.cfg.NegativeTTL > 0 {
+			ttl := c.jittered(c.cfg.NegativeTTL)
+			c.write(ctx, bk, encodeEntry(true, time.Now().Add(ttl), nil), ttl)
+		} else if stale {
+			if errTy := c.cfg.Backend.Delete(ctx, bk); errTy.IsNotBlank() {
+				logger.Warn(fmt.Sprintf("W#3DEFX9 - Could not remove the stale value of %v: %v", bk, errTy))
+			}
+		}
+		return zero, ErrNotFound
+	}
+	if err != nil {
+		return zero, err
+	}
+
+	payload, err := c.cfg.Codec.Marshal(value)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("W#3D4CPZ - Could not encode the value of %v: %v", bk, err))
+		return value, nil
+	}
+	ttl := c.jittered(c.cfg.TTL)
+	c.write(ctx, bk, encodeEntry(false, time.Now().Add(ttl), payload), ttl+c.cfg.StaleTTL)
+	return value, nil
+}
+
+// refreshInBackground reloads a stale key, unless it is already being reloaded
+func (c *Cache[K, V]) refreshInBackground(ctx context.Context, key K, bk string) {
+	if _, running := c.refreshing.LoadOrStore(bk, struct{}{}); running {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer c.refreshing.Delete(bk)
+		_, err, _ := c.group.Do(bk, func() (any, error) {
+			return c.load(ctx, key, bk, true)
+		})
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			logger.Warn(fmt.Sprintf("W#3EMFIZ - Could not refresh the stale value of %v: %v", bk, err))
+		}
+	}()
+}
+
+// read returns the entry of the key from the backend; an entry which can not be read is a miss
+func (c *Cache[K, V]) read(ctx context.Context, bk string) (entry, bool) {
+	data, found, errTy := c.cfg.Backend.Get(ctx, bk)
+	if errTy.IsNotBlank() {
+		logger.Warn(fmt.Sprintf("W#3AOVGS - Could not read %v from the cache: %v", bk, errTy))
+		return entry{}, false
+	}
+	if !found {
+		return entry{}, false
+	}
+	e, ok := decodeEntry(data)
+	if !ok {
+		logger.Warn(fmt.Sprintf("W#3EPNDT - Ignoring the malformed cache entry of %v", bk))
+	}
+	return e, ok
+}
+
+func (c *Cache[K, V]) write(ctx context.Context, bk string, data []byte, ttl time.Duration) {
+	if errTy := c.cfg.Backend.Set(ctx, bk, data, ttl); errTy.IsNotBlank() {
+		logger.Warn(fmt.Sprintf("W#3CZGVR - Could not write %v to the cache: %v", bk, errTy))
+	}
+}
+
+func (c *Cache[K, V]) backendKey(key K) string {
+	return c.cfg.KeyPrefix + c.cfg.KeyFunc(key)
+}
+
+func (c *Cache[K, V]) jittered(ttl time.Duration) time.Duration {
+	if c.cfg.TTLJitter <= 0 || ttl <= 0 {
+		return ttl
+	}
+	return ttl + time.Duration(rand.Float64()*c.cfg.TTLJitter*float64(ttl))
+}
+
+func isNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, sql.ErrNoRows) || errors.Is(err, cache.ErrNotFound)
+}
+
+// entry is what is stored in the backend: a flag telling whether the value is missing, the time until which the
+// entry is fresh (in unix milliseconds) and the encoded value
+type entry struct {
+	negative   bool
+	freshUntil time.Time
+	payload    []byte
+}
+
+const entryHeaderSize = 9
+
+func encodeEntry(negative bool, freshUntil time.Time, payload []byte) []byte {
+	data := make([]byte, entryHeaderSize, entryHeaderSize+len(payload))
+	if negative {
+		data[0] = 1
+	}
+	binary.BigEndian.PutUint64(data[1:], uint64(freshUntil.UnixMilli()))
+	return append(data, payload...)
+}
+
+func decodeEntry(data []byte) (entry, bool) {
+	if len(data) < entryHeaderSize || data[0] > 1 {
+		return entry{}, false
+	}
+	return entry{
+		negative:   data[0] == 1,
+		freshUntil: time.UnixMilli(int64(binary.BigEndian.Uint64(data[1:]))),
+		payload:    data[entryHeaderSize:],
+	}, true
+}
@@ -0,0 +1,107 @@
+package typedcache
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/techrail/ground/cache"
+	"github.com/techrail/ground/typs/jsonObject"
+)
+
+type user struct {
+	Id   int
+	Name string
+}
+
+func TestReadThrough(t *testing.T) {
+	var loads atomic.Int32
+	release := make(chan struct{})
+	cfg := DefaultConfig(NewValkeyBackend(cache.NewMemoryValkeyCache()), "user:",
+		func(ctx context.Context, id int) (user, error) {
+			loads.Add(1)
+			<-release
+			if id == 0 {
+				return user{}, sql.ErrNoRows
+			}
+			return user{Id: id, Name: "Ada"}, nil
+		})
+	cfg.Codec = Gob
+	c := New(cfg)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if u, err := c.Get(ctx, 1); err != nil || u.Name != "Ada" {
+				t.Errorf("E#3GL6BM - Get gave %+v, %v", u, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := loads.Load(); n != 1 {
+		t.Errorf("E#3AIDGG - The concurrent misses were loaded %v times instead of once", n)
+	}
+
+	for range 2 {
+		if _, err := c.Get(ctx, 0); !errors.Is(err, ErrNotFound) {
+			t.Errorf("E#3DP9QM - Get of a missing value gave %v instead of ErrNotFound", err)
+		}
+	}
+	if n := loads.Load(); n != 2 {
+		t.Errorf("E#3HDH3P - The missing value was not cached (%v loads)", n)
+	}
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	var version atomic.Int32
+	cfg := DefaultConfig(NewValkeyBackend(cache.NewMemoryValkeyCache()), "doc:",
+		func(ctx context.Context, key string) (jsonObject.Typ, error) {
+			return jsonObject.NewJsonObject("version", version.Add(1)), nil
+		})
+	cfg.TTL = 20 * time.Millisecond
+	cfg.TTLJitter = 0
+	cfg.StaleTTL = time.Minute
+	c := New(cfg)
+	ctx := context.Background()
+
+	_, _ = c.Get(ctx, "a")
+	time.Sleep(40 * time.Millisecond)
+	if doc, err := c.Get(ctx, "a"); err != nil || doc.GetTopLevelElement("version") != float64(1) {
+		t.Errorf("E#3GE5S8 - The stale value was not served: %v, %v", doc.String(), err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if doc, _ := c.Get(ctx, "a"); doc.GetTopLevelElement("version") != float64(2) {
+		t.Errorf("E#3BPR2D - The stale value was not refreshed: %v", doc.String())
+	}
+}
+
+func TestNilInterfaceValue(t *testing.T) {
+	cfg := DefaultConfig(NewValkeyBackend(cache.NewMemoryValkeyCache()), "any:",
+		func(ctx context.Context, key string) (any, error) {
+			return nil, nil
+		})
+	c := New(cfg)
+	if v, err := c.Get(context.Background(), "a"); v != nil || err != nil {
+		t.Errorf("E#3FODR7 - Get gave %v, %v; want a nil value", v, err)
+	}
+}
+
+func TestEntryEncoding(t *testing.T) {
+	freshUntil := time.UnixMilli(time.Now().UnixMilli())
+	e, ok := decodeEntry(encodeEntry(true, freshUntil, []byte("x")))
+	if !ok || !e.negative || !e.freshUntil.Equal(freshUntil) || string(e.payload) != "x" {
+		t.Errorf("E#3GT168 - The entry did not survive the encoding: %+v", e)
+	}
+	if _, ok = decodeEntry([]byte("junk")); ok {
+		t.Errorf("E#3GWNP9 - A malformed entry was decoded")
+	}
+}
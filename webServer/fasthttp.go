@@ -8,6 +8,7 @@ import (
 	"github.com/fasthttp/router"
 	"github.com/valyala/fasthttp"
 
+	"github.com/techrail/ground/constants/customCtxKey"
 	"github.com/techrail/ground/typs/appError"
 	"github.com/techrail/ground/utils"
 	"github.com/techrail/ground/webServer/middlewares"
@@ -26,9 +27,12 @@ type FastHttpServer struct {
 	Server       fasthttp.Server
 	BindPort     int
 	EnableIpv6   bool
-	BlockOnStart bool   // Should we block on start or not
-	currentState string // What is the current state of the server
-	middlewares  map[string]MiddlewareSet
+	BlockOnStart bool // Should we block on start or not
+	// ProblemDetailsFailures makes the failure renderers respond with RFC 7807 problem details for every request
+	// served by this server (clients can still ask for them on their own using the `Accept` header)
+	ProblemDetailsFailures bool
+	currentState           string // What is the current state of the server
+	middlewares            map[string]MiddlewareSet
 }
 
 // NewLocalServer creates a basic new local server and returns it.
@@ -37,6 +41,7 @@ func NewLocalServer() *FastHttpServer {
 	r := router.New()
 	mws := map[string]MiddlewareSet{
 		"Default": {
+			middlewares.RecoverPanic,
 			middlewares.SetRequestId,
 			middlewares.SetRandomVar,
 			middlewares.CheckShutdownRequested,
@@ -111,7 +116,7 @@ func (s *FastHttpServer) WithMiddlewareSet(name string, handler fasthttp.Request
 // Start starts the web server according to given parameters
 func (s *FastHttpServer) Start() appError.Typ {
 	s.Server = fasthttp.Server{
-		Handler: s.Router.Handler,
+		Handler: s.rootHandler(),
 	}
 
 	var listener net.Listener
@@ -165,6 +170,18 @@ func (s *FastHttpServer) Start() appError.Typ {
 	return appError.BlankError
 }
 
+// rootHandler returns the handler of the router, wrapped with the server level settings which have to be made
+// available to the renderers through the request context
+func (s *FastHttpServer) rootHandler() fasthttp.RequestHandler {
+	if !s.ProblemDetailsFailures {
+		return s.Router.Handler
+	}
+	return func(ctx *fasthttp.RequestCtx) {
+		ctx.SetUserValue(customCtxKey.ProblemDetailsPreferred, true)
+		s.Router.Handler(ctx)
+	}
+}
+
 // stop will stop the server. It does so by setting the current state. The manager will notice the change
 // and stop the server gracefully
 // Important: The manager has to be adjusted to this behavior. Once done, export the function!
@@ -0,0 +1,120 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/techrail/ground/constants/customHeaders"
+	"github.com/techrail/ground/constants/httpheaders"
+	"github.com/techrail/ground/logger"
+	"github.com/techrail/ground/respcache"
+)
+
+// CacheResponse returns a middleware caching the responses of the GET and HEAD requests in the configured store.
+// The entries are keyed on the path, the (sorted) query and the configured request headers and kept for the TTL of
+// the route. The cached responses carry an ETag and a Last-Modified header, and the conditional requests
+// (If-None-Match, If-Modified-Since) matching them get a 304. Streaming responses are never cached. The requests with
+// credentials (Authorization or Cookie) are only served, and only store, the responses marked as shared
+// (Cache-Control public or s-maxage).
+func CacheResponse(cfg respcache.Config) func(handler fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(handler fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			path := string(ctx.Path())
+			ttl := cfg.TTLFor(path)
+			if ttl <= 0 || !respcache.Applies(string(ctx.Method()),
+				string(ctx.Request.Header.Peek(httpheaders.CacheControl))) {
+				handler(ctx)
+				return
+			}
+
+			requestHeader := func(name string) string {
+				return string(ctx.Request.Header.Peek(name))
+			}
+			key := cfg.Key(path, string(ctx.URI().QueryString()), requestHeader)
+			authenticated := respcache.Authenticated(requestHeader)
+			entry, found, errTy := cfg.Store.Get(ctx, key)
+			if errTy.IsNotBlank() {
+				logger.Warn(fmt.Sprintf("W#3BKYWA - Could not read the response cache: %v", errTy))
+			}
+			if found && entry.Servable(authenticated) {
+				serveCachedEntry(ctx, entry)
+				return
+			}
+
+			handler(ctx)
+
+			if !ctx.IsGet() || ctx.Response.IsBodyStream() || ctx.Hijacked() {
+				// HEAD responses have no body, so only the GET responses are stored
+				return
+			}
+			header := http.Header{}
+			for k, v := range ctx.Response.Header.All() {
+				header.Add(string(k), string(v))
+			}
+			body := ctx.Response.Body()
+			if !cfg.Cacheable(ctx.Response.StatusCode(), header, len(body), authenticated) {
+				return
+			}
+			entry = respcache.NewEntry(ctx.Response.StatusCode(), header, append([]byte(nil), body...))
+			if errTy = cfg.Store.Set(context.Background(), key, entry, ttl); errTy.IsNotBlank() {
+				logger.Warn(fmt.Sprintf("W#3FP24W - Could not store the response in the cache: %v", errTy))
+			}
+			ctx.Response.Header.Set(httpheaders.ETag, entry.ETag)
+			ctx.Response.Header.Set(httpheaders.LastModified, entry.LastModified.Format(http.TimeFormat))
+			ctx.Response.Header.Set(respcache.CacheStatusHeader, "MISS")
+			if notModified(ctx, entry) {
+				setNotModified(ctx)
+			}
+		}
+	}
+}
+
+// serveCachedEntry writes the cached entry (or a 304 if the request is a matching conditional request). The entries
+// do not hold a request ID, so the one of this request is kept.
+func serveCachedEntry(ctx *fasthttp.RequestCtx, entry respcache.Entry) {
+	requestId := string(ctx.Response.Header.Peek(customHeaders.RequestId))
+	ctx.Response.Reset()
+	for name, values := range entry.Header {
+		for _, v := range values {
+			ctx.Response.Header.Add(name, v)
+		}
+	}
+	if requestId != "" {
+		ctx.Response.Header.Set(customHeaders.RequestId, requestId)
+	}
+	ctx.Response.Header.Set(httpheaders.Age, entry.Age())
+	ctx.Response.Header.Set(respcache.CacheStatusHeader, "HIT")
+	ctx.SetStatusCode(entry.Status)
+	if notModified(ctx, entry) {
+		setNotModified(ctx)
+		return
+	}
+	ctx.Response.SetBody(entry.Body)
+}
+
+func notModified(ctx *fasthttp.RequestCtx, entry respcache.Entry) bool {
+	return respcache.NotModified(string(ctx.Request.Header.Peek(httpheaders.IfNoneMatch)),
+		string(ctx.Request.Header.Peek(httpheaders.IfModifiedSince)), entry.ETag, entry.LastModified)
+}
+
+// setNotModified turns the response into a 304, keeping only the headers allowed in it
+func setNotModified(ctx *fasthttp.RequestCtx) {
+	keep := map[string][]string{}
+	for k, v := range ctx.Response.Header.All() {
+		if name := string(k); respcache.KeepInNotModified(name) || strings.EqualFold(name, respcache.CacheStatusHeader) ||
+			strings.EqualFold(name, httpheaders.Age) || strings.EqualFold(name, customHeaders.RequestId) {
+			keep[name] = append(keep[name], string(v))
+		}
+	}
+	ctx.Response.Reset()
+	for name, values := range keep {
+		for _, v := range values {
+			ctx.Response.Header.Add(name, v)
+		}
+	}
+	ctx.SetStatusCode(fasthttp.StatusNotModified)
+}
@@ -0,0 +1,99 @@
+package middlewares
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/techrail/ground/constants/customHeaders"
+	"github.com/techrail/ground/constants/httpheaders"
+	"github.com/techrail/ground/respcache"
+)
+
+// serve runs a GET request for the path with the given headers through the handler
+func serve(handler fasthttp.RequestHandler, path string, header map[string]string) *fasthttp.RequestCtx {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.SetRequestURI(path)
+	for name, value := range header {
+		ctx.Request.Header.Set(name, value)
+	}
+	handler(ctx)
+	return ctx
+}
+
+func TestCacheResponse(t *testing.T) {
+	calls := 0
+	handler := SetRequestId(CacheResponse(respcache.DefaultConfig(respcache.NewMemoryStore(0)))(
+		func(ctx *fasthttp.RequestCtx) {
+			calls++
+			if string(ctx.Path()) == "/public" {
+				ctx.Response.Header.Set(httpheaders.CacheControl, "public, max-age=60")
+			}
+			ctx.SetBodyString("hello")
+		}))
+
+	first := serve(handler, "/items", nil)
+	second := serve(handler, "/items", nil)
+	if string(first.Response.Header.Peek(respcache.CacheStatusHeader)) != "MISS" ||
+		string(second.Response.Header.Peek(respcache.CacheStatusHeader)) != "HIT" || calls != 1 {
+		t.Fatalf("E#3EFU6O - Expected a MISS then a HIT (%v calls)", calls)
+	}
+	if string(second.Response.Body()) != "hello" {
+		t.Errorf("E#3FSXX3 - The cached response has the body %q", second.Response.Body())
+	}
+	if id := string(second.Response.Header.Peek(customHeaders.RequestId)); id == "" ||
+		id == string(first.Response.Header.Peek(customHeaders.RequestId)) {
+		t.Errorf("E#3GBQMX - The cached response carried the request ID %q; want the one of the request", id)
+	}
+
+	etag := string(first.Response.Header.Peek(httpheaders.ETag))
+	lastModified := string(first.Response.Header.Peek(httpheaders.LastModified))
+	for _, header := range []map[string]string{
+		{httpheaders.IfNoneMatch: etag},
+		{httpheaders.IfModifiedSince: lastModified},
+	} {
+		ctx := serve(handler, "/items", header)
+		if ctx.Response.StatusCode() != fasthttp.StatusNotModified || len(ctx.Response.Body()) > 0 ||
+			string(ctx.Response.Header.Peek(httpheaders.ETag)) != etag ||
+			len(ctx.Response.Header.Peek(customHeaders.RequestId)) == 0 {
+			t.Errorf("E#3EPZGI - Expected a 304 with the ETag and the request ID for %v, got %v %q", header,
+				ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+	}
+	ctx := serve(handler, "/items", map[string]string{httpheaders.IfNoneMatch: `"other"`})
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Errorf("E#3F6P6H - Expected a 200 for a different ETag, got %v", ctx.Response.StatusCode())
+	}
+	if calls != 1 {
+		t.Errorf("E#3HJCV6 - The conditional requests reached the handler (%v calls)", calls)
+	}
+}
+
+func TestCacheResponse_AuthenticatedRequests(t *testing.T) {
+	calls := 0
+	handler := SetRequestId(CacheResponse(respcache.DefaultConfig(respcache.NewMemoryStore(0)))(
+		func(ctx *fasthttp.RequestCtx) {
+			calls++
+			if string(ctx.Path()) == "/public" {
+				ctx.Response.Header.Set(httpheaders.CacheControl, "public, max-age=60")
+			}
+			ctx.SetBodyString("hello")
+		}))
+
+	serve(handler, "/profile", nil)
+	serve(handler, "/profile", map[string]string{"Authorization": "Bearer alice"})
+	serve(handler, "/profile", map[string]string{"Cookie": "session=bob"})
+	if calls != 3 {
+		t.Errorf("E#3H3BF9 - The authenticated requests were served from the cache (%v calls; want 3)", calls)
+	}
+	serve(handler, "/account", map[string]string{"Cookie": "session=bob"})
+	if ctx := serve(handler, "/account", nil); string(ctx.Response.Header.Peek(respcache.CacheStatusHeader)) != "MISS" {
+		t.Errorf("E#3C2JFU - The response to an authenticated request was stored and served to another client")
+	}
+
+	serve(handler, "/public", map[string]string{"Authorization": "Bearer alice"})
+	if ctx := serve(handler, "/public", map[string]string{"Authorization": "Bearer bob"}); calls != 6 ||
+		string(ctx.Response.Header.Peek(respcache.CacheStatusHeader)) != "HIT" {
+		t.Errorf("E#3AXZ33 - The shared response was not served from the cache (%v calls; want 6)", calls)
+	}
+}
@@ -0,0 +1,62 @@
+package middlewares
+
+import (
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/techrail/ground/compression"
+	"github.com/techrail/ground/logger"
+)
+
+// CompressResponse compresses the response body using the encoding negotiated with the client (zstd, br, gzip or
+// deflate) and the default compression configuration.
+func CompressResponse(handler fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return CompressResponseWithConfig(compression.DefaultConfig())(handler)
+}
+
+// CompressResponseWithConfig returns a middleware which compresses the responses according to the given config.
+// Streaming responses, responses which already have a Content-Encoding, small responses and responses with
+// content types not in the allowlist are sent as they are.
+func CompressResponseWithConfig(cfg compression.Config) func(handler fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(handler fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			handler(ctx)
+
+			if ctx.IsHead() || ctx.Response.IsBodyStream() || ctx.Hijacked() {
+				return
+			}
+			status := ctx.Response.StatusCode()
+			if status < 200 || status == fasthttp.StatusNoContent || status == fasthttp.StatusNotModified {
+				return
+			}
+			if !cfg.ContentTypeAllowed(string(ctx.Response.Header.ContentType())) {
+				return
+			}
+
+			// The response could have been compressed for some clients, so the caches have to know about it
+			ctx.Response.Header.Set(fasthttp.HeaderVary,
+				compression.AddVary(string(ctx.Response.Header.Peek(fasthttp.HeaderVary)), fasthttp.HeaderAcceptEncoding))
+
+			if len(ctx.Response.Header.ContentEncoding()) > 0 || len(ctx.Response.Body()) < cfg.MinSize {
+				return
+			}
+
+			encoding := cfg.Negotiate(string(ctx.Request.Header.Peek(fasthttp.HeaderAcceptEncoding)))
+			if encoding == "" {
+				return
+			}
+
+			compressed, err := compression.Compress(encoding, ctx.Response.Body())
+			if err.IsNotBlank() {
+				logger.Warn(fmt.Sprintf("W#3GLE6Y - Sending uncompressed response: %v", err))
+				return
+			}
+			ctx.Response.SetBodyRaw(compressed)
+			ctx.Response.Header.SetContentEncoding(encoding)
+			if etag := ctx.Response.Header.Peek(fasthttp.HeaderETag); len(etag) > 0 {
+				ctx.Response.Header.Set(fasthttp.HeaderETag, compression.WeakETag(string(etag)))
+			}
+		}
+	}
+}
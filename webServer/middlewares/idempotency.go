@@ -0,0 +1,90 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/techrail/ground/constants/customHeaders"
+	"github.com/techrail/ground/idempotency"
+	"github.com/techrail/ground/logger"
+	"github.com/techrail/ground/render"
+)
+
+// Idempotency returns a middleware giving `Idempotency-Key` semantics to the requests of the configured methods:
+// the response to the first request with a key is stored and replayed (with the Idempotent-Replayed header) for the
+// duplicates. Streaming responses, hijacked connections, server errors and panics release the key instead, so that
+// the client can retry.
+func Idempotency(cfg idempotency.Config) func(handler fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(handler fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			method := string(ctx.Method())
+			if !cfg.Applies(method) {
+				handler(ctx)
+				return
+			}
+			key := string(ctx.Request.Header.Peek(cfg.HeaderName))
+			if errTy := cfg.ValidateKey(key); errTy.IsNotBlank() {
+				render.JsonWithFailureUsingErrorType(ctx, errTy)
+				return
+			}
+			if key == "" {
+				handler(ctx)
+				return
+			}
+
+			storeKey := cfg.StoreKey(method, string(ctx.Path()), key)
+			fingerprint := idempotency.Fingerprint(method, string(ctx.RequestURI()), ctx.Request.Body())
+			outcome, rec, errTy := cfg.Begin(ctx, storeKey, fingerprint)
+			switch outcome {
+			case idempotency.Proceed:
+			case idempotency.Replay:
+				// The stored headers do not include the request ID: the one of this request is kept
+				requestId := string(ctx.Response.Header.Peek(customHeaders.RequestId))
+				ctx.Response.Reset()
+				for name, values := range rec.Header {
+					for _, v := range values {
+						ctx.Response.Header.Add(name, v)
+					}
+				}
+				if requestId != "" {
+					ctx.Response.Header.Set(customHeaders.RequestId, requestId)
+				}
+				ctx.Response.Header.Set(idempotency.ReplayedHeader, "true")
+				ctx.SetStatusCode(rec.Status)
+				ctx.Response.SetBody(rec.Body)
+				return
+			default:
+				render.JsonWithFailureUsingErrorType(ctx, errTy)
+				return
+			}
+
+			finished := false
+			defer func() {
+				if !finished {
+					// The handler panicked
+					_ = cfg.Abandon(context.Background(), storeKey, rec.Owner)
+				}
+			}()
+			handler(ctx)
+			finished = true
+
+			if ctx.Response.IsBodyStream() || ctx.Hijacked() {
+				if errTy = cfg.Abandon(context.Background(), storeKey, rec.Owner); errTy.IsNotBlank() {
+					logger.Warn(fmt.Sprintf("W#3B2OMW - Could not release the idempotency key: %v", errTy))
+				}
+				return
+			}
+			header := map[string][]string{}
+			for k, v := range ctx.Response.Header.All() {
+				header[string(k)] = append(header[string(k)], string(v))
+			}
+			errTy = cfg.Finish(context.Background(), storeKey, rec.Owner, fingerprint, ctx.Response.StatusCode(), header,
+				append([]byte(nil), ctx.Response.Body()...))
+			if errTy.IsNotBlank() {
+				logger.Warn(fmt.Sprintf("W#3D77HP - Could not store the response for the idempotency key: %v", errTy))
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/techrail/ground/constants/customCtxKey"
+	"github.com/techrail/ground/logger"
+	"github.com/techrail/ground/render"
+	"github.com/techrail/ground/typs/appError"
+)
+
+// RecoverPanic recovers from a panic raised by the handlers down the chain. The panic is converted into an error
+// at the Panic level, logged (with the request ID and the stack trace) and the standard failure response is sent.
+// The stack trace is included in the response only if it was requested (see customCtxKey.StackTraceRequested).
+// It should be the first middleware of the set so that it covers all the others.
+func RecoverPanic(handler fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+
+			requestId, _ := ctx.UserValue(customCtxKey.RequestId).(string)
+			logger.Panic(fmt.Sprintf("P#3FO371 - Panic recovered while serving request %v (%s %s): %v\n%s",
+				requestId, ctx.Method(), ctx.RequestURI(), rec, debug.Stack()))
+
+			errTy := appError.NewNetworkError(fasthttp.StatusInternalServerError, appError.Panic, "3FO371",
+				"Internal error. Error logged against request ID "+requestId, fmt.Sprintf("Panic: %v", rec))
+
+			// Throw away whatever the handler had put in the response before it panicked. The renderer is called
+			// from within this deferred function, so the stack trace it captures still contains the panicking frames.
+			ctx.Response.Reset()
+			render.JsonWithFailureUsingErrorType(ctx, errTy)
+		}()
+		handler(ctx)
+	}
+}
@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/techrail/ground/constants/customHeaders"
+)
+
+func TestRecoverPanic(t *testing.T) {
+	handler := RecoverPanic(SetRequestId(func(ctx *fasthttp.RequestCtx) {
+		ctx.Response.Header.Set("X-Partial", "yes")
+		ctx.SetBodyString("partial")
+		panic("boom")
+	}))
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.SetRequestURI("/panics")
+	ctx.Request.Header.Set(customHeaders.RequestId, "req-1")
+	handler(ctx)
+
+	if ctx.Response.StatusCode() != fasthttp.StatusInternalServerError {
+		t.Errorf("E#3CJ7A0 - Expected a 500, got %v", ctx.Response.StatusCode())
+	}
+	if id := string(ctx.Response.Header.Peek(customHeaders.RequestId)); id != "req-1" {
+		t.Errorf("E#3D69Z0 - Expected the request ID in the response, got %q", id)
+	}
+	if len(ctx.Response.Header.Peek("X-Partial")) > 0 {
+		t.Errorf("E#3EUUBT - The partial response of the handler was kept")
+	}
+	var body struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(ctx.Response.Body(), &body); err != nil || body.Code != "3FO371" ||
+		!strings.Contains(body.Message, "req-1") {
+		t.Errorf("E#3GBY4R - Expected the standard failure with the request ID, got %s (%v)", ctx.Response.Body(), err)
+	}
+}
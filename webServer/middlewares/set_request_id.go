@@ -3,24 +3,32 @@ package middlewares
 import (
 	"github.com/techrail/ground/constants/customCtxKey"
 	"github.com/techrail/ground/constants/customHeaders"
+	"github.com/techrail/ground/constants/httpheaders"
 	"github.com/techrail/ground/logger"
-	"github.com/techrail/ground/uuid"
+	"github.com/techrail/ground/requestid"
 	"github.com/valyala/fasthttp"
 )
 
+// SetRequestId puts the request ID in the request context (see customCtxKey.RequestId) and echoes it in the
+// response. The ID sent by the client in the X-Request-Id header is used if it is valid; otherwise a new one
+// (a ULID) is generated.
 func SetRequestId(handler fasthttp.RequestHandler) fasthttp.RequestHandler {
 	return func(ctx *fasthttp.RequestCtx) {
 		logger.Println("D#1MR7SH - Hit the SetRequestId Middleware")
 
-		// TODO: Implement the logging to the request context
-		// Check that the header is present or not.
-		requestId := ctx.Request.Header.Peek(customHeaders.RequestId)
+		incoming := string(ctx.Request.Header.Peek(customHeaders.RequestId))
+		requestId := requestid.FromIncoming(incoming)
+		if incoming != "" && incoming != requestId {
+			logger.Println("D#3G98B6 - Discarded invalid incoming request ID; using " + requestId)
+		}
 
-		if requestId != nil {
-			ctx.SetUserValue(customCtxKey.RequestId, string(requestId))
-		} else {
-			ctx.SetUserValue(customCtxKey.RequestId, uuid.GetNewUlid().String())
+		ctx.SetUserValue(customCtxKey.RequestId, requestId)
+		// The trace context is kept as it is; the outbound HTTP client (see httpclient) forwards it
+		if tp := ctx.Request.Header.Peek(httpheaders.Traceparent); len(tp) > 0 {
+			ctx.SetUserValue(customCtxKey.TraceParent, string(tp))
+			ctx.SetUserValue(customCtxKey.TraceState, string(ctx.Request.Header.Peek(httpheaders.Tracestate)))
 		}
+		ctx.Response.Header.Set(customHeaders.RequestId, requestId)
 
 		handler(ctx)
 	}
@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"github.com/valyala/fasthttp"
+
+	"github.com/techrail/ground/respheaders"
+)
+
+// SetSecurityHeaders adds the security headers (CSP, X-Frame-Options, X-Content-Type-Options, Referrer-Policy,
+// Permissions-Policy and HSTS) configured in the header policy to the response. The Strict-Transport-Security
+// header is only sent when the request came over a secure connection (directly or through a TLS terminating proxy).
+func SetSecurityHeaders(handler fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		policy := respheaders.Current()
+		for _, h := range policy.Security {
+			ctx.Response.Header.Set(h.Name, h.Value)
+		}
+		if policy.Hsts != "" &&
+			(ctx.IsTLS() || string(ctx.Request.Header.Peek(fasthttp.HeaderXForwardedProto)) == "https") {
+			ctx.Response.Header.Set(fasthttp.HeaderStrictTransportSecurity, policy.Hsts)
+		}
+		handler(ctx)
+	}
+}
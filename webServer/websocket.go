@@ -0,0 +1,46 @@
+package webServer
+
+import (
+	"fmt"
+
+	"github.com/fasthttp/websocket"
+	"github.com/valyala/fasthttp"
+
+	"github.com/techrail/ground/constants/customCtxKey"
+	"github.com/techrail/ground/core"
+	"github.com/techrail/ground/logger"
+	"github.com/techrail/ground/render"
+	"github.com/techrail/ground/wshub"
+)
+
+// WebSocketHandler returns a request handler which upgrades the request to a websocket connection and hands it over
+// to the hub. Register it against a GET route, e.g.
+//
+//	s.Router.GET("/ws", s.WithMiddlewareSet("Default", webServer.WebSocketHandler(hub)))
+func WebSocketHandler(hub *wshub.Hub) fasthttp.RequestHandler {
+	upgrader := websocket.FastHTTPUpgrader{
+		ReadBufferSize:  hub.Config.ReadBufferSize,
+		WriteBufferSize: hub.Config.WriteBufferSize,
+		CheckOrigin: func(ctx *fasthttp.RequestCtx) bool {
+			return hub.OriginAllowed(string(ctx.Request.Header.Peek(fasthttp.HeaderOrigin)), string(ctx.Host()))
+		},
+	}
+
+	return func(ctx *fasthttp.RequestCtx) {
+		if hub.IsClosed() || core.State().WebServerShutdownRequested.Load() {
+			render.JsonWithFailure(ctx, fasthttp.StatusServiceUnavailable, "3AYNXL",
+				"Server is in process of shutting down", "")
+			return
+		}
+
+		requestId, _ := ctx.UserValue(customCtxKey.RequestId).(string)
+		err := upgrader.Upgrade(ctx, func(ws *websocket.Conn) {
+			if e := hub.Serve(ws, requestId); e.IsNotBlank() {
+				logger.Warn(fmt.Sprintf("W#3CGJCN - Websocket connection could not be served: %v", e))
+			}
+		})
+		if err != nil {
+			logger.Debug(fmt.Sprintf("D#3HKW5Q - Websocket upgrade failed: %v", err))
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package wshub
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fasthttp/websocket"
+
+	"github.com/techrail/ground/logger"
+	"github.com/techrail/ground/typs/appError"
+	"github.com/techrail/ground/uuid"
+)
+
+type outboundMessage struct {
+	messageType int
+	data        []byte
+}
+
+// Conn is a single websocket connection registered with a Hub
+type Conn struct {
+	Id        string // Unique ID of the connection (a ULID)
+	RequestId string // ID of the request which was upgraded to this connection
+	hub       *Hub
+	ws        *websocket.Conn
+	send      chan outboundMessage // Buffered channel of outbound messages
+	done      chan struct{}        // Closed when the connection is supposed to go away
+	closeOnce sync.Once
+	closeCode int
+	closeText string
+	values    sync.Map
+}
+
+func newConn(h *Hub, ws *websocket.Conn, requestId string) *Conn {
+	return &Conn{
+		Id:        uuid.GetNewUlidAsString(),
+		RequestId: requestId,
+		hub:       h,
+		ws:        ws,
+		send:      make(chan outboundMessage, h.Config.SendBufferSize),
+		done:      make(chan struct{}),
+		closeCode: websocket.CloseNormalClosure,
+	}
+}
+
+// Set stores a value against the connection (e.g. the ID of the authenticated user)
+func (c *Conn) Set(key string, value any) {
+	c.values.Store(key, value)
+}
+
+// Get fetches a value stored against the connection using Set
+func (c *Conn) Get(key string) (any, bool) {
+	return c.values.Load(key)
+}
+
+// Join adds this connection to the room
+func (c *Conn) Join(room string) {
+	c.hub.Join(c, room)
+}
+
+// Leave removes this connection from the room
+func (c *Conn) Leave(room string) {
+	c.hub.Leave(c, room)
+}
+
+// Send queues a text message for the connection
+func (c *Conn) Send(msg []byte) appError.Typ {
+	return c.SendWithType(TextMessage, msg)
+}
+
+// SendWithType queues a message of the given type for the connection. The call never blocks: if the send buffer
+// of the connection is full then the client is considered too slow and the connection is closed.
+func (c *Conn) SendWithType(messageType int, msg []byte) appError.Typ {
+	select {
+	case <-c.done:
+		return appError.NewError(appError.Warning, "3DT0PT",
+			fmt.Sprintf("Websocket connection %v is closed", c.Id))
+	default:
+	}
+
+	select {
+	case c.send <- outboundMessage{messageType: messageType, data: msg}:
+		return appError.BlankError
+	default:
+		c.closeWithReason(websocket.ClosePolicyViolation, "send buffer full")
+		return appError.NewError(appError.Warning, "3CQTR8",
+			fmt.Sprintf("Send buffer of websocket connection %v is full", c.Id))
+	}
+}
+
+// Close closes the connection normally
+func (c *Conn) Close() {
+	c.closeWithReason(websocket.CloseNormalClosure, "")
+}
+
+func (c *Conn) closeWithReason(code int, text string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeText = text
+		close(c.done)
+	})
+}
+
+func (c *Conn) close() {
+	c.closeWithReason(websocket.CloseNormalClosure, "")
+}
+
+// readPump reads the messages from the connection and hands them over to the OnMessage hook of the hub.
+// It returns when the peer goes away or the read fails.
+func (c *Conn) readPump() {
+	cfg := c.hub.Config
+	if cfg.MaxMessageSize > 0 {
+		c.ws.SetReadLimit(cfg.MaxMessageSize)
+	}
+	_ = c.ws.SetReadDeadline(time.Now().Add(cfg.PongWait))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(cfg.PongWait))
+	})
+
+	for {
+		messageType, msg, err := c.ws.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure,
+				websocket.CloseNoStatusReceived) {
+				logger.Debug(fmt.Sprintf("D#3GCX01 - Websocket connection %v closed unexpectedly: %v", c.Id, err))
+			}
+			c.close()
+			return
+		}
+		if c.hub.OnMessage != nil {
+			c.hub.OnMessage(c, messageType, msg)
+		}
+	}
+}
+
+// writePump writes the queued messages and the keepalive pings to the connection. It is the only goroutine which
+// writes to the underlying websocket connection.
+func (c *Conn) writePump() {
+	cfg := c.hub.Config
+	ticker := time.NewTicker(cfg.PingInterval)
+	defer func() {
+		ticker.Stop()
+		_ = c.ws.Close()
+	}()
+
+	for {
+		select {
+		case <-c.done:
+			_ = c.ws.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(c.closeCode, c.closeText), time.Now().Add(cfg.WriteWait))
+			return
+		case msg := <-c.send:
+			_ = c.ws.SetWriteDeadline(time.Now().Add(cfg.WriteWait))
+			if err := c.ws.WriteMessage(msg.messageType, msg.data); err != nil {
+				c.close()
+				return
+			}
+		case <-ticker.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(cfg.WriteWait)); err != nil {
+				c.close()
+				return
+			}
+		}
+	}
+}
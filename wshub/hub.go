@@ -0,0 +1,278 @@
+// Package wshub contains a WebSocket connection hub which can be used by both the fasthttp (webServer) and the
+// net/http (netserver) flavours of the servers. The hub keeps track of the connected clients, lets them join and
+// leave rooms, and allows the application to broadcast messages to everyone or to a single room.
+package wshub
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/techrail/bark/appRuntime"
+
+	"github.com/techrail/ground/core"
+	"github.com/techrail/ground/logger"
+	"github.com/techrail/ground/typs/appError"
+)
+
+const (
+	TextMessage   = websocket.TextMessage
+	BinaryMessage = websocket.BinaryMessage
+)
+
+// Config controls the behaviour of every connection handled by a Hub
+type Config struct {
+	SendBufferSize  int           // Number of messages which can be queued for a single connection
+	MaxMessageSize  int64         // Maximum size of an incoming message in bytes
+	WriteWait       time.Duration // Time allowed to write a single message to the peer
+	PongWait        time.Duration // Time allowed to read the next pong message from the peer
+	PingInterval    time.Duration // Send pings to peer with this period. Must be less than PongWait
+	ReadBufferSize  int           // Size of the read buffer used during the upgrade
+	WriteBufferSize int           // Size of the write buffer used during the upgrade
+	AllowedOrigins  []string      // Origins allowed to connect. If empty, only same-origin requests are allowed
+}
+
+// DefaultConfig returns the configuration which is used when NewHub is called without one
+func DefaultConfig() Config {
+	return Config{
+		SendBufferSize:  256,
+		MaxMessageSize:  64 * 1024,
+		WriteWait:       10 * time.Second,
+		PongWait:        60 * time.Second,
+		PingInterval:    54 * time.Second,
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+	}
+}
+
+// Hub maintains the set of active connections and the rooms they have joined
+type Hub struct {
+	Config       Config
+	OnConnect    func(c *Conn)                              // Called after a connection is registered with the hub
+	OnMessage    func(c *Conn, messageType int, msg []byte) // Called for every message received from a connection
+	OnDisconnect func(c *Conn)                              // Called after a connection is removed from the hub
+
+	mu     sync.RWMutex
+	conns  map[*Conn]struct{}
+	rooms  map[string]map[*Conn]struct{}
+	closed atomic.Bool
+	done   chan struct{}
+}
+
+// NewHub creates a new hub and starts watching the shutdown flags so that all the connections are closed
+// cleanly when the server (or the app) is asked to shut down. If no config is supplied, DefaultConfig is used.
+func NewHub(cfg ...Config) *Hub {
+	c := DefaultConfig()
+	if len(cfg) > 0 {
+		c = cfg[0]
+	}
+	if c.SendBufferSize <= 0 {
+		c.SendBufferSize = DefaultConfig().SendBufferSize
+	}
+	if c.PongWait <= 0 {
+		c.PongWait = DefaultConfig().PongWait
+	}
+	if c.PingInterval <= 0 || c.PingInterval >= c.PongWait {
+		c.PingInterval = (c.PongWait * 9) / 10
+	}
+	if c.WriteWait <= 0 {
+		c.WriteWait = DefaultConfig().WriteWait
+	}
+
+	h := &Hub{
+		Config: c,
+		conns:  make(map[*Conn]struct{}),
+		rooms:  make(map[string]map[*Conn]struct{}),
+		done:   make(chan struct{}),
+	}
+	go h.watchShutdown()
+	return h
+}
+
+// watchShutdown keeps checking the shutdown flags and closes the hub when one of them is raised
+func (h *Hub) watchShutdown() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.done:
+			return
+		case <-ticker.C:
+			if core.State().WebServerShutdownRequested.Load() || appRuntime.ShutdownRequested.Load() {
+				logger.Info("I#3H2K3V - Shutdown was requested. Closing all websocket connections.")
+				h.Close()
+				return
+			}
+		}
+	}
+}
+
+// IsClosed tells if the hub has been closed (and is not accepting any new connections)
+func (h *Hub) IsClosed() bool {
+	return h.closed.Load()
+}
+
+// Serve registers the websocket connection with the hub and blocks until the connection is closed. Both the
+// fasthttp and the net/http upgrade handlers call this function after a successful upgrade.
+func (h *Hub) Serve(ws *websocket.Conn, requestId string) appError.Typ {
+	c := newConn(h, ws, requestId)
+	if !h.register(c) {
+		_ = ws.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "server is shutting down"),
+			time.Now().Add(h.Config.WriteWait))
+		_ = ws.Close()
+		return appError.NewError(appError.Warning, "3GBE2F", "Websocket hub is closed. Connection rejected.")
+	}
+	if h.OnConnect != nil {
+		h.OnConnect(c)
+	}
+
+	go c.writePump()
+	c.readPump()
+
+	h.unregister(c)
+	if h.OnDisconnect != nil {
+		h.OnDisconnect(c)
+	}
+	return appError.BlankError
+}
+
+// register adds the connection to the hub, unless the hub is closed. The closed flag is checked under the lock
+// which Close takes to set it, so that a connection is either rejected here or closed by Close.
+func (h *Hub) register(c *Conn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed.Load() {
+		return false
+	}
+	h.conns[c] = struct{}{}
+	return true
+}
+
+func (h *Hub) unregister(c *Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.conns, c)
+	for room, members := range h.rooms {
+		delete(members, c)
+		if len(members) == 0 {
+			delete(h.rooms, room)
+		}
+	}
+	c.close()
+}
+
+// Join adds the connection to a room. A connection can be in any number of rooms.
+func (h *Hub) Join(c *Conn, room string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.conns[c]; !ok {
+		return
+	}
+	if _, ok := h.rooms[room]; !ok {
+		h.rooms[room] = make(map[*Conn]struct{})
+	}
+	h.rooms[room][c] = struct{}{}
+}
+
+// Leave removes the connection from the room
+func (h *Hub) Leave(c *Conn, room string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if members, ok := h.rooms[room]; ok {
+		delete(members, c)
+		if len(members) == 0 {
+			delete(h.rooms, room)
+		}
+	}
+}
+
+// Rooms returns the names of all the rooms which have at least one connection in them
+func (h *Hub) Rooms() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	rooms := make([]string, 0, len(h.rooms))
+	for room := range h.rooms {
+		rooms = append(rooms, room)
+	}
+	return rooms
+}
+
+// ConnectionCount returns the number of connections currently registered with the hub
+func (h *Hub) ConnectionCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.conns)
+}
+
+// RoomSize returns the number of connections in a room
+func (h *Hub) RoomSize(room string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.rooms[room])
+}
+
+// Broadcast sends a text message to every connection in the hub
+func (h *Hub) Broadcast(msg []byte) {
+	h.BroadcastWithType(TextMessage, msg)
+}
+
+// BroadcastWithType sends a message of the given type (TextMessage or BinaryMessage) to every connection
+func (h *Hub) BroadcastWithType(messageType int, msg []byte) {
+	h.mu.RLock()
+	targets := make([]*Conn, 0, len(h.conns))
+	for c := range h.conns {
+		targets = append(targets, c)
+	}
+	h.mu.RUnlock()
+
+	h.sendToAll(targets, messageType, msg)
+}
+
+// BroadcastToRoom sends a text message to every connection in the room
+func (h *Hub) BroadcastToRoom(room string, msg []byte) {
+	h.BroadcastToRoomWithType(room, TextMessage, msg)
+}
+
+// BroadcastToRoomWithType sends a message of the given type to every connection in the room
+func (h *Hub) BroadcastToRoomWithType(room string, messageType int, msg []byte) {
+	h.mu.RLock()
+	members := h.rooms[room]
+	targets := make([]*Conn, 0, len(members))
+	for c := range members {
+		targets = append(targets, c)
+	}
+	h.mu.RUnlock()
+
+	h.sendToAll(targets, messageType, msg)
+}
+
+func (h *Hub) sendToAll(targets []*Conn, messageType int, msg []byte) {
+	for _, c := range targets {
+		if e := c.SendWithType(messageType, msg); e.IsNotBlank() {
+			logger.Warn(fmt.Sprintf("W#3F5EM0 - Dropping slow websocket connection %v: %v", c.Id, e))
+		}
+	}
+}
+
+// Close closes every connection (with a "going away" close frame) and stops accepting new ones. It is safe to call
+// Close multiple times.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	if !h.closed.CompareAndSwap(false, true) {
+		h.mu.Unlock()
+		return
+	}
+	targets := make([]*Conn, 0, len(h.conns))
+	for c := range h.conns {
+		targets = append(targets, c)
+	}
+	h.mu.Unlock()
+	close(h.done)
+
+	for _, c := range targets {
+		c.closeWithReason(websocket.CloseGoingAway, "server is shutting down")
+	}
+}
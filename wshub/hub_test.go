@@ -0,0 +1,114 @@
+package wshub
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/websocket"
+)
+
+func newTestServer(t *testing.T, h *Hub) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
+		ws, err := upgrader.Upgrade(w, rq, nil)
+		if err != nil {
+			t.Errorf("E#3CKQ9X - Upgrade failed: %v", err)
+			return
+		}
+		h.Serve(ws, "")
+	}))
+}
+
+func dial(t *testing.T, srv *httptest.Server) *websocket.Conn {
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("E#3ELDGH - Dial failed: %v", err)
+	}
+	return ws
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("E#3B0L4Q - Condition was not met in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHub_BroadcastToRoom(t *testing.T) {
+	h := NewHub()
+	defer h.Close()
+	h.OnMessage = func(c *Conn, messageType int, msg []byte) {
+		c.Join(string(msg))
+	}
+
+	srv := newTestServer(t, h)
+	defer srv.Close()
+
+	inRoom := dial(t, srv)
+	defer inRoom.Close()
+	notInRoom := dial(t, srv)
+	defer notInRoom.Close()
+
+	if err := inRoom.WriteMessage(websocket.TextMessage, []byte("dashboard")); err != nil {
+		t.Fatalf("E#3C35A0 - Write failed: %v", err)
+	}
+	waitFor(t, func() bool { return h.ConnectionCount() == 2 && h.RoomSize("dashboard") == 1 })
+
+	h.BroadcastToRoom("dashboard", []byte("hello room"))
+	h.Broadcast([]byte("hello all"))
+
+	_ = inRoom.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, msg, err := inRoom.ReadMessage()
+	if err != nil || string(msg) != "hello room" {
+		t.Errorf("E#3D3KWE - Expected `hello room`, got `%s` (error: %v)", msg, err)
+	}
+
+	_ = notInRoom.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, msg, err = notInRoom.ReadMessage()
+	if err != nil || string(msg) != "hello all" {
+		t.Errorf("E#3CHCEV - Expected `hello all`, got `%s` (error: %v)", msg, err)
+	}
+}
+
+func TestHub_CloseSendsGoingAway(t *testing.T) {
+	h := NewHub()
+	srv := newTestServer(t, h)
+	defer srv.Close()
+
+	ws := dial(t, srv)
+	defer ws.Close()
+	waitFor(t, func() bool { return h.ConnectionCount() == 1 })
+
+	h.Close()
+
+	_ = ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := ws.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("E#3DBCMA - Expected a going away close error, got: %v", err)
+	}
+	waitFor(t, func() bool { return h.ConnectionCount() == 0 })
+}
+
+func TestHub_RejectsConnectionsAfterClose(t *testing.T) {
+	h := NewHub()
+	srv := newTestServer(t, h)
+	defer srv.Close()
+	h.Close()
+
+	ws := dial(t, srv)
+	defer ws.Close()
+	_ = ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := ws.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("E#3BJG48 - Expected a going away close error, got: %v", err)
+	}
+	if h.ConnectionCount() != 0 {
+		t.Errorf("E#3G4Z4Q - A connection was registered with a closed hub")
+	}
+}
@@ -0,0 +1,30 @@
+package wshub
+
+import (
+	"net/url"
+	"strings"
+)
+
+// OriginAllowed tells if a connection coming from the given origin should be upgraded. If no AllowedOrigins were
+// configured, only same-origin requests (or requests without an Origin header) are allowed. A value of "*" in the
+// AllowedOrigins list allows every origin.
+func (h *Hub) OriginAllowed(origin string, host string) bool {
+	if origin == "" {
+		return true
+	}
+
+	if len(h.Config.AllowedOrigins) == 0 {
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+		return strings.EqualFold(u.Host, host)
+	}
+
+	for _, allowed := range h.Config.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}